@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/azure"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	log "github.com/sirupsen/logrus"
+)
+
+// How an existing Asana task matched a work item, most certain first.
+const (
+	matchLinkField = "link_field"
+	matchNotesURL  = "notes_url"
+	matchTitle     = "title"
+)
+
+// matchRanks orders the matches by confidence.
+var matchRanks = map[string]int{
+	matchLinkField: 3,
+	matchNotesURL:  2,
+	matchTitle:     1,
+}
+
+// workItemURLPattern finds the work item ID in ADO web and API work item
+// URLs.
+var workItemURLPattern = regexp.MustCompile(`(?i)(?:_workitems/edit|_apis/wit/workitems)/(\d+)`)
+
+// workItemIDsInText returns the IDs of the ADO work items linked in s.
+func workItemIDsInText(s string) map[int]bool {
+	ids := map[int]bool{}
+	for _, m := range workItemURLPattern.FindAllStringSubmatch(s, -1) {
+		if id, err := strconv.Atoi(m[1]); err == nil {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// linkedWorkItem returns the ID of the ADO work item the Asana task links
+// to in a custom field, such as the link field set by the sync, or else in
+// its notes, which the sync starts with a link to the work item. It reports
+// false when the task links no work item, or several.
+func linkedWorkItem(t asana.Task) (int, bool) {
+	ids := map[int]bool{}
+	for _, v := range t.CustomFields {
+		maps.Copy(ids, workItemIDsInText(v))
+	}
+	if len(ids) == 0 {
+		ids = notesWorkItemIDs(t)
+	}
+	if len(ids) != 1 {
+		return 0, false
+	}
+	for id := range ids {
+		return id, true
+	}
+	return 0, false
+}
+
+// notesWorkItemIDs returns the IDs of the ADO work items linked in the notes
+// of the task. Links only keep their URL in the HTML notes.
+func notesWorkItemIDs(t asana.Task) map[int]bool {
+	ids := workItemIDsInText(t.Notes)
+	maps.Copy(ids, workItemIDsInText(t.HTMLNotes))
+	return ids
+}
+
+// adoptionCandidates returns the Asana tasks that may belong to the work
+// item, keeping only those with the most certain kind of match. Tasks whose
+// link field or notes link another work item are never candidates.
+func adoptionCandidates(tasks []asana.Task, wi azure.WorkItem, name, linkFieldGID string) []db.AdoptionCandidate {
+	var best []db.AdoptionCandidate
+	bestRank := 0
+	for _, t := range tasks {
+		match := matchTask(t, wi, name, linkFieldGID)
+		rank := matchRanks[match]
+		if rank == 0 || rank < bestRank {
+			continue
+		}
+		if rank > bestRank {
+			best, bestRank = nil, rank
+		}
+		best = append(best, db.AdoptionCandidate{AsanaTaskID: t.GID, Name: t.Name, Match: match})
+	}
+	return best
+}
+
+// matchTask returns how the task matches the work item, or "" when it does
+// not.
+func matchTask(t asana.Task, wi azure.WorkItem, name, linkFieldGID string) string {
+	if link := t.CustomFields[linkFieldGID]; linkFieldGID != "" && link != "" {
+		if link == wi.URL || workItemIDsInText(link)[wi.ID] {
+			return matchLinkField
+		}
+		return ""
+	}
+	if ids := notesWorkItemIDs(t); len(ids) > 0 {
+		if ids[wi.ID] {
+			return matchNotesURL
+		}
+		return ""
+	}
+	if t.Name == name {
+		return matchTitle
+	}
+	return ""
+}
+
+// adoptExistingTask maps the work item to an Asana task that already
+// exists in the project and is not mapped yet. A single best match by link
+// is adopted; several matches, or a match by title only, are left for
+// review in the web UI, and the work item waits for a decision. It reports
+// whether the work item was handled.
+func (app *App) adoptExistingTask(ctx context.Context, asanaProj, workspace string, wi azure.WorkItem, name, desc string) (bool, error) {
+	review, ok, err := app.DB.AdoptionReviewByADOTaskID(ctx, wi.ID)
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		return app.applyAdoptionReview(ctx, review, workspace, wi, name, desc)
+	}
+
+	tasks, err := app.Asana.ListProjectTasks(ctx, asanaProj)
+	if err != nil {
+		app.forgetOnNotFound(ctx, err, asanaProj)
+		return false, err
+	}
+	var linkFieldGID string
+	if cf, ok := app.getLinkCustomField(ctx, asanaProj); ok {
+		linkFieldGID = cf.GID
+	}
+
+	candidates, err := app.unmappedCandidates(ctx, tasks, wi, name, linkFieldGID)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case len(candidates) == 0:
+		return false, nil
+	case len(candidates) == 1 && candidates[0].Match != matchTitle:
+		log.WithField("ado_task_id", wi.ID).WithField("asana_task_id", candidates[0].AsanaTaskID).
+			WithField("match", candidates[0].Match).Info("adopting existing Asana task")
+		return true, app.adoptTask(ctx, candidates[0].AsanaTaskID, asanaProj, workspace, wi, name, desc)
+	}
+
+	log.WithField("ado_task_id", wi.ID).WithField("candidates", len(candidates)).
+		WithField("match", candidates[0].Match).Warn("Asana task match is uncertain, waiting for review")
+	return true, app.DB.AddAdoptionReview(ctx, db.AdoptionReview{
+		ADOTaskID:      wi.ID,
+		ADOProjectName: wi.TeamProject,
+		ADOTitle:       name,
+		ADOURL:         wi.URL,
+		AsanaProjectID: asanaProj,
+		Candidates:     candidates,
+	})
+}
+
+// unmappedCandidates returns the adoption candidates among the tasks not
+// mapped to another work item. Only the best candidates are looked up; when
+// all of them are mapped, the next best are tried.
+func (app *App) unmappedCandidates(ctx context.Context, tasks []asana.Task, wi azure.WorkItem, name, linkFieldGID string) ([]db.AdoptionCandidate, error) {
+	for {
+		candidates := adoptionCandidates(tasks, wi, name, linkFieldGID)
+		var unmapped []db.AdoptionCandidate
+		mapped := make(map[string]bool)
+		for _, c := range candidates {
+			_, found, err := app.DB.TaskByAsanaTaskID(ctx, c.AsanaTaskID)
+			if err != nil {
+				return nil, err
+			}
+			if found {
+				mapped[c.AsanaTaskID] = true
+				continue
+			}
+			unmapped = append(unmapped, c)
+		}
+		if len(unmapped) > 0 || len(mapped) == 0 {
+			return unmapped, nil
+		}
+		rest := make([]asana.Task, 0, len(tasks)-len(mapped))
+		for _, t := range tasks {
+			if !mapped[t.GID] {
+				rest = append(rest, t)
+			}
+		}
+		tasks = rest
+	}
+}
+
+// applyAdoptionReview applies the decision made in the web UI for the work
+// item, or leaves it waiting when there is none yet. A chosen Asana task
+// mapped to another work item since is dropped from the candidates and the
+// review asked again.
+func (app *App) applyAdoptionReview(ctx context.Context, review db.AdoptionReview, workspace string, wi azure.WorkItem, name, desc string) (bool, error) {
+	var err error
+	switch review.Decision {
+	case db.AdoptionDecisionAdopt:
+		mapping, found, lookupErr := app.DB.TaskByAsanaTaskID(ctx, review.AsanaTaskID)
+		if lookupErr != nil {
+			return true, lookupErr
+		}
+		if found && mapping.ADOTaskID != wi.ID {
+			log.WithField("ado_task_id", wi.ID).WithField("asana_task_id", review.AsanaTaskID).
+				WithField("mapped_ado_task_id", mapping.ADOTaskID).Warn("chosen Asana task is already mapped, waiting for review")
+			review.Candidates = slices.DeleteFunc(review.Candidates, func(c db.AdoptionCandidate) bool {
+				return c.AsanaTaskID == review.AsanaTaskID
+			})
+			return true, app.DB.AddAdoptionReview(ctx, review)
+		}
+		err = app.adoptTask(ctx, review.AsanaTaskID, review.AsanaProjectID, workspace, wi, name, desc)
+	case db.AdoptionDecisionCreate:
+		err = app.createAndMapTask(ctx, review.AsanaProjectID, workspace, wi, name, desc)
+	default:
+		log.WithField("ado_task_id", wi.ID).Debug("waiting for adoption review")
+		return true, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	return true, app.DB.RemoveAdoptionReview(ctx, wi.ID)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdoptionCandidates(t *testing.T) {
+	wi := createTestWorkItem(7, "Title", "TestProject", "https://dev.azure.com/org/_apis/wit/workItems/7", time.Now())
+	byLink := asana.Task{GID: "link", Name: "Renamed", CustomFields: map[string]string{"cf-link": wi.URL}}
+	byWebLink := asana.Task{GID: "web", CustomFields: map[string]string{"cf-link": "https://dev.azure.com/org/p/_workitems/edit/7"}}
+	byNotes := asana.Task{GID: "notes", Notes: "Imported from https://dev.azure.com/org/p/_workitems/edit/7/"}
+	byHTMLNotes := asana.Task{GID: "html", Notes: "Story 7: Renamed", HTMLNotes: `<body><a href="` + wi.URL + `">Story 7:</a> Renamed</body>`}
+	byTitle := asana.Task{GID: "title", Name: "Story 7: Title"}
+	otherItem := asana.Task{GID: "other", Name: "Story 7: Title", HTMLNotes: `<body><a href="https://dev.azure.com/org/_apis/wit/workItems/8">Story 8:</a></body>`}
+	otherLink := asana.Task{GID: "other-link", Name: "Story 7: Title", CustomFields: map[string]string{"cf-link": "https://dev.azure.com/org/p/_workitems/edit/70"}}
+
+	tests := []struct {
+		name  string
+		tasks []asana.Task
+		want  []string
+		match string
+	}{
+		{"link field", []asana.Task{byTitle, byLink, byNotes}, []string{"link"}, matchLinkField},
+		{"web link in field", []asana.Task{byWebLink}, []string{"web"}, matchLinkField},
+		{"notes URL", []asana.Task{byTitle, byNotes}, []string{"notes"}, matchNotesURL},
+		{"link in HTML notes", []asana.Task{byTitle, byHTMLNotes}, []string{"html"}, matchNotesURL},
+		{"title", []asana.Task{byTitle}, []string{"title"}, matchTitle},
+		{"ambiguous", []asana.Task{byLink, byWebLink, byTitle}, []string{"link", "web"}, matchLinkField},
+		{"tasks of other work items", []asana.Task{otherItem, otherLink}, nil, ""},
+		{"no match", []asana.Task{{GID: "x", Name: "Something else"}}, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := adoptionCandidates(tt.tasks, wi, "Story 7: Title", "cf-link")
+
+			var gids []string
+			for _, c := range got {
+				gids = append(gids, c.AsanaTaskID)
+				assert.Equal(t, tt.match, c.Match)
+			}
+			assert.Equal(t, tt.want, gids)
+		})
+	}
+}
+
+func TestLinkedWorkItem(t *testing.T) {
+	tests := []struct {
+		name string
+		task asana.Task
+		want int
+	}{
+		{"link field", asana.Task{CustomFields: map[string]string{"cf": "https://dev.azure.com/org/_apis/wit/workItems/7"}, Notes: "see _workitems/edit/8"}, 7},
+		{"notes link", asana.Task{HTMLNotes: `<body><a href="https://dev.azure.com/org/p/_workitems/edit/9">Bug 9:</a> Crash</body>`}, 9},
+		{"several work items", asana.Task{Notes: "_workitems/edit/1 and _workitems/edit/2"}, 0},
+		{"no work item", asana.Task{Notes: "nothing"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, ok := linkedWorkItem(tt.task)
+			assert.Equal(t, tt.want != 0, ok)
+			assert.Equal(t, tt.want, id)
+		})
+	}
+}
+
+func setupAdoptionTest(t *testing.T) (*App, *enhancedMockDB, *enhancedMockAsana) {
+	t.Helper()
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockDB.projects = []db.Project{
+		{ADOProjectName: "TestProject", AsanaWorkspaceName: "workspace1", AsanaProjectName: "AsanaProj"},
+	}
+	mockAsana.projects["workspace1"] = map[string]string{"AsanaProj": "proj-gid-1"}
+	mockAsana.customFields["proj-gid-1"] = []asana.CustomField{{GID: "cf-link", Name: "link"}}
+	mockAzure.workItems[123] = createTestWorkItem(123, "Renamed Task", "TestProject", "http://ado.com/_apis/wit/workItems/123", time.Now())
+	return app, mockDB, mockAsana
+}
+
+func TestHandleTaskAdoptsTaskByLinkField(t *testing.T) {
+	app, mockDB, mockAsana := setupAdoptionTest(t)
+	mockAsana.tasks["proj-gid-1"] = []asana.Task{
+		{GID: "manual-1", Name: "Old title", CustomFields: map[string]string{"cf-link": "http://ado.com/_apis/wit/workItems/123"}},
+	}
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 123})
+
+	assert.NoError(t, err)
+	assert.Empty(t, mockAsana.tasksCreated)
+	assert.Equal(t, "manual-1", mockDB.tasks[123].AsanaTaskID)
+}
+
+func TestHandleTaskSkipsMappedTasks(t *testing.T) {
+	app, mockDB, mockAsana := setupAdoptionTest(t)
+	mockDB.tasks[99] = db.TaskMapping{ADOTaskID: 99, AsanaProjectID: "proj-gid-1", AsanaTaskID: "mapped-1"}
+	mockAsana.tasks["proj-gid-1"] = []asana.Task{
+		{GID: "mapped-1", Name: "Copy", CustomFields: map[string]string{"cf-link": "http://ado.com/_apis/wit/workItems/123"}},
+		{GID: "manual-1", Name: "Old title", Notes: "http://ado.com/org/p/_workitems/edit/123"},
+	}
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 123})
+
+	assert.NoError(t, err)
+	assert.Empty(t, mockAsana.tasksCreated)
+	assert.Equal(t, "manual-1", mockDB.tasks[123].AsanaTaskID, "the next best unmapped task is adopted")
+}
+
+func TestHandleTaskQueuesAmbiguousMatchForReview(t *testing.T) {
+	app, mockDB, mockAsana := setupAdoptionTest(t)
+	mockAsana.tasks["proj-gid-1"] = []asana.Task{
+		{GID: "a", Name: "Copy A", Notes: "http://ado.com/org/p/_workitems/edit/123"},
+		{GID: "b", Name: "Copy B", Notes: "see http://ado.com/org/p/_workitems/edit/123"},
+	}
+	ctx := context.Background()
+	wlog := log.WithField("test", "worker")
+
+	_, err := app.handleTask(ctx, wlog, SyncTask{ADOTaskID: 123})
+
+	assert.NoError(t, err)
+	assert.Empty(t, mockAsana.tasksCreated, "no duplicate while the match is ambiguous")
+	assert.NotContains(t, mockDB.tasks, 123)
+	review := mockDB.reviews[123]
+	assert.Len(t, review.Candidates, 2)
+	assert.Equal(t, matchNotesURL, review.Candidates[0].Match)
+	assert.Equal(t, "proj-gid-1", review.AsanaProjectID)
+
+	_, err = app.handleTask(ctx, wlog, SyncTask{ADOTaskID: 123})
+	assert.NoError(t, err)
+	assert.Empty(t, mockAsana.tasksCreated, "still waiting for a decision")
+
+	assert.NoError(t, mockDB.DecideAdoptionReview(ctx, 123, db.AdoptionDecisionAdopt, "b"))
+	assert.Equal(t, db.JobQueued, mockDB.jobs[123].State, "decision queues the work item")
+	_, err = app.handleTask(ctx, wlog, SyncTask{ADOTaskID: 123})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "b", mockDB.tasks[123].AsanaTaskID)
+	assert.Empty(t, mockDB.reviews)
+}
+
+func TestHandleTaskReviewsAgainWhenChosenTaskIsMapped(t *testing.T) {
+	app, mockDB, mockAsana := setupAdoptionTest(t)
+	mockDB.tasks[99] = db.TaskMapping{ADOTaskID: 99, AsanaProjectID: "proj-gid-1", AsanaTaskID: "a"}
+	mockDB.reviews[123] = db.AdoptionReview{
+		ADOTaskID:      123,
+		ADOProjectName: "TestProject",
+		AsanaProjectID: "proj-gid-1",
+		Candidates:     []db.AdoptionCandidate{{AsanaTaskID: "a"}, {AsanaTaskID: "b"}},
+		Decision:       db.AdoptionDecisionAdopt,
+		AsanaTaskID:    "a",
+	}
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 123})
+
+	assert.NoError(t, err)
+	assert.NotContains(t, mockDB.tasks, 123, "one Asana task is never mapped twice")
+	assert.Empty(t, mockAsana.tasksCreated)
+	review := mockDB.reviews[123]
+	assert.Empty(t, review.Decision, "the review is asked again")
+	assert.Equal(t, []db.AdoptionCandidate{{AsanaTaskID: "b"}}, review.Candidates)
+}
+
+func TestHandleTaskCreatesTaskWhenReviewDecidesSo(t *testing.T) {
+	app, mockDB, mockAsana := setupAdoptionTest(t)
+	mockDB.reviews[123] = db.AdoptionReview{
+		ADOTaskID:      123,
+		ADOProjectName: "TestProject",
+		AsanaProjectID: "proj-gid-1",
+		Candidates:     []db.AdoptionCandidate{{AsanaTaskID: "a"}, {AsanaTaskID: "b"}},
+		Decision:       db.AdoptionDecisionCreate,
+	}
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 123})
+
+	assert.NoError(t, err)
+	assert.Len(t, mockAsana.tasksCreated, 1)
+	assert.Equal(t, mockAsana.tasksCreated[0].GID, mockDB.tasks[123].AsanaTaskID)
+	assert.Empty(t, mockDB.reviews)
+}
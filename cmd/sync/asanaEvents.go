@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+)
+
+// handleAsanaEvents turns Asana events into work: every mapped task that
+// changed or was commented on is queued to be compared with its work item,
+// once however many events it has. Events made by the service itself, such
+// as its own updates and comments, are ignored rather than synced back. It
+// returns the number of tasks queued.
+func (app *App) handleAsanaEvents(ctx context.Context, events []asana.Event) (int, error) {
+	seen := make(map[string]bool)
+	queued := 0
+	for _, e := range events {
+		if app.AsanaUserGID != "" && e.User != nil && e.User.GID == app.AsanaUserGID {
+			continue
+		}
+		gid := e.TaskGID()
+		if gid == "" || seen[gid] {
+			continue
+		}
+		seen[gid] = true
+
+		mapping, found, err := app.DB.TaskByAsanaTaskID(ctx, gid)
+		if err != nil {
+			return queued, err
+		}
+		if !found {
+			continue
+		}
+		if err := app.DB.CheckAsanaTask(ctx, mapping.ADOTaskID, mapping.ADOProjectID); err != nil {
+			return queued, err
+		}
+		queued++
+	}
+	return queued, nil
+}
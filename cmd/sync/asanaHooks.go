@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// asanaHandshakeWindow is how long after a webhook was requested its
+// handshake is accepted.
+const asanaHandshakeWindow = time.Minute
+
+// asanaEnabled reports whether Asana webhooks are configured.
+func (c HookConfig) asanaEnabled() bool {
+	return c.AsanaURL != ""
+}
+
+// asanaTarget returns the URL the webhook of the Asana project delivers to.
+func (c HookConfig) asanaTarget(projectGID string) string {
+	return c.AsanaURL + "/hooks/asana/" + projectGID
+}
+
+// asanaHookHandler receives the webhook of a mapped Asana project. The first
+// request is the handshake Asana makes while the webhook is created, whose
+// X-Hook-Secret is stored and echoed back. Later requests are signed with
+// the secret and carry a batch of events, or none for a heartbeat. An error
+// response makes Asana deliver the events again.
+func (app *App) asanaHookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := app.Tracer.Start(r.Context(), "sync.hooks.asana")
+	defer span.End()
+
+	projectGID := r.PathValue("project")
+	span.SetAttributes(attribute.String("asana_project_gid", projectGID))
+	hlog := log.WithField("asana_project_gid", projectGID)
+
+	if secret := r.Header.Get("X-Hook-Secret"); secret != "" {
+		// Only webhooks this service is creating may hand over a secret.
+		ok, err := app.DB.SetAsanaWebhookSecret(ctx, projectGID, secret, time.Now().Add(-asanaHandshakeWindow))
+		if err != nil {
+			span.RecordError(err, trace.WithStackTrace(true))
+			hlog.WithError(err).Error("error storing Asana webhook secret")
+			http.Error(w, "error storing secret", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			hlog.Warn("rejected unexpected Asana webhook handshake")
+			http.Error(w, "no webhook requested", http.StatusForbidden)
+			return
+		}
+		hlog.Info("Asana webhook handshake completed")
+		w.Header().Set("X-Hook-Secret", secret)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHookBody))
+	if err != nil {
+		span.RecordError(err)
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	hook, found, err := app.DB.AsanaWebhook(ctx, projectGID)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		hlog.WithError(err).Error("error reading Asana webhook")
+		http.Error(w, "error reading webhook", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		// Asana deletes the webhooks answered with 410 Gone.
+		hlog.Info("events for a project without webhook, asking Asana to delete it")
+		http.Error(w, "unknown webhook", http.StatusGone)
+		return
+	}
+	if hook.Secret == "" || !validSignature(body, hook.Secret, r.Header.Get("X-Hook-Signature")) {
+		hlog.Warn("rejected Asana webhook with an invalid signature")
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Events []asana.Event `json:"events"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		span.RecordError(err)
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if err := app.DB.TouchAsanaWebhook(ctx, projectGID); err != nil {
+		hlog.WithError(err).Warn("error recording Asana webhook delivery")
+	}
+	span.SetAttributes(attribute.Int("events", len(payload.Events)))
+	if len(payload.Events) == 0 {
+		hlog.Debug("Asana webhook heartbeat")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	queued, err := app.handleAsanaEvents(ctx, payload.Events)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		hlog.WithError(err).Error("error queuing Asana events")
+		http.Error(w, "error queuing events", http.StatusInternalServerError)
+		return
+	}
+	hlog.WithField("events", len(payload.Events)).WithField("tasks", queued).Info("received Asana events")
+	w.WriteHeader(http.StatusOK)
+}
+
+// validSignature checks the X-Hook-Signature of a webhook body: the hex
+// HMAC-SHA256 of the body keyed with the webhook secret.
+func validSignature(body []byte, secret, signature string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	want := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(signature), []byte(want))
+}
+
+// reconcileWebhooks keeps a webhook on every mapped Asana project. Webhooks
+// are created for new mappings, created again when their target changed or
+// they went quiet for longer than AsanaRenewAfter, as Asana sends
+// heartbeats every eight hours, and deleted along with their mapping.
+func (app *App) reconcileWebhooks(ctx context.Context) {
+	if !app.Hooks.asanaEnabled() {
+		return
+	}
+	ctx, span := app.Tracer.Start(ctx, "sync.reconcileWebhooks")
+	defer span.End()
+
+	projects, err := app.DB.Projects(ctx)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		log.WithError(err).Warn("error getting projects to reconcile Asana webhooks")
+		return
+	}
+	hooks, err := app.DB.AsanaWebhooks(ctx)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		log.WithError(err).Warn("error getting Asana webhooks")
+		return
+	}
+	existing := make(map[string]db.AsanaWebhook)
+	for _, h := range hooks {
+		existing[h.AsanaProjectGID] = h
+	}
+
+	wanted := make(map[string]bool)
+	for _, p := range projects {
+		gid := p.AsanaProjectGID
+		// Projects whose GID is not resolved yet get a webhook next run.
+		if gid == "" || wanted[gid] {
+			continue
+		}
+		wanted[gid] = true
+
+		hook, ok := existing[gid]
+		target := app.Hooks.asanaTarget(gid)
+		switch {
+		case !ok || hook.WebhookGID == "":
+		case hook.Target != target:
+		case time.Since(latest(hook.CreatedAt, hook.LastEventAt)) > app.Hooks.AsanaRenewAfter:
+			log.WithField("asana_project_gid", gid).Warn("Asana webhook went quiet, creating it again")
+		default:
+			continue
+		}
+		app.createWebhook(ctx, hook, gid, target)
+	}
+
+	for gid, hook := range existing {
+		if !wanted[gid] {
+			app.deleteWebhook(ctx, hook)
+		}
+	}
+}
+
+// createWebhook replaces the webhook of the Asana project with a new one.
+// Failures are logged and retried on the next run.
+func (app *App) createWebhook(ctx context.Context, old db.AsanaWebhook, projectGID, target string) {
+	wlog := log.WithField("asana_project_gid", projectGID)
+	if old.WebhookGID != "" {
+		err := app.Asana.DeleteWebhook(ctx, old.WebhookGID)
+		if err != nil && !errors.Is(err, asana.ErrNotFound) {
+			wlog.WithError(err).Warn("error deleting Asana webhook")
+			return
+		}
+	}
+	if err := app.DB.RequestAsanaWebhook(ctx, projectGID, target); err != nil {
+		wlog.WithError(err).Warn("error requesting Asana webhook")
+		return
+	}
+	hook, err := app.Asana.CreateWebhook(ctx, projectGID, target)
+	if err != nil {
+		wlog.WithError(err).Warn("error creating Asana webhook")
+		return
+	}
+	if err := app.DB.WriteAsanaWebhookGID(ctx, projectGID, hook.GID); err != nil {
+		wlog.WithError(err).Warn("error writing Asana webhook GID")
+		return
+	}
+	wlog.WithField("webhook_gid", hook.GID).Info("created Asana webhook")
+}
+
+// deleteWebhook removes the webhook of a project no longer mapped.
+func (app *App) deleteWebhook(ctx context.Context, hook db.AsanaWebhook) {
+	wlog := log.WithField("asana_project_gid", hook.AsanaProjectGID)
+	if hook.WebhookGID != "" {
+		err := app.Asana.DeleteWebhook(ctx, hook.WebhookGID)
+		if err != nil && !errors.Is(err, asana.ErrNotFound) {
+			wlog.WithError(err).Warn("error deleting Asana webhook")
+			return
+		}
+	}
+	if err := app.DB.RemoveAsanaWebhook(ctx, hook.AsanaProjectGID); err != nil {
+		wlog.WithError(err).Warn("error removing Asana webhook")
+		return
+	}
+	wlog.Info("deleted Asana webhook of unmapped project")
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupAsanaHookTest(t *testing.T) (*App, *enhancedMockDB, *enhancedMockAsana) {
+	t.Helper()
+	app, mockDB, mockAsana, mapping := setupFingerprintTest(t)
+	app.Hooks = HookConfig{AsanaURL: "https://sync.example.com", AsanaRenewAfter: 24 * time.Hour}
+	mapping.ADORevision = 2
+	mockDB.tasks[123] = mapping
+	return app, mockDB, mockAsana
+}
+
+// postAsanaHook posts body to the webhook of Asana project proj-1, signed
+// with secret unless it is empty.
+func postAsanaHook(app *App, body, secret string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/hooks/asana/proj-1", bytes.NewReader([]byte(body)))
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		req.Header.Set("X-Hook-Signature", hex.EncodeToString(mac.Sum(nil)))
+	}
+	rec := httptest.NewRecorder()
+	app.hookHandler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAsanaHookHandshake(t *testing.T) {
+	app, mockDB, _ := setupAsanaHookTest(t)
+	handshake := http.Header{"X-Hook-Secret": {"s3cret"}}
+
+	rec := postAsanaHook(app, "", "", handshake)
+	assert.Equal(t, http.StatusForbidden, rec.Code, "no webhook was requested")
+
+	assert.NoError(t, mockDB.RequestAsanaWebhook(context.Background(), "proj-1", app.Hooks.asanaTarget("proj-1")))
+	rec = postAsanaHook(app, "", "", handshake)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "s3cret", rec.Header().Get("X-Hook-Secret"))
+	assert.Equal(t, "s3cret", mockDB.webhooks["proj-1"].Secret)
+
+	rec = postAsanaHook(app, "", "", http.Header{"X-Hook-Secret": {"other"}})
+	assert.Equal(t, http.StatusForbidden, rec.Code, "the secret cannot be replaced")
+}
+
+func TestAsanaHookQueuesChangedTasks(t *testing.T) {
+	app, mockDB, _ := setupAsanaHookTest(t)
+	mockDB.webhooks["proj-1"] = db.AsanaWebhook{AsanaProjectGID: "proj-1", Secret: "s3cret"}
+	body := `{"events":[
+		{"action":"changed","resource":{"gid":"task-1","resource_type":"task"},"change":{"field":"name","action":"changed"}},
+		{"action":"added","resource":{"gid":"story-1","resource_type":"story"},"parent":{"gid":"task-1","resource_type":"task"}},
+		{"action":"changed","resource":{"gid":"task-9","resource_type":"task"}}
+	]}`
+
+	rec := postAsanaHook(app, body, "s3cret", nil)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, mockDB.jobs, 1, "unmapped tasks are ignored and events batched per task")
+	assert.True(t, mockDB.jobs[123].CheckAsana)
+	assert.False(t, mockDB.webhooks["proj-1"].LastEventAt.IsZero())
+}
+
+func TestAsanaHookIgnoresOwnEvents(t *testing.T) {
+	app, mockDB, _ := setupAsanaHookTest(t)
+	app.AsanaUserGID = "sync-user"
+	mockDB.webhooks["proj-1"] = db.AsanaWebhook{AsanaProjectGID: "proj-1", Secret: "s3cret"}
+	body := `{"events":[
+		{"action":"changed","resource":{"gid":"task-1","resource_type":"task"},"user":{"gid":"sync-user"}},
+		{"action":"added","resource":{"gid":"story-1","resource_type":"story"},"parent":{"gid":"task-1","resource_type":"task"},"user":{"gid":"sync-user"}}
+	]}`
+
+	rec := postAsanaHook(app, body, "s3cret", nil)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, mockDB.jobs, "the service's own writes are not synced back")
+}
+
+func TestAsanaHookRejectsInvalidSignature(t *testing.T) {
+	app, mockDB, _ := setupAsanaHookTest(t)
+	mockDB.webhooks["proj-1"] = db.AsanaWebhook{AsanaProjectGID: "proj-1", Secret: "s3cret"}
+	body := `{"events":[{"action":"changed","resource":{"gid":"task-1","resource_type":"task"}}]}`
+
+	assert.Equal(t, http.StatusUnauthorized, postAsanaHook(app, body, "", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, postAsanaHook(app, body, "wrong", nil).Code)
+	assert.Empty(t, mockDB.jobs)
+}
+
+func TestAsanaHookHeartbeat(t *testing.T) {
+	app, mockDB, _ := setupAsanaHookTest(t)
+	mockDB.webhooks["proj-1"] = db.AsanaWebhook{AsanaProjectGID: "proj-1", Secret: "s3cret"}
+
+	rec := postAsanaHook(app, `{"events":[]}`, "s3cret", nil)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, mockDB.webhooks["proj-1"].LastEventAt.IsZero())
+	assert.Empty(t, mockDB.jobs)
+}
+
+func TestAsanaHookGoneForUnknownProject(t *testing.T) {
+	app, _, _ := setupAsanaHookTest(t)
+
+	rec := postAsanaHook(app, `{"events":[]}`, "s3cret", nil)
+
+	assert.Equal(t, http.StatusGone, rec.Code, "Asana deletes the webhook")
+}
+
+func TestCheckAsanaTaskRecordsAsanaOnlyEdit(t *testing.T) {
+	app, mockDB, mockAsana := setupAsanaHookTest(t)
+	ctx := context.Background()
+	mapping := mockDB.tasks[123]
+	mapping.AsanaLastUpdated = time.Now().Add(-time.Hour)
+	mockDB.tasks[123] = mapping
+	desc, _ := app.Azure.(*enhancedMockAzure).workItems[123].FormatTitleWithLink()
+	mockAsana.remote["task-1"] = asana.Task{GID: "task-1", Name: "Renamed in Asana", HTMLNotes: "<body>" + desc + "</body>", ModifiedAt: time.Now()}
+
+	_, err := app.handleAsanaEvents(ctx, []asana.Event{{Action: "changed", Resource: asana.EventResource{GID: "task-1", ResourceType: "task"}}})
+	assert.NoError(t, err)
+	app.processJobs(ctx, ctx, log.WithField("test", "worker"), "test/1")
+
+	assert.Empty(t, mockAsana.taskChanges, "the Asana edit is kept")
+	assert.Equal(t, []string{fingerprintName}, mockDB.tasks[123].AsanaOwnedFields)
+	assert.Equal(t, "Renamed in Asana", mockDB.conflicts[conflictKey(123, fingerprintName)].AsanaValue)
+	assert.False(t, mockDB.jobs[123].CheckAsana, "the check is done")
+}
+
+func TestCheckAsanaTaskRestoresADOOwnedField(t *testing.T) {
+	app, mockDB, mockAsana := setupAsanaHookTest(t)
+	app.FieldPolicy = FieldPolicy{fingerprintName: ownerADO}
+	ctx := context.Background()
+	desc, _ := app.Azure.(*enhancedMockAzure).workItems[123].FormatTitleWithLink()
+	mockAsana.remote["task-1"] = asana.Task{GID: "task-1", Name: "Renamed in Asana", HTMLNotes: "<body>" + desc + "</body>", ModifiedAt: time.Now()}
+
+	_, err := app.handleTask(ctx, log.WithField("test", "worker"), SyncTask{ADOTaskID: 123, CheckAsana: true})
+
+	assert.NoError(t, err)
+	assert.Len(t, mockAsana.taskChanges, 1)
+	assert.Contains(t, mockAsana.taskChanges[0].Name, "Task")
+	assert.Empty(t, mockAsana.taskChanges[0].Notes, "unedited notes are not sent")
+	assert.Empty(t, mockDB.conflicts)
+}
+
+func TestReconcileWebhooks(t *testing.T) {
+	app, mockDB, mockAsana := setupAsanaHookTest(t)
+	ctx := context.Background()
+	mockDB.projects = []db.Project{
+		{ADOProjectName: "A", AsanaProjectGID: "proj-1"},
+		{ADOProjectName: "B", AsanaProjectGID: "proj-2"},
+		{ADOProjectName: "C", AsanaProjectGID: "proj-2"},
+		{ADOProjectName: "D"},
+	}
+	quiet := time.Now().Add(-48 * time.Hour)
+	mockDB.webhooks["proj-2"] = db.AsanaWebhook{AsanaProjectGID: "proj-2", WebhookGID: "old-2", Target: app.Hooks.asanaTarget("proj-2"), CreatedAt: quiet, LastEventAt: quiet}
+	mockDB.webhooks["proj-3"] = db.AsanaWebhook{AsanaProjectGID: "proj-3", WebhookGID: "old-3"}
+
+	app.reconcileWebhooks(ctx)
+
+	assert.ElementsMatch(t, []string{"proj-1", "proj-2"}, mockAsana.webhooksCreated)
+	assert.ElementsMatch(t, []string{"old-2", "old-3"}, mockAsana.webhooksDeleted, "quiet and unmapped webhooks are deleted")
+	assert.Equal(t, "webhook-proj-1", mockDB.webhooks["proj-1"].WebhookGID)
+	assert.Equal(t, "https://sync.example.com/hooks/asana/proj-1", mockDB.webhooks["proj-1"].Target)
+	assert.NotContains(t, mockDB.webhooks, "proj-3")
+
+	mockAsana.webhooksCreated = nil
+	app.reconcileWebhooks(ctx)
+	assert.Empty(t, mockAsana.webhooksCreated, "live webhooks are kept")
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// pollAsanaEvents polls the events of the mapped Asana projects every
+// interval until ctx is cancelled, as a replacement for Asana webhooks
+// where the sync service cannot be reached from the internet. Only the
+// controller replica polls. It returns straight away when interval is zero.
+func (app *App) pollAsanaEvents(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	log.Infof("polling Asana events every %v", interval)
+	for {
+		if app.Elector.IsLeader() {
+			app.pollEvents(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// pollEvents reads the new events of every mapped Asana project and queues
+// the tasks they changed. Projects no longer mapped stop being polled.
+// Failures are logged and retried on the next poll.
+func (app *App) pollEvents(ctx context.Context) {
+	ctx, span := app.Tracer.Start(ctx, "sync.pollEvents")
+	defer span.End()
+
+	projects, err := app.DB.Projects(ctx)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		log.WithError(err).Warn("error getting projects to poll Asana events")
+		return
+	}
+	syncs, err := app.DB.AsanaEventSyncs(ctx)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		log.WithError(err).Warn("error getting Asana sync tokens")
+		return
+	}
+	tokens := make(map[string]string)
+	for _, s := range syncs {
+		tokens[s.AsanaProjectGID] = s.SyncToken
+	}
+
+	// An Asana project may be mapped from several ADO projects.
+	wanted := make(map[string][]string)
+	for _, p := range projects {
+		if p.AsanaProjectGID != "" {
+			wanted[p.AsanaProjectGID] = append(wanted[p.AsanaProjectGID], p.ADOProjectName)
+		}
+	}
+	span.SetAttributes(attribute.Int("projects", len(wanted)))
+
+	for gid, adoProjects := range wanted {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := app.pollProjectEvents(ctx, gid, tokens[gid], adoProjects); err != nil {
+			span.RecordError(err)
+			log.WithError(err).WithField("asana_project_gid", gid).Warn("error polling Asana events")
+		}
+	}
+
+	for gid := range tokens {
+		if _, ok := wanted[gid]; ok {
+			continue
+		}
+		if err := app.DB.RemoveAsanaEventSync(ctx, gid); err != nil {
+			log.WithError(err).WithField("asana_project_gid", gid).Warn("error removing Asana sync token")
+		}
+	}
+}
+
+// pollProjectEvents reads the events of the Asana project following token
+// and queues the tasks they changed, saving the token after each page. A
+// project polled for the first time only gets a token. When the token
+// expired the events missed are unknown, so every task of the project is
+// checked instead.
+func (app *App) pollProjectEvents(ctx context.Context, gid, token string, adoProjects []string) error {
+	plog := log.WithField("asana_project_gid", gid)
+	for {
+		page, err := app.Asana.Events(ctx, gid, token)
+		if errors.Is(err, asana.ErrSyncExpired) && page.Sync != "" {
+			if token != "" {
+				plog.Warn("Asana sync token expired, checking every task of the project")
+				n, err := app.checkProjectTasks(ctx, gid, adoProjects)
+				if err != nil {
+					return err
+				}
+				plog.WithField("queued", n).Info("queued the tasks of the project to be checked")
+			}
+			return app.DB.WriteAsanaSyncToken(ctx, gid, page.Sync)
+		}
+		if err != nil {
+			return err
+		}
+
+		n, err := app.handleAsanaEvents(ctx, page.Events)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			plog.WithField("queued", n).Info("queued tasks changed in Asana")
+		}
+		if err := app.DB.WriteAsanaSyncToken(ctx, gid, page.Sync); err != nil {
+			return err
+		}
+		if !page.HasMore {
+			return nil
+		}
+		token = page.Sync
+	}
+}
+
+// checkProjectTasks queues every task of the Asana project to be compared
+// with its work item. It returns the number of tasks queued.
+func (app *App) checkProjectTasks(ctx context.Context, gid string, adoProjects []string) (int, error) {
+	tasks, err := app.DB.Tasks(ctx, adoProjects...)
+	if err != nil {
+		return 0, err
+	}
+	queued := 0
+	for _, t := range tasks {
+		if t.AsanaProjectID != gid {
+			continue
+		}
+		if err := app.DB.CheckAsanaTask(ctx, t.ADOTaskID, t.ADOProjectID); err != nil {
+			return queued, err
+		}
+		queued++
+	}
+	return queued, nil
+}
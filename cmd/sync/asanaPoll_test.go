@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupPollTest(t *testing.T) (*App, *enhancedMockDB, *enhancedMockAsana) {
+	t.Helper()
+	app, mockDB, mockAsana, mapping := setupFingerprintTest(t)
+	mockDB.projects = []db.Project{{ADOProjectName: "TestProject", AsanaProjectGID: "proj-1"}}
+	mapping.ADORevision = 2
+	mockDB.tasks[123] = mapping
+	mockDB.tasks[456] = db.TaskMapping{ADOProjectID: "TestProject", ADOTaskID: 456, AsanaProjectID: "proj-1", AsanaTaskID: "task-2"}
+	mockDB.tasks[789] = db.TaskMapping{ADOProjectID: "TestProject", ADOTaskID: 789, AsanaProjectID: "proj-other", AsanaTaskID: "task-3"}
+	return app, mockDB, mockAsana
+}
+
+func taskEvent(gid string) asana.Event {
+	return asana.Event{Action: "changed", Resource: asana.EventResource{GID: gid, ResourceType: "task"}}
+}
+
+func TestPollEventsStartsWithFreshToken(t *testing.T) {
+	app, mockDB, mockAsana := setupPollTest(t)
+
+	app.pollEvents(context.Background())
+
+	assert.Equal(t, []string{""}, mockAsana.eventSyncs)
+	assert.Equal(t, "fresh", mockDB.eventSyncs["proj-1"])
+	assert.Empty(t, mockDB.jobs, "nothing is checked before the first token")
+}
+
+func TestPollEventsQueuesChangedTasks(t *testing.T) {
+	app, mockDB, mockAsana := setupPollTest(t)
+	mockDB.eventSyncs["proj-1"] = "tok-1"
+	mockAsana.eventPages["proj-1"] = []asana.EventPage{
+		{Events: []asana.Event{taskEvent("task-1"), taskEvent("task-1")}, Sync: "tok-2", HasMore: true},
+		{Events: []asana.Event{taskEvent("task-9")}, Sync: "tok-3"},
+	}
+
+	app.pollEvents(context.Background())
+
+	assert.Equal(t, []string{"tok-1", "tok-2"}, mockAsana.eventSyncs)
+	assert.Equal(t, "tok-3", mockDB.eventSyncs["proj-1"])
+	assert.Len(t, mockDB.jobs, 1)
+	assert.True(t, mockDB.jobs[123].CheckAsana)
+}
+
+func TestPollEventsKeepsTokenWhenQueuingFails(t *testing.T) {
+	app, mockDB, mockAsana := setupPollTest(t)
+	mockDB.eventSyncs["proj-1"] = "tok-1"
+	mockDB.errors["TaskByAsanaTaskID"] = assert.AnError
+	mockAsana.eventPages["proj-1"] = []asana.EventPage{{Events: []asana.Event{taskEvent("task-1")}, Sync: "tok-2"}}
+
+	app.pollEvents(context.Background())
+
+	assert.Equal(t, "tok-1", mockDB.eventSyncs["proj-1"], "the events are read again next poll")
+}
+
+func TestPollEventsChecksEveryTaskWhenTokenExpired(t *testing.T) {
+	app, mockDB, mockAsana := setupPollTest(t)
+	mockDB.eventSyncs["proj-1"] = "tok-1"
+	mockAsana.errors["Events"] = &asana.APIError{StatusCode: http.StatusPreconditionFailed}
+
+	app.pollEvents(context.Background())
+
+	assert.Equal(t, "fresh", mockDB.eventSyncs["proj-1"])
+	assert.Len(t, mockDB.jobs, 2, "tasks of other Asana projects are left alone")
+	assert.True(t, mockDB.jobs[123].CheckAsana)
+	assert.True(t, mockDB.jobs[456].CheckAsana)
+}
+
+func TestPollEventsForgetsUnmappedProjects(t *testing.T) {
+	app, mockDB, _ := setupPollTest(t)
+	mockDB.eventSyncs["proj-1"] = "tok-1"
+	mockDB.eventSyncs["proj-gone"] = "tok-9"
+
+	app.pollEvents(context.Background())
+
+	assert.Equal(t, map[string]string{"proj-1": "tok-1"}, mockDB.eventSyncs)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/azure"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+)
+
+// Breaker tracks the controller cycles that failed in a row. Once Threshold
+// cycles failed the controller is degraded. After a failure the next cycle
+// runs after RetryDelay, doubled for every further failure up to MaxDelay,
+// instead of the usual sleep time.
+type Breaker struct {
+	Threshold  int
+	RetryDelay time.Duration
+	MaxDelay   time.Duration
+
+	failures    int
+	lastErr     error
+	lastSuccess time.Time
+	lastFailure time.Time
+}
+
+func getBreaker() *Breaker {
+	return &Breaker{
+		Threshold:  max(getInt("CONTROLLER_FAILURE_THRESHOLD", 3), 1),
+		RetryDelay: getDuration("CONTROLLER_RETRY_DELAY", 30*time.Second),
+		MaxDelay:   getDuration("CONTROLLER_MAX_RETRY_DELAY", 30*time.Minute),
+	}
+}
+
+// Record counts the outcome of a controller cycle.
+func (b *Breaker) Record(err error) {
+	if err == nil {
+		b.failures = 0
+		b.lastErr = nil
+		b.lastSuccess = time.Now()
+		return
+	}
+	b.failures++
+	b.lastErr = err
+	b.lastFailure = time.Now()
+}
+
+// Degraded reports whether enough cycles failed in a row to flag the
+// controller.
+func (b *Breaker) Degraded() bool {
+	return b.failures >= b.Threshold
+}
+
+// Delay returns how long to wait before the next cycle: interval after a
+// success, the backoff after a failure.
+func (b *Breaker) Delay(interval time.Duration) time.Duration {
+	if b.failures == 0 {
+		return interval
+	}
+	d := b.RetryDelay
+	for i := 1; i < b.failures && d < b.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, b.MaxDelay)
+}
+
+// Status returns the controller health to report, with the next cycle
+// starting after delay.
+func (b *Breaker) Status(holder string, delay time.Duration) db.ControllerStatus {
+	status := db.ControllerStatus{
+		State:               db.ControllerHealthy,
+		Holder:              holder,
+		ConsecutiveFailures: b.failures,
+		LastSuccessAt:       b.lastSuccess,
+		LastFailureAt:       b.lastFailure,
+		NextRunAt:           time.Now().Add(delay),
+	}
+	if b.Degraded() {
+		status.State = db.ControllerDegraded
+	}
+	if b.lastErr != nil {
+		status.LastError = b.lastErr.Error()
+	}
+	return status
+}
+
+// unrecoverableError reports whether an error comes from the configuration,
+// such as a rejected ADO or Asana token, and would fail every cycle and job
+// until the service is reconfigured. Joined errors are unrecoverable when
+// all of them are.
+func unrecoverableError(err error) bool {
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) {
+		for _, e := range joined.Unwrap() {
+			if !unrecoverableError(e) {
+				return false
+			}
+		}
+		return true
+	}
+	return errors.Is(err, azure.ErrUnauthorized) || errors.Is(err, asana.ErrUnauthorized)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/azure"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+)
+
+func TestBreakerBacksOffAndDegrades(t *testing.T) {
+	b := &Breaker{Threshold: 2, RetryDelay: time.Minute, MaxDelay: 3 * time.Minute}
+	assert.Equal(t, 5*time.Minute, b.Delay(5*time.Minute), "usual interval while healthy")
+
+	b.Record(errors.New("ado down"))
+	assert.Equal(t, time.Minute, b.Delay(5*time.Minute))
+	assert.False(t, b.Degraded())
+
+	b.Record(errors.New("ado down"))
+	assert.Equal(t, 2*time.Minute, b.Delay(5*time.Minute))
+	assert.True(t, b.Degraded())
+	status := b.Status("replica-1", time.Minute)
+	assert.Equal(t, db.ControllerDegraded, status.State)
+	assert.Equal(t, 2, status.ConsecutiveFailures)
+	assert.Equal(t, "ado down", status.LastError)
+
+	b.Record(errors.New("ado down"))
+	assert.Equal(t, 3*time.Minute, b.Delay(5*time.Minute), "capped")
+
+	b.Record(nil)
+	assert.Equal(t, 5*time.Minute, b.Delay(5*time.Minute))
+	status = b.Status("replica-1", time.Minute)
+	assert.Equal(t, db.ControllerHealthy, status.State)
+	assert.Empty(t, status.LastError)
+	assert.False(t, status.LastSuccessAt.IsZero())
+}
+
+func TestUnrecoverableError(t *testing.T) {
+	unauthorized := fmt.Errorf("project A: %w", azure.ErrUnauthorized)
+
+	assert.True(t, unrecoverableError(unauthorized))
+	assert.True(t, unrecoverableError(fmt.Errorf("all failed: %w", errors.Join(unauthorized, unauthorized))))
+	assert.False(t, unrecoverableError(fmt.Errorf("all failed: %w", errors.Join(unauthorized, errors.New("timeout")))))
+	assert.False(t, unrecoverableError(errors.New("mongo down")))
+	assert.True(t, unrecoverableError(&asana.APIError{StatusCode: 401}))
+}
+
+func TestControllerReportsFailedCycle(t *testing.T) {
+	md := newMockDB("A", "B")
+	ma := newMockAzure()
+	ma.errors["A"] = fmt.Errorf("ado down")
+	app := &App{Azure: ma, DB: md, Elector: newLeader(md), Tracer: otel.Tracer("test")}
+
+	_, err := app.controller(context.Background(), time.Minute)
+	assert.NoError(t, err, "one project still synced")
+
+	ma.errors["B"] = fmt.Errorf("ado down")
+	delete(md.lastRuns, "B")
+	_, err = app.controller(context.Background(), time.Minute)
+	assert.Error(t, err)
+
+	md.projectsErr = fmt.Errorf("mongo down")
+	_, err = app.controller(context.Background(), time.Minute)
+	assert.Error(t, err)
+}
+
+func TestRunControllerRecordsStatus(t *testing.T) {
+	md := newMockDB("A")
+	ma := newMockAzure()
+	ma.errors["A"] = fmt.Errorf("ado down")
+	app := &App{
+		Azure:      ma,
+		Asana:      newEnhancedMockAsana(),
+		DB:         md,
+		Elector:    newLeader(md),
+		Resolver:   NewResolver(md, time.Hour),
+		Tracer:     otel.Tracer("test"),
+		InstanceID: "replica-1",
+		Breaker:    &Breaker{Threshold: 1, RetryDelay: time.Second, MaxDelay: time.Minute},
+	}
+
+	delay, err := app.runController(context.Background(), time.Hour)
+
+	assert.NoError(t, err, "a transient failure does not stop the service")
+	assert.Equal(t, time.Second, delay)
+	assert.Equal(t, db.ControllerDegraded, md.status.State)
+	assert.Equal(t, "replica-1", md.status.Holder)
+
+	delete(ma.errors, "A")
+	delay, err = app.runController(context.Background(), time.Hour)
+
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Hour, delay, float64(time.Second), "the project is due again in an hour")
+	assert.Equal(t, db.ControllerHealthy, md.status.State)
+}
+
+func TestRunControllerStopsOnUnrecoverableError(t *testing.T) {
+	md := newMockDB("A")
+	ma := newMockAzure()
+	ma.errors["A"] = fmt.Errorf("query: %w", azure.ErrUnauthorized)
+	app := &App{
+		Azure:    ma,
+		Asana:    newEnhancedMockAsana(),
+		DB:       md,
+		Elector:  newLeader(md),
+		Resolver: NewResolver(md, time.Hour),
+		Tracer:   otel.Tracer("test"),
+		Breaker:  &Breaker{Threshold: 1, RetryDelay: time.Second, MaxDelay: time.Minute},
+	}
+
+	_, err := app.runController(context.Background(), time.Hour)
+
+	assert.ErrorIs(t, err, azure.ErrUnauthorized)
+}
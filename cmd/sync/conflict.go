@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/azure"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	log "github.com/sirupsen/logrus"
+)
+
+// Who keeps a synced field once it has been edited in Asana.
+const (
+	// ownerADO overwrites the Asana edit from ADO.
+	ownerADO = "ado"
+	// ownerAsana keeps the Asana edit and stops syncing the field.
+	ownerAsana = "asana"
+	// ownerConflict keeps the Asana edit and records a conflict for review.
+	ownerConflict = "conflict"
+)
+
+// FieldPolicy maps the task fields synced from ADO (name and notes) to who
+// keeps them once edited in Asana. Custom fields always follow ADO.
+type FieldPolicy map[string]string
+
+// owner returns the policy for the field, flagging a conflict by default.
+func (p FieldPolicy) owner(field string) string {
+	if o, ok := p[field]; ok {
+		return o
+	}
+	return ownerConflict
+}
+
+func getFieldPolicy() FieldPolicy {
+	return parseFieldPolicy(os.Getenv("ASANA_FIELD_POLICY"))
+}
+
+// parseFieldPolicy reads a list such as "name=conflict,notes=asana".
+// Unknown fields and owners are ignored.
+func parseFieldPolicy(v string) FieldPolicy {
+	policy := FieldPolicy{}
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		field, owner, _ := strings.Cut(entry, "=")
+		field = strings.ToLower(strings.TrimSpace(field))
+		owner = strings.ToLower(strings.TrimSpace(owner))
+		if field != fingerprintName && field != fingerprintNotes {
+			log.WithField("field", field).Warn("unknown field in ASANA_FIELD_POLICY, ignoring")
+			continue
+		}
+		switch owner {
+		case ownerADO, ownerAsana, ownerConflict:
+			policy[field] = owner
+		default:
+			log.WithField("field", field).WithField("owner", owner).Warn("unknown owner in ASANA_FIELD_POLICY, ignoring")
+		}
+	}
+	return policy
+}
+
+// applyFieldPolicy removes the fields owned by Asana from the name and notes
+// about to be sent, and checks the others for edits made in Asana since the
+// last sync: their value in Asana no longer matches what Asana stored when
+// they were last sent. An edited field is handed over to Asana unless the policy says
+// ADO wins. send maps the fields to the changed values to send, empty for
+// unchanged fields, and rendered to their current ADO value. With check
+// set, as after the task was seen changing in Asana, edits of unchanged
+// fields are handled too, sending them again when ADO wins. Without a
+// fingerprint, as on a forced resync, there is nothing to compare against
+// and only the owned fields are skipped.
+func (app *App) applyFieldPolicy(ctx context.Context, wi azure.WorkItem, mapping *db.TaskMapping, rendered, send map[string]string, check bool) error {
+	for _, f := range mapping.AsanaOwnedFields {
+		send[f] = ""
+	}
+	pending := send[fingerprintName] != "" || send[fingerprintNotes] != ""
+	if (!pending && !check) || mapping.AsanaFingerprint == nil {
+		return nil
+	}
+
+	current, err := app.Asana.TaskByGID(ctx, mapping.AsanaTaskID)
+	if err != nil {
+		app.forgetOnNotFound(ctx, err, mapping.AsanaProjectID)
+		return err
+	}
+	inAsana := storedFields(current)
+	for _, field := range []string{fingerprintName, fingerprintNotes} {
+		if slices.Contains(mapping.AsanaOwnedFields, field) {
+			continue
+		}
+		last, ok := mapping.AsanaStored[field]
+		if !ok {
+			// Sent before Asana's copy was recorded: compare with what was
+			// sent, as long as the task changed since.
+			if !current.ModifiedAt.After(mapping.AsanaLastUpdated) {
+				continue
+			}
+			last = mapping.AsanaFingerprint[field]
+		}
+		if hashField(inAsana[field]) == last {
+			continue
+		}
+		if send[field] == "" && !check {
+			continue
+		}
+		flog := log.WithField("ado_task_id", wi.ID).WithField("field", field)
+		switch app.FieldPolicy.owner(field) {
+		case ownerADO:
+			flog.Info("field edited in Asana, overwriting from ADO")
+			send[field] = rendered[field]
+			continue
+		case ownerConflict:
+			flog.Warn("field edited in Asana and ADO, recording conflict")
+			err := app.DB.AddConflict(ctx, db.Conflict{
+				ADOTaskID:      wi.ID,
+				ADOProjectName: mapping.ADOProjectID,
+				AsanaTaskID:    mapping.AsanaTaskID,
+				Field:          field,
+				ADOValue:       rendered[field],
+				AsanaValue:     inAsana[field],
+			})
+			if err != nil {
+				return err
+			}
+		default:
+			flog.Info("field edited in Asana, keeping the Asana value")
+		}
+		mapping.AsanaOwnedFields = append(mapping.AsanaOwnedFields, field)
+		send[field] = ""
+	}
+	return nil
+}
+
+// readBack records on the mapping the hash of the fields just sent as Asana
+// stored them, and when Asana last modified the task. When the task cannot
+// be read the fields are compared with what was sent instead.
+func (app *App) readBack(ctx context.Context, mapping *db.TaskMapping, fields ...string) {
+	t, err := app.Asana.TaskByGID(ctx, mapping.AsanaTaskID)
+	if err != nil {
+		log.WithError(err).WithField("asana_task_id", mapping.AsanaTaskID).Warn("error reading back Asana task")
+		for _, f := range fields {
+			delete(mapping.AsanaStored, f)
+		}
+		mapping.AsanaLastUpdated = time.Now()
+		return
+	}
+	if mapping.AsanaStored == nil {
+		mapping.AsanaStored = make(map[string]string)
+	}
+	stored := storedFields(t)
+	for _, f := range fields {
+		mapping.AsanaStored[f] = hashField(stored[f])
+	}
+	mapping.AsanaLastUpdated = t.ModifiedAt
+}
+
+// storedFields returns the name and notes of the task as compared with the
+// values last stored.
+func storedFields(t asana.Task) map[string]string {
+	return map[string]string{
+		fingerprintName:  t.Name,
+		fingerprintNotes: stripHTMLBody(t.HTMLNotes),
+	}
+}
+
+// keepOwnedFingerprint carries over the last fingerprint of the fields owned
+// by Asana, since their new value was not sent.
+func keepOwnedFingerprint(fp, last map[string]string, owned []string) {
+	for _, f := range owned {
+		if h, ok := last[f]; ok {
+			fp[f] = h
+		} else {
+			delete(fp, f)
+		}
+	}
+}
+
+// stripHTMLBody removes the <body> element Asana wraps HTML notes in.
+func stripHTMLBody(notes string) string {
+	notes = strings.TrimPrefix(notes, "<body>")
+	return strings.TrimSuffix(notes, "</body>")
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFieldPolicy(t *testing.T) {
+	assert.Equal(t, FieldPolicy{}, parseFieldPolicy(""))
+	assert.Equal(t, FieldPolicy{"name": ownerADO, "notes": ownerAsana}, parseFieldPolicy(" name=ADO , notes=asana"))
+	assert.Equal(t, FieldPolicy{"notes": ownerConflict}, parseFieldPolicy("title=ado,name=nobody,notes=conflict"))
+	assert.Equal(t, ownerConflict, FieldPolicy{}.owner(fingerprintName), "conflict by default")
+}
+
+// setupConflictTest renames the work item in ADO after its Asana task was
+// renamed by hand.
+func setupConflictTest(t *testing.T, policy FieldPolicy) (*App, *enhancedMockDB, *enhancedMockAsana) {
+	t.Helper()
+	app, mockDB, mockAsana, mapping := setupFingerprintTest(t)
+	app.FieldPolicy = policy
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	desc, _ := mockAzure.workItems[123].FormatTitleWithLink()
+
+	mapping.AsanaLastUpdated = time.Now().Add(-time.Hour)
+	mockDB.tasks[123] = mapping
+	mockAsana.remote["task-1"] = asana.Task{
+		GID:        "task-1",
+		Name:       "Renamed in Asana",
+		HTMLNotes:  "<body>" + desc + "</body>",
+		ModifiedAt: time.Now(),
+	}
+
+	wi := mockAzure.workItems[123]
+	wi.Title = "Renamed in ADO"
+	mockAzure.workItems[123] = wi
+	return app, mockDB, mockAsana
+}
+
+func TestHandleTaskRecordsConflictForAsanaEdit(t *testing.T) {
+	app, mockDB, mockAsana := setupConflictTest(t, nil)
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 123})
+
+	assert.NoError(t, err)
+	assert.Len(t, mockAsana.taskChanges, 1)
+	assert.Empty(t, mockAsana.taskChanges[0].Name, "the Asana edit is kept")
+	assert.Contains(t, mockAsana.taskChanges[0].Notes, "Renamed in ADO", "unedited notes still follow ADO")
+	assert.Equal(t, []string{fingerprintName}, mockDB.tasks[123].AsanaOwnedFields)
+	c := mockDB.conflicts[conflictKey(123, fingerprintName)]
+	assert.Equal(t, "Renamed in Asana", c.AsanaValue)
+	assert.Contains(t, c.ADOValue, "Renamed in ADO")
+	assert.Len(t, mockDB.conflicts, 1)
+}
+
+func TestHandleTaskIgnoresAsanaRewrite(t *testing.T) {
+	app, mockDB, mockAsana := setupConflictTest(t, nil)
+	// Asana stored the name and notes sent last in its own form, and its
+	// clock is ahead of ours.
+	rewritten := asana.Task{
+		GID:        "task-1",
+		Name:       "User Story 123: Test Task",
+		HTMLNotes:  `<body><a href="http://ado.com/123" target="_blank">User Story 123:</a> Test Task</body>`,
+		ModifiedAt: time.Now().Add(time.Minute),
+	}
+	mockAsana.remote["task-1"] = rewritten
+	mapping := mockDB.tasks[123]
+	mapping.AsanaStored = map[string]string{
+		fingerprintName:  hashField(rewritten.Name),
+		fingerprintNotes: hashField(stripHTMLBody(rewritten.HTMLNotes)),
+	}
+	mockDB.tasks[123] = mapping
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 123})
+
+	assert.NoError(t, err)
+	assert.Empty(t, mockDB.conflicts)
+	assert.Contains(t, mockAsana.taskChanges[0].Name, "Renamed in ADO")
+	assert.Contains(t, mockAsana.taskChanges[0].Notes, "Renamed in ADO")
+	// The mock keeps the task as it was, standing in for Asana's copy.
+	assert.Equal(t, hashField(rewritten.Name), mockDB.tasks[123].AsanaStored[fingerprintName])
+	assert.Equal(t, rewritten.ModifiedAt, mockDB.tasks[123].AsanaLastUpdated, "Asana's clock is kept")
+}
+
+func TestHandleTaskKeepsAsanaOwnedField(t *testing.T) {
+	app, mockDB, mockAsana := setupConflictTest(t, FieldPolicy{fingerprintName: ownerAsana})
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 123})
+
+	assert.NoError(t, err)
+	assert.Empty(t, mockAsana.taskChanges[0].Name)
+	assert.Equal(t, []string{fingerprintName}, mockDB.tasks[123].AsanaOwnedFields)
+	assert.Empty(t, mockDB.conflicts)
+
+	// A forced resync still leaves the field alone, without looking at
+	// Asana again.
+	mockAsana.errors["TaskByGID"] = assert.AnError
+	mockAsana.taskChanges = nil
+	assert.NoError(t, mockDB.ForceResync(context.Background(), 123))
+	app.processJobs(context.Background(), context.Background(), log.WithField("test", "worker"), "test/1")
+
+	assert.Len(t, mockAsana.taskChanges, 1)
+	assert.Empty(t, mockAsana.taskChanges[0].Name)
+	assert.NotEmpty(t, mockAsana.taskChanges[0].Notes)
+}
+
+func TestHandleTaskOverwritesAsanaEditWhenADOWins(t *testing.T) {
+	app, mockDB, mockAsana := setupConflictTest(t, FieldPolicy{fingerprintName: ownerADO})
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 123})
+
+	assert.NoError(t, err)
+	assert.Contains(t, mockAsana.taskChanges[0].Name, "Renamed in ADO")
+	assert.Empty(t, mockDB.tasks[123].AsanaOwnedFields)
+	assert.Empty(t, mockDB.conflicts)
+}
+
+func TestResolveConflictWithADOResendsField(t *testing.T) {
+	app, mockDB, mockAsana := setupConflictTest(t, nil)
+	ctx := context.Background()
+	wlog := log.WithField("test", "worker")
+	_, err := app.handleTask(ctx, wlog, SyncTask{ADOTaskID: 123})
+	assert.NoError(t, err)
+	mockAsana.taskChanges = nil
+
+	assert.NoError(t, mockDB.ResolveConflict(ctx, 123, fingerprintName, true))
+	app.processJobs(ctx, ctx, wlog, "test/1")
+
+	assert.Empty(t, mockDB.conflicts)
+	assert.Empty(t, mockDB.tasks[123].AsanaOwnedFields)
+	assert.Len(t, mockAsana.taskChanges, 1)
+	assert.Contains(t, mockAsana.taskChanges[0].Name, "Renamed in ADO")
+}
@@ -2,48 +2,162 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
 	"time"
 
+	"github.com/ADO-Asana-Sync/sync-engine/internal/azure"
 	log "github.com/sirupsen/logrus"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
-func (app *App) controller(ctx context.Context, syncTasks chan<- SyncTask) {
+// controller queues the work items changed in each mapped ADO project since
+// its checkpoint. Queued jobs survive restarts, so a project's checkpoint
+// advances as soon as all of its changes are queued; the workers sync them
+// and retry the ones that fail. Projects are only queried when their
+// schedule says they are due, interval apart when they have none. It
+// returns the earliest time a project is due next, and an error when the
+// cycle failed as a whole: the projects could not be read, or ADO failed
+// for every project due.
+func (app *App) controller(ctx context.Context, interval time.Duration) (time.Time, error) {
 	// Configure the tracing.
 	ctx, span := app.Tracer.Start(ctx, "sync.controller")
 	defer span.End()
 
 	log.Info("controller started")
 
-	// List all ADO projects
-	lastSync := app.DB.LastSync(ctx)
-	log.WithField("lastSyncTime", lastSync.Time).Info("get items modified since last sync")
-	items, err := app.Azure.GetChangedWorkItems(ctx, lastSync.Time)
+	projects, err := app.DB.Projects(ctx)
 	if err != nil {
 		span.RecordError(err, trace.WithStackTrace(true))
 		span.SetStatus(codes.Error, err.Error())
-		log.WithError(err).Fatal("error getting projects")
+		log.WithError(err).Error("error getting projects")
+		return time.Time{}, fmt.Errorf("error getting projects: %w", err)
 	}
 
-	resultCh := make(chan error, len(items))
-	for _, t := range items {
-		syncTasks <- SyncTask{ADOTaskID: *t.Id, AsanaTaskID: "", Result: resultCh}
-	}
+	// Each mapped ADO project keeps its own checkpoint so one failing project
+	// does not hold back the others.
+	seen := make(map[string]bool)
+	queued, due := 0, 0
+	var next nextDue
+	var queryErrs []error
+	for _, p := range projects {
+		if seen[p.ADOProjectName] {
+			continue
+		}
+		seen[p.ADOProjectName] = true
+		cp, err := app.DB.SyncCheckpoint(ctx, p.ADOProjectName)
+		if err != nil {
+			// Without its checkpoint the project would be queried from the
+			// start; try again next cycle.
+			span.RecordError(err, trace.WithStackTrace(true))
+			log.WithError(err).WithField("project", p.ADOProjectName).Error("error getting sync checkpoint, skipping project")
+			continue
+		}
 
-	success := true
-	for i := 0; i < len(items); i++ {
-		if err := <-resultCh; err != nil {
-			success = false
+		sched := projectSchedule(p)
+		now := time.Now()
+		if at := sched.Next(cp.LastRunAt, now, interval); at.IsZero() || now.Before(at) {
+			next.add(at)
+			if !at.Equal(cp.NextRunAt) {
+				// The schedule changed since the last run.
+				if err := app.DB.WriteSyncRun(ctx, p.ADOProjectName, cp.LastRunAt, at); err != nil {
+					log.WithError(err).WithField("project", p.ADOProjectName).Warn("error writing next sync run")
+				}
+			}
+			continue
 		}
-	}
+		due++
 
-	if success {
-		if err := app.DB.WriteLastSync(ctx, time.Now()); err != nil {
+		// Query from slightly before the checkpoint so items saved in ADO
+		// with a ChangedDate just behind it are not missed. Changes already
+		// queued are not queued again.
+		since := cp.Time.Add(-app.WatermarkOverlap)
+		plog := log.WithField("project", p.ADOProjectName).WithField("since", since)
+		plog.Info("get items modified since last sync")
+		items, asOf, err := app.Azure.GetChangedWorkItems(ctx, p.ADOProjectName, since)
+		if err != nil {
+			span.RecordError(err, trace.WithStackTrace(true))
+			plog.WithError(err).Error("error getting changed work items")
+			queryErrs = append(queryErrs, err)
+			continue
+		}
+
+		ids := make([]int, 0, len(items))
+		for _, t := range items {
+			ids = append(ids, *t.Id)
+		}
+		sort.Ints(ids)
+		if err := app.enqueue(ctx, p.ADOProjectName, ids, asOf); err != nil {
+			// The checkpoint is not advanced, even for the items queued
+			// before the failure, so the rest are queried again.
+			span.RecordError(err, trace.WithStackTrace(true))
+			plog.WithError(err).Error("error queuing changed work items")
+			continue
+		}
+		queued += len(ids)
+
+		at := sched.Next(now, now, interval)
+		next.add(at)
+		if err := app.DB.WriteSyncRun(ctx, p.ADOProjectName, now, at); err != nil {
+			span.RecordError(err, trace.WithStackTrace(true))
+			plog.WithError(err).Error("error writing sync run")
+		}
+
+		// Every change made before the query ran is now queued. Without the
+		// ADO time of the query the checkpoint is kept, and the items queried
+		// again next cycle.
+		if !asOf.After(cp.Time) {
+			continue
+		}
+		// Another replica took over if the lease expired during the cycle;
+		// leave the checkpoints to it.
+		if !app.Elector.IsLeader() {
+			plog.Warn("controller lease lost, not writing sync checkpoint")
+			break
+		}
+		if err := app.DB.WriteSyncCheckpoint(ctx, p.ADOProjectName, asOf); err != nil {
 			span.RecordError(err, trace.WithStackTrace(true))
 			span.SetStatus(codes.Error, err.Error())
-			log.WithError(err).Error("error writing last sync time")
+			plog.WithError(err).Error("error writing sync checkpoint")
+		}
+	}
+	span.SetAttributes(attribute.Int("items", queued), attribute.Int("projects_due", due))
+
+	if len(queryErrs) > 0 && len(queryErrs) == due {
+		err := fmt.Errorf("error getting changed work items of all %d projects due: %w", due, errors.Join(queryErrs...))
+		span.SetStatus(codes.Error, err.Error())
+		return time.Time(next), err
+	}
+	return time.Time(next), nil
+}
+
+// enqueue queues a job for each work item, changed as of the time the query
+// ran, and stops at the first job it fails to queue. The workers hydrate the
+// work items when they sync them.
+func (app *App) enqueue(ctx context.Context, project string, ids []int, asOf time.Time) error {
+	for _, id := range ids {
+		if err := app.DB.EnqueueJob(ctx, id, project, asOf); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// hydrate fetches the work items in a single batch request. Work items
+// missing from the result, or all of them when the request fails, are left
+// for the caller to fetch individually.
+func (app *App) hydrate(ctx context.Context, ids []int) map[int]azure.WorkItem {
+	hydrated := make(map[int]azure.WorkItem, len(ids))
+	items, err := app.Azure.GetWorkItemsBatch(ctx, ids, false)
+	if err != nil {
+		log.WithError(err).Warn("error fetching work item batch, fetching them individually")
+	}
+	for _, wi := range items {
+		hydrated[wi.ID] = wi
+	}
+	return hydrated
 }
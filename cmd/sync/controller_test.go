@@ -10,67 +10,385 @@ import (
 	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
+	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.opentelemetry.io/otel"
 )
 
-type mockDB struct{ wrote bool }
+type mockDB struct {
+	projects    []db.Project
+	checkpoints map[string]time.Time
+	written     map[string]time.Time
+	lastRuns    map[string]time.Time
+	nextRuns    map[string]time.Time
+	queued      map[int]db.Job
+	enqueueErr  error
+	enqueueErrs map[int]error
+	projectsErr error
+	// checkpointErrs fails reading the checkpoint of a project.
+	checkpointErrs map[string]error
+	status         db.ControllerStatus
+}
 
-func (m *mockDB) Connect(ctx context.Context, uri string) error                  { return nil }
-func (m *mockDB) Disconnect(ctx context.Context) error                           { return nil }
-func (m *mockDB) EnsureIndexes(ctx context.Context) error                        { return nil }
-func (m *mockDB) Projects(ctx context.Context) ([]db.Project, error)             { return nil, nil }
+func newMockDB(projects ...string) *mockDB {
+	m := &mockDB{
+		checkpoints: make(map[string]time.Time),
+		written:     make(map[string]time.Time),
+		lastRuns:    make(map[string]time.Time),
+		nextRuns:    make(map[string]time.Time),
+		queued:      make(map[int]db.Job),
+	}
+	for _, p := range projects {
+		m.projects = append(m.projects, db.Project{ADOProjectName: p})
+	}
+	return m
+}
+
+func (m *mockDB) Connect(ctx context.Context, uri string) error { return nil }
+func (m *mockDB) Disconnect(ctx context.Context) error          { return nil }
+func (m *mockDB) EnsureIndexes(ctx context.Context) error       { return nil }
+func (m *mockDB) Projects(ctx context.Context) ([]db.Project, error) {
+	return m.projects, m.projectsErr
+}
 func (m *mockDB) AddProject(ctx context.Context, project db.Project) error       { return nil }
 func (m *mockDB) RemoveProject(ctx context.Context, id primitive.ObjectID) error { return nil }
 func (m *mockDB) UpdateProject(ctx context.Context, project db.Project) error    { return nil }
 func (m *mockDB) LastSync(ctx context.Context) db.LastSync                       { return db.LastSync{} }
-func (m *mockDB) WriteLastSync(ctx context.Context, timestamp time.Time) error {
-	m.wrote = true
+func (m *mockDB) WriteLastSync(ctx context.Context, timestamp time.Time) error   { return nil }
+func (m *mockDB) SyncCheckpoint(ctx context.Context, project string) (db.SyncCheckpoint, error) {
+	if err := m.checkpointErrs[project]; err != nil {
+		return db.SyncCheckpoint{}, err
+	}
+	return db.SyncCheckpoint{
+		ADOProjectName: project,
+		Time:           m.checkpoints[project],
+		LastRunAt:      m.lastRuns[project],
+		NextRunAt:      m.nextRuns[project],
+	}, nil
+}
+func (m *mockDB) WriteSyncCheckpoint(ctx context.Context, project string, timestamp time.Time) error {
+	m.written[project] = timestamp
+	return nil
+}
+func (m *mockDB) WriteSyncRun(ctx context.Context, project string, lastRun, nextRun time.Time) error {
+	m.lastRuns[project] = lastRun
+	m.nextRuns[project] = nextRun
+	return nil
+}
+func (m *mockDB) SyncCheckpoints(ctx context.Context) ([]db.SyncCheckpoint, error) { return nil, nil }
+func (m *mockDB) EnqueueJob(ctx context.Context, adoTaskID int, project string, changed time.Time) error {
+	if m.enqueueErr != nil {
+		return m.enqueueErr
+	}
+	if err := m.enqueueErrs[adoTaskID]; err != nil {
+		return err
+	}
+	m.queued[adoTaskID] = db.Job{ADOTaskID: adoTaskID, ADOProjectName: project, ChangedDate: changed}
+	return nil
+}
+func (m *mockDB) LeaseJobs(ctx context.Context, owner string, shard db.Shard, limit int, lease time.Duration) ([]db.Job, error) {
+	return nil, nil
+}
+func (m *mockDB) CompleteJob(ctx context.Context, job db.Job) error { return nil }
+func (m *mockDB) ReturnJob(ctx context.Context, job db.Job) error   { return nil }
+func (m *mockDB) FailJob(ctx context.Context, job db.Job, class, cause string, retryAt time.Time) error {
+	return nil
+}
+func (m *mockDB) DeadLetterJob(ctx context.Context, job db.Job, class, cause string) error {
 	return nil
 }
+func (m *mockDB) DeadLetters(ctx context.Context) ([]db.DeadLetter, error) { return nil, nil }
+func (m *mockDB) RetryDeadLetter(ctx context.Context, adoTaskID int) error { return nil }
+func (m *mockDB) IgnoreDeadLetter(ctx context.Context, adoTaskID int) error {
+	return nil
+}
+func (m *mockDB) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	return true, nil
+}
+func (m *mockDB) ReleaseLease(ctx context.Context, name, holder string) error { return nil }
+func (m *mockDB) Tasks(ctx context.Context, projectIDs ...string) ([]db.TaskMapping, error) {
+	return nil, nil
+}
 func (m *mockDB) TaskByADOTaskID(ctx context.Context, id int) (db.TaskMapping, error) {
 	return db.TaskMapping{}, nil
 }
-func (m *mockDB) AddTask(ctx context.Context, task db.TaskMapping) error    { return nil }
-func (m *mockDB) UpdateTask(ctx context.Context, task db.TaskMapping) error { return nil }
+func (m *mockDB) AddTask(ctx context.Context, task db.TaskMapping) error        { return nil }
+func (m *mockDB) UpdateTask(ctx context.Context, task db.TaskMapping) error     { return nil }
+func (m *mockDB) RemoveTask(ctx context.Context, id primitive.ObjectID) error   { return nil }
+func (m *mockDB) RenameTasksProject(ctx context.Context, from, to string) error { return nil }
+func (m *mockDB) CreationIntent(ctx context.Context, adoTaskID int) (db.CreationIntent, bool, error) {
+	return db.CreationIntent{}, false, nil
+}
+func (m *mockDB) AddCreationIntent(ctx context.Context, intent db.CreationIntent) error { return nil }
+func (m *mockDB) RemoveCreationIntent(ctx context.Context, adoTaskID int) error         { return nil }
+func (m *mockDB) ForceResync(ctx context.Context, adoTaskID int) error                  { return nil }
+func (m *mockDB) ControllerStatus(ctx context.Context) (db.ControllerStatus, bool, error) {
+	return m.status, m.status.State != "", nil
+}
+func (m *mockDB) WriteControllerStatus(ctx context.Context, status db.ControllerStatus) error {
+	m.status = status
+	return nil
+}
+func (m *mockDB) Conflicts(ctx context.Context) ([]db.Conflict, error) { return nil, nil }
+func (m *mockDB) AddConflict(ctx context.Context, c db.Conflict) error { return nil }
+func (m *mockDB) ResolveConflict(ctx context.Context, adoTaskID int, field string, useADO bool) error {
+	return nil
+}
+func (m *mockDB) AdoptionReviews(ctx context.Context) ([]db.AdoptionReview, error) { return nil, nil }
+func (m *mockDB) AdoptionReviewByADOTaskID(ctx context.Context, adoTaskID int) (db.AdoptionReview, bool, error) {
+	return db.AdoptionReview{}, false, nil
+}
+func (m *mockDB) AddAdoptionReview(ctx context.Context, review db.AdoptionReview) error { return nil }
+func (m *mockDB) DecideAdoptionReview(ctx context.Context, adoTaskID int, decision, asanaTaskID string) error {
+	return nil
+}
+func (m *mockDB) RemoveAdoptionReview(ctx context.Context, adoTaskID int) error { return nil }
 func (m *mockDB) GetCacheItem(ctx context.Context, key string) (db.CacheItem, error) {
 	return db.CacheItem{}, fmt.Errorf("not found")
 }
 func (m *mockDB) UpsertCacheItem(ctx context.Context, item db.CacheItem) error { return nil }
+func (m *mockDB) DeleteCacheItem(ctx context.Context, key string) error        { return nil }
 func (m *mockDB) WorkspaceTag(ctx context.Context, workspaceName string) (db.WorkspaceTag, error) {
 	return db.WorkspaceTag{}, fmt.Errorf("not found")
 }
 func (m *mockDB) UpsertWorkspaceTag(ctx context.Context, tag db.WorkspaceTag) error { return nil }
+func (m *mockDB) WorkItemProjectByADOID(ctx context.Context, id int) (db.WorkItemProject, error) {
+	return db.WorkItemProject{}, fmt.Errorf("error finding work item project: %w", mongo.ErrNoDocuments)
+}
+func (m *mockDB) UpsertWorkItemProject(ctx context.Context, p db.WorkItemProject) error { return nil }
+func (m *mockDB) TaskByAsanaTaskID(ctx context.Context, gid string) (db.TaskMapping, bool, error) {
+	return db.TaskMapping{}, false, nil
+}
+func (m *mockDB) CheckAsanaTask(ctx context.Context, adoTaskID int, project string) error { return nil }
+func (m *mockDB) AsanaWebhooks(ctx context.Context) ([]db.AsanaWebhook, error)            { return nil, nil }
+func (m *mockDB) AsanaWebhook(ctx context.Context, projectGID string) (db.AsanaWebhook, bool, error) {
+	return db.AsanaWebhook{}, false, nil
+}
+func (m *mockDB) RequestAsanaWebhook(ctx context.Context, projectGID, target string) error {
+	return nil
+}
+func (m *mockDB) SetAsanaWebhookSecret(ctx context.Context, projectGID, secret string, since time.Time) (bool, error) {
+	return false, nil
+}
+func (m *mockDB) WriteAsanaWebhookGID(ctx context.Context, projectGID, webhookGID string) error {
+	return nil
+}
+func (m *mockDB) TouchAsanaWebhook(ctx context.Context, projectGID string) error  { return nil }
+func (m *mockDB) RemoveAsanaWebhook(ctx context.Context, projectGID string) error { return nil }
+func (m *mockDB) AsanaEventSyncs(ctx context.Context) ([]db.AsanaEventSync, error) {
+	return nil, nil
+}
+func (m *mockDB) WriteAsanaSyncToken(ctx context.Context, projectGID, token string) error {
+	return nil
+}
+func (m *mockDB) RemoveAsanaEventSync(ctx context.Context, projectGID string) error { return nil }
+func (m *mockDB) Reconciliations(ctx context.Context) ([]db.Reconciliation, error) {
+	return nil, nil
+}
+func (m *mockDB) RequestReconciliation(ctx context.Context, adoProjectName string, repair bool) error {
+	return nil
+}
+func (m *mockDB) WriteReconciliation(ctx context.Context, rec db.Reconciliation) error { return nil }
 
-type mockAzure struct{}
+type mockAzure struct {
+	ids     map[string][]int
+	asOf    map[string]time.Time
+	since   map[string]time.Time
+	errors  map[string]error
+	batches [][]int
+}
+
+func newMockAzure() *mockAzure {
+	return &mockAzure{
+		ids:    make(map[string][]int),
+		asOf:   make(map[string]time.Time),
+		since:  make(map[string]time.Time),
+		errors: make(map[string]error),
+	}
+}
 
 func (m *mockAzure) Connect(ctx context.Context, orgUrl, pat string) {}
-func (m *mockAzure) GetChangedWorkItems(ctx context.Context, lastSync time.Time) ([]workitemtracking.WorkItemReference, error) {
-	return nil, nil
+func (m *mockAzure) GetChangedWorkItems(ctx context.Context, project string, lastSync time.Time) ([]workitemtracking.WorkItemReference, time.Time, error) {
+	m.since[project] = lastSync
+	if err := m.errors[project]; err != nil {
+		return nil, time.Time{}, err
+	}
+	ids := m.ids[project]
+	refs := make([]workitemtracking.WorkItemReference, len(ids))
+	for i := range ids {
+		refs[i] = workitemtracking.WorkItemReference{Id: &ids[i]}
+	}
+	return refs, m.asOf[project], nil
 }
 func (m *mockAzure) GetWorkItem(ctx context.Context, id int) (azure.WorkItem, error) {
 	return azure.WorkItem{}, nil
 }
+func (m *mockAzure) GetWorkItemsBatch(ctx context.Context, ids []int, expandRelations bool) ([]azure.WorkItem, error) {
+	m.batches = append(m.batches, ids)
+	if err := m.errors["GetWorkItemsBatch"]; err != nil {
+		return nil, err
+	}
+	items := make([]azure.WorkItem, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, azure.WorkItem{ID: id, Title: fmt.Sprintf("item %d", id)})
+	}
+	return items, nil
+}
+func (m *mockAzure) GetWorkItemRevision(ctx context.Context, id, rev int) (azure.WorkItem, error) {
+	return azure.WorkItem{}, nil
+}
 func (m *mockAzure) GetProjects(ctx context.Context) ([]core.TeamProjectReference, error) {
 	return nil, nil
 }
 
-func TestControllerWritesLastSync(t *testing.T) {
-	app := &App{
-		Azure:  &mockAzure{},
-		DB:     &mockDB{},
-		Tracer: otel.Tracer("test"),
-	}
-	taskCh := make(chan SyncTask)
-	go func() {
-		// drain channel if any tasks sent
-		for range taskCh {
-		}
-	}()
-	ctx := context.Background()
-	app.controller(ctx, taskCh)
-	md := app.DB.(*mockDB)
-	if !md.wrote {
-		t.Fatalf("expected WriteLastSync to be called")
-	}
+var checkpointBase = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestControllerWritesQueryTimePerProject(t *testing.T) {
+	md := newMockDB("A", "B")
+	md.checkpoints["A"] = checkpointBase
+	md.checkpoints["B"] = checkpointBase
+	ma := newMockAzure()
+	ma.ids["A"] = []int{1, 2}
+	ma.ids["B"] = []int{3}
+	ma.asOf["A"] = checkpointBase.Add(time.Hour)
+	ma.asOf["B"] = checkpointBase.Add(30 * time.Minute)
+	app := &App{Azure: ma, DB: md, Elector: newLeader(md), Tracer: otel.Tracer("test"), WatermarkOverlap: 2 * time.Minute}
+
+	app.controller(context.Background(), time.Minute)
+
+	assert.Equal(t, checkpointBase.Add(-2*time.Minute), ma.since["A"], "query should overlap the checkpoint")
+	assert.Equal(t, checkpointBase.Add(time.Hour), md.written["A"])
+	assert.Equal(t, checkpointBase.Add(30*time.Minute), md.written["B"])
+}
+
+func TestControllerQueuesChangedWorkItems(t *testing.T) {
+	md := newMockDB("A", "B")
+	ma := newMockAzure()
+	ma.ids["A"] = []int{1, 2}
+	ma.ids["B"] = []int{3}
+	ma.asOf["A"] = checkpointBase
+	app := &App{Azure: ma, DB: md, Elector: newLeader(md), Tracer: otel.Tracer("test")}
+
+	app.controller(context.Background(), time.Minute)
+
+	assert.Len(t, md.queued, 3)
+	assert.Equal(t, "A", md.queued[2].ADOProjectName)
+	assert.Equal(t, checkpointBase, md.queued[2].ChangedDate, "items are queued as of the query time")
+	assert.Equal(t, "B", md.queued[3].ADOProjectName)
+}
+
+func TestControllerEnqueueErrorKeepsCheckpoint(t *testing.T) {
+	md := newMockDB("A")
+	md.enqueueErr = fmt.Errorf("mongo down")
+	ma := newMockAzure()
+	ma.ids["A"] = []int{1}
+	ma.asOf["A"] = checkpointBase.Add(time.Hour)
+	app := &App{Azure: ma, DB: md, Elector: newLeader(md), Tracer: otel.Tracer("test")}
+
+	app.controller(context.Background(), time.Minute)
+
+	assert.Empty(t, md.written, "items that were not queued must be queried again")
+}
+
+func TestControllerPartialEnqueueErrorKeepsCheckpoint(t *testing.T) {
+	md := newMockDB("A")
+	md.checkpoints["A"] = checkpointBase
+	md.enqueueErrs = map[int]error{2: fmt.Errorf("mongo down")}
+	ma := newMockAzure()
+	ma.ids["A"] = []int{1, 2}
+	ma.asOf["A"] = checkpointBase.Add(time.Hour)
+	app := &App{Azure: ma, DB: md, Elector: newLeader(md), Tracer: otel.Tracer("test")}
+
+	app.controller(context.Background(), time.Minute)
+
+	assert.Contains(t, md.queued, 1)
+	assert.Empty(t, md.written, "the checkpoint must not pass an item that was not queued")
+}
+
+func TestControllerLostLeaseKeepsCheckpoint(t *testing.T) {
+	md := newMockDB("A")
+	md.checkpoints["A"] = checkpointBase
+	ma := newMockAzure()
+	ma.ids["A"] = []int{1}
+	ma.asOf["A"] = checkpointBase.Add(time.Hour)
+	elector := newLeader(md)
+	elector.until.Store(time.Now().UnixNano())
+	app := &App{Azure: ma, DB: md, Elector: elector, Tracer: otel.Tracer("test")}
+
+	app.controller(context.Background(), time.Minute)
+
+	assert.Empty(t, md.written, "the new leader owns the checkpoint")
+}
+
+func TestControllerQueryErrorKeepsCheckpoint(t *testing.T) {
+	md := newMockDB("A", "B")
+	ma := newMockAzure()
+	ma.errors["A"] = fmt.Errorf("ado down")
+	ma.ids["B"] = []int{3}
+	ma.asOf["B"] = checkpointBase.Add(time.Hour)
+	app := &App{Azure: ma, DB: md, Elector: newLeader(md), Tracer: otel.Tracer("test")}
+
+	app.controller(context.Background(), time.Minute)
+
+	_, wroteA := md.written["A"]
+	assert.False(t, wroteA)
+	assert.Equal(t, checkpointBase.Add(time.Hour), md.written["B"])
+}
+
+func TestControllerCheckpointErrorSkipsProject(t *testing.T) {
+	md := newMockDB("A", "B")
+	md.checkpointErrs = map[string]error{"A": fmt.Errorf("mongo timeout")}
+	ma := newMockAzure()
+	ma.ids["A"] = []int{1}
+	ma.ids["B"] = []int{3}
+	ma.asOf["B"] = checkpointBase.Add(time.Hour)
+	app := &App{Azure: ma, DB: md, Elector: newLeader(md), Tracer: otel.Tracer("test")}
+
+	_, err := app.controller(context.Background(), time.Minute)
+
+	assert.NoError(t, err)
+	assert.NotContains(t, ma.since, "A", "the project is not queried from the start")
+	assert.NotContains(t, md.queued, 1)
+	assert.NotContains(t, md.lastRuns, "A", "the project stays due")
+	assert.Contains(t, md.queued, 3)
+}
+
+func TestControllerNoItemsKeepsCheckpoint(t *testing.T) {
+	md := newMockDB("A")
+	md.checkpoints["A"] = checkpointBase
+	app := &App{Azure: newMockAzure(), DB: md, Elector: newLeader(md), Tracer: otel.Tracer("test")}
+
+	app.controller(context.Background(), time.Minute)
+
+	assert.Empty(t, md.written, "checkpoint should not be set to the current time")
+}
+
+func TestControllerDoesNotHydrateWorkItems(t *testing.T) {
+	md := newMockDB("A")
+	md.checkpoints["A"] = checkpointBase
+	ma := newMockAzure()
+	ma.ids["A"] = []int{1, 2}
+	ma.asOf["A"] = checkpointBase.Add(time.Hour)
+	ma.errors["GetWorkItemsBatch"] = fmt.Errorf("ado down")
+	app := &App{Azure: ma, DB: md, Elector: newLeader(md), Tracer: otel.Tracer("test")}
+
+	app.controller(context.Background(), time.Minute)
+
+	assert.Empty(t, ma.batches, "the workers fetch the work items")
+	assert.Len(t, md.queued, 2)
+	assert.Equal(t, checkpointBase.Add(time.Hour), md.written["A"])
+}
+
+func TestControllerMissingQueryTimeKeepsCheckpoint(t *testing.T) {
+	md := newMockDB("A")
+	md.checkpoints["A"] = checkpointBase
+	ma := newMockAzure()
+	ma.ids["A"] = []int{1}
+	app := &App{Azure: ma, DB: md, Elector: newLeader(md), Tracer: otel.Tracer("test")}
+
+	app.controller(context.Background(), time.Minute)
+
+	assert.Len(t, md.queued, 1)
+	assert.Empty(t, md.written, "checkpoint cannot advance without the query time")
 }
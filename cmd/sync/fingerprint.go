@@ -0,0 +1,49 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Fingerprint keys of the task fields; custom fields are keyed by their GID
+// after customFieldPrefix.
+const (
+	fingerprintName   = "name"
+	fingerprintNotes  = "notes"
+	customFieldPrefix = "custom_field:"
+)
+
+// taskFingerprint hashes each field rendered for an Asana task.
+func taskFingerprint(name, notes string, customFields map[string]string) map[string]string {
+	fp := map[string]string{
+		fingerprintName:  hashField(name),
+		fingerprintNotes: hashField(notes),
+	}
+	for gid, v := range customFields {
+		fp[customFieldPrefix+gid] = hashField(v)
+	}
+	return fp
+}
+
+func hashField(v string) string {
+	sum := sha256.Sum256([]byte(v))
+	return hex.EncodeToString(sum[:])
+}
+
+// changedFields returns the name, notes and custom fields whose hash differs
+// from the last fingerprint. Unchanged name and notes are returned empty.
+func changedFields(last map[string]string, name, notes string, customFields map[string]string) (string, string, map[string]string) {
+	if last[fingerprintName] == hashField(name) {
+		name = ""
+	}
+	if last[fingerprintNotes] == hashField(notes) {
+		notes = ""
+	}
+	changed := map[string]string{}
+	for gid, v := range customFields {
+		if last[customFieldPrefix+gid] != hashField(v) {
+			changed[gid] = v
+		}
+	}
+	return name, notes, changed
+}
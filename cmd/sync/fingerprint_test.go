@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangedFields(t *testing.T) {
+	cf := map[string]string{"cf-link": "http://ado.com/1"}
+	last := taskFingerprint("Name", "Notes", cf)
+
+	name, notes, fields := changedFields(last, "Name", "Notes", cf)
+	assert.Empty(t, name)
+	assert.Empty(t, notes)
+	assert.Empty(t, fields)
+
+	name, notes, fields = changedFields(last, "New name", "Notes", map[string]string{"cf-link": "http://ado.com/1", "cf-new": "x"})
+	assert.Equal(t, "New name", name)
+	assert.Empty(t, notes)
+	assert.Equal(t, map[string]string{"cf-new": "x"}, fields)
+
+	name, notes, fields = changedFields(nil, "Name", "Notes", cf)
+	assert.Equal(t, "Name", name, "everything is sent without a fingerprint")
+	assert.Equal(t, "Notes", notes)
+	assert.Equal(t, cf, fields)
+}
+
+func setupFingerprintTest(t *testing.T) (*App, *enhancedMockDB, *enhancedMockAsana, db.TaskMapping) {
+	t.Helper()
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAsana.customFields["proj-1"] = []asana.CustomField{{GID: "cf-link", Name: "link"}}
+	wi := createTestWorkItem(123, "Task", "TestProject", "http://ado.com/123", time.Now())
+	wi.Rev = 2
+	mockAzure.workItems[123] = wi
+	name, _ := wi.FormatTitle()
+	desc, _ := wi.FormatTitleWithLink()
+	mapping := db.TaskMapping{
+		ADOProjectID:     "TestProject",
+		ADOTaskID:        123,
+		AsanaProjectID:   "proj-1",
+		AsanaTaskID:      "task-1",
+		AsanaFingerprint: taskFingerprint(name, desc, map[string]string{"cf-link": wi.URL}),
+	}
+	mockDB.tasks[123] = mapping
+	return app, mockDB, mockAsana, mapping
+}
+
+func TestHandleTaskSkipsUnchangedAsanaFields(t *testing.T) {
+	app, mockDB, mockAsana, _ := setupFingerprintTest(t)
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 123})
+
+	assert.NoError(t, err)
+	assert.Empty(t, mockAsana.taskChanges, "nothing Asana-relevant changed")
+	assert.Empty(t, mockAsana.tagsAdded)
+	assert.Equal(t, 2, mockDB.tasks[123].ADORevision, "revision recorded")
+}
+
+func TestHandleTaskSendsOnlyChangedFields(t *testing.T) {
+	app, mockDB, mockAsana, _ := setupFingerprintTest(t)
+	wi := app.Azure.(*enhancedMockAzure).workItems[123]
+	wi.Title = "Renamed"
+	app.Azure.(*enhancedMockAzure).workItems[123] = wi
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 123})
+
+	assert.NoError(t, err)
+	assert.Len(t, mockAsana.taskChanges, 1)
+	change := mockAsana.taskChanges[0]
+	assert.Contains(t, change.Name, "Renamed")
+	assert.Contains(t, change.Notes, "Renamed", "the notes embed the title")
+	assert.Empty(t, change.CustomFields, "the link did not change")
+
+	name, _ := wi.FormatTitle()
+	assert.Equal(t, hashField(name), mockDB.tasks[123].AsanaFingerprint[fingerprintName])
+}
+
+func TestHandleTaskForceResyncSendsEverything(t *testing.T) {
+	app, mockDB, mockAsana, mapping := setupFingerprintTest(t)
+	mapping.ADORevision = 2
+	mockDB.tasks[123] = mapping
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 123})
+	assert.NoError(t, err)
+	assert.Empty(t, mockAsana.taskChanges, "revision already synced")
+
+	assert.NoError(t, mockDB.ForceResync(context.Background(), 123))
+	app.processJobs(context.Background(), context.Background(), log.WithField("test", "worker"), "test/1")
+
+	assert.Len(t, mockAsana.taskChanges, 1)
+	change := mockAsana.taskChanges[0]
+	assert.NotEmpty(t, change.Name)
+	assert.NotEmpty(t, change.Notes)
+	assert.Equal(t, map[string]string{"cf-link": "http://ado.com/123"}, change.CustomFields)
+	assert.False(t, mockDB.jobs[123].Force, "cleared once synced")
+}
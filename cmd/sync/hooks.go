@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxHookBody bounds the size of the webhook payloads read. Work item
+// payloads carry every field, descriptions included.
+const maxHookBody = 5 << 20
+
+// HookConfig configures the HTTP server receiving webhooks. A receiver is
+// only enabled once its credentials are set, and the server only runs when
+// a receiver is enabled.
+type HookConfig struct {
+	// Addr is the address the server listens on.
+	Addr string
+	// ADOUsername and ADOPassword are the basic authentication credentials
+	// of the ADO service hooks, and ADOSecret the value of their
+	// X-ADO-Hook-Secret header. Either or both may be set.
+	ADOUsername string
+	ADOPassword string
+	ADOSecret   string
+	// AsanaURL is the public base URL of the server, such as
+	// https://sync.example.com, which Asana delivers webhooks to. Webhooks
+	// are only created on the mapped Asana projects when it is set.
+	AsanaURL string
+	// AsanaRenewAfter is how long an Asana webhook may go without events or
+	// heartbeats before it is created again.
+	AsanaRenewAfter time.Duration
+}
+
+func getHookConfig() HookConfig {
+	addr := os.Getenv("HOOK_LISTEN_ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+	return HookConfig{
+		Addr:            addr,
+		ADOUsername:     os.Getenv("ADO_HOOK_USERNAME"),
+		ADOPassword:     os.Getenv("ADO_HOOK_PASSWORD"),
+		ADOSecret:       os.Getenv("ADO_HOOK_SECRET"),
+		AsanaURL:        strings.TrimRight(os.Getenv("ASANA_WEBHOOK_URL"), "/"),
+		AsanaRenewAfter: getDuration("ASANA_WEBHOOK_RENEW_AFTER", 24*time.Hour),
+	}
+}
+
+// adoEnabled reports whether the ADO service hook receiver is configured.
+func (c HookConfig) adoEnabled() bool {
+	return c.ADOPassword != "" || c.ADOSecret != ""
+}
+
+// hookHandler routes the webhooks of the enabled receivers. It returns nil
+// when none is enabled.
+func (app *App) hookHandler() http.Handler {
+	mux := http.NewServeMux()
+	enabled := false
+	if app.Hooks.adoEnabled() {
+		mux.HandleFunc("POST /hooks/ado", app.adoHookHandler)
+		enabled = true
+	}
+	if app.Hooks.asanaEnabled() {
+		mux.HandleFunc("POST /hooks/asana/{project}", app.asanaHookHandler)
+		enabled = true
+	}
+	if !enabled {
+		return nil
+	}
+	return mux
+}
+
+// serveHooks receives webhooks until ctx is cancelled. It returns straight
+// away when no receiver is enabled. Webhooks only speed syncing up: the
+// controller still picks every change up on its next run.
+func (app *App) serveHooks(ctx context.Context) {
+	handler := app.hookHandler()
+	if handler == nil {
+		log.Info("no webhook receiver configured")
+		return
+	}
+
+	srv := &http.Server{Addr: app.Hooks.Addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		sctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(sctx); err != nil {
+			log.WithError(err).Warn("error shutting down the webhook server")
+		}
+	}()
+
+	log.Infof("receiving webhooks on %v", app.Hooks.Addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.WithError(err).Error("webhook server failed, relying on the controller alone")
+	}
+}
+
+// adoHookEvent is the part of an ADO service hook payload needed to queue
+// the work item. See
+// https://learn.microsoft.com/en-us/azure/devops/service-hooks/events#work-item-created
+type adoHookEvent struct {
+	EventType string          `json:"eventType"`
+	Resource  json.RawMessage `json:"resource"`
+}
+
+// adoHookResource is the resource of the work item events. Created, deleted
+// and restored events carry the work item itself; updated events carry the
+// update, with the work item ID and its new revision.
+type adoHookResource struct {
+	ID         int            `json:"id"`
+	WorkItemID int            `json:"workItemId"`
+	Fields     map[string]any `json:"fields"`
+	Revision   *struct {
+		ID     int            `json:"id"`
+		Fields map[string]any `json:"fields"`
+	} `json:"revision"`
+}
+
+// workItem returns the ID, project and ChangedDate of the work item the
+// event is about. The ChangedDate is zero when missing.
+func (r adoHookResource) workItem(eventType string) (int, string, time.Time) {
+	id, fields := r.ID, r.Fields
+	if eventType == "workitem.updated" {
+		id = r.WorkItemID
+		if r.Revision != nil {
+			id, fields = r.Revision.ID, r.Revision.Fields
+		}
+	}
+	project, _ := fields["System.TeamProject"].(string)
+	changedStr, _ := fields["System.ChangedDate"].(string)
+	changed, _ := time.Parse(time.RFC3339Nano, changedStr)
+	return id, project, changed
+}
+
+// adoHookAuthorized checks the basic authentication credentials and shared
+// secret configured for the ADO service hooks.
+func (c HookConfig) adoHookAuthorized(r *http.Request) bool {
+	if c.ADOPassword != "" {
+		user, pass, ok := r.BasicAuth()
+		if !ok || !secretEqual(user, c.ADOUsername) || !secretEqual(pass, c.ADOPassword) {
+			return false
+		}
+	}
+	if c.ADOSecret != "" && !secretEqual(r.Header.Get("X-ADO-Hook-Secret"), c.ADOSecret) {
+		return false
+	}
+	return true
+}
+
+func secretEqual(got, want string) bool {
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// adoHookHandler queues the work item of an ADO workitem.created, updated,
+// deleted or restored event for syncing straight away. Other events and
+// work items of unmapped projects are acknowledged and ignored. An error
+// response makes ADO deliver the event again.
+func (app *App) adoHookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := app.Tracer.Start(r.Context(), "sync.hooks.ado")
+	defer span.End()
+
+	if !app.Hooks.adoHookAuthorized(r) {
+		log.WithField("remote", r.RemoteAddr).Warn("rejected unauthorized ADO service hook")
+		w.Header().Set("WWW-Authenticate", `Basic realm="sync"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var event adoHookEvent
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxHookBody)).Decode(&event); err != nil {
+		span.RecordError(err)
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	span.SetAttributes(attribute.String("event_type", event.EventType))
+
+	switch event.EventType {
+	case "workitem.created", "workitem.updated", "workitem.deleted", "workitem.restored":
+	default:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var res adoHookResource
+	if err := json.Unmarshal(event.Resource, &res); err != nil {
+		span.RecordError(err)
+		http.Error(w, "invalid resource", http.StatusBadRequest)
+		return
+	}
+	id, project, changed := res.workItem(event.EventType)
+	if id == 0 || project == "" {
+		http.Error(w, "work item ID or project missing", http.StatusBadRequest)
+		return
+	}
+	span.SetAttributes(attribute.Int("ado_task_id", id), attribute.String("project", project))
+	hlog := log.WithField("event", event.EventType).WithField("ado_task_id", id).WithField("project", project)
+
+	mapped, err := app.projectMapped(ctx, project)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		hlog.WithError(err).Error("error reading project mappings for ADO service hook")
+		http.Error(w, "error reading project mappings", http.StatusInternalServerError)
+		return
+	}
+	if !mapped {
+		hlog.Debug("ignoring ADO service hook of unmapped project")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := app.DB.EnqueueJob(ctx, id, project, changed); err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		hlog.WithError(err).Error("error queuing work item from ADO service hook")
+		http.Error(w, "error queuing work item", http.StatusInternalServerError)
+		return
+	}
+	hlog.Info("queued work item from ADO service hook")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// projectMapped reports whether the ADO project is mapped to Asana.
+func (app *App) projectMapped(ctx context.Context, adoProject string) (bool, error) {
+	projects, err := app.DB.Projects(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range projects {
+		if p.ADOProjectName == adoProject {
+			return true, nil
+		}
+	}
+	return false, nil
+}
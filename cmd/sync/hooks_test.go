@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+)
+
+// postADOHook posts the recorded ADO service hook payload to the hook
+// handler, with the credentials set by auth.
+func postADOHook(t *testing.T, app *App, payload string, auth func(*http.Request)) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", "ado", payload+".json"))
+	assert.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/hooks/ado", bytes.NewReader(body))
+	if auth != nil {
+		auth(req)
+	}
+	rec := httptest.NewRecorder()
+	app.hookHandler().ServeHTTP(rec, req)
+	return rec
+}
+
+func basicAuth(user, pass string) func(*http.Request) {
+	return func(r *http.Request) { r.SetBasicAuth(user, pass) }
+}
+
+func setupHookTest() (*App, *mockDB) {
+	md := newMockDB("FabrikamCloud")
+	app := &App{
+		DB:     md,
+		Tracer: otel.Tracer("test"),
+		Hooks:  HookConfig{ADOUsername: "ado", ADOPassword: "hunter2"},
+	}
+	return app, md
+}
+
+func TestADOHookQueuesWorkItem(t *testing.T) {
+	for _, payload := range []string{"workitem.created", "workitem.updated", "workitem.deleted"} {
+		t.Run(payload, func(t *testing.T) {
+			app, md := setupHookTest()
+
+			rec := postADOHook(t, app, payload, basicAuth("ado", "hunter2"))
+
+			assert.Equal(t, http.StatusAccepted, rec.Code)
+			assert.Equal(t, "FabrikamCloud", md.queued[5].ADOProjectName)
+			assert.False(t, md.queued[5].ChangedDate.IsZero(), "the ChangedDate lets the controller skip the work item")
+		})
+	}
+}
+
+func TestADOHookUpdatedUsesRevision(t *testing.T) {
+	app, md := setupHookTest()
+
+	postADOHook(t, app, "workitem.updated", basicAuth("ado", "hunter2"))
+
+	assert.Len(t, md.queued, 1, "the update ID is not a work item ID")
+	assert.Equal(t, time.Date(2014, 7, 15, 17, 42, 44, 663000000, time.UTC), md.queued[5].ChangedDate)
+}
+
+func TestADOHookRejectsBadCredentials(t *testing.T) {
+	app, md := setupHookTest()
+
+	assert.Equal(t, http.StatusUnauthorized, postADOHook(t, app, "workitem.updated", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, postADOHook(t, app, "workitem.updated", basicAuth("ado", "wrong")).Code)
+	assert.Empty(t, md.queued)
+}
+
+func TestADOHookChecksSharedSecret(t *testing.T) {
+	app, md := setupHookTest()
+	app.Hooks = HookConfig{ADOSecret: "s3cret"}
+
+	assert.Equal(t, http.StatusUnauthorized, postADOHook(t, app, "workitem.updated", nil).Code)
+	rec := postADOHook(t, app, "workitem.updated", func(r *http.Request) { r.Header.Set("X-ADO-Hook-Secret", "s3cret") })
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Len(t, md.queued, 1)
+}
+
+func TestADOHookIgnoresUnmappedProject(t *testing.T) {
+	app, md := setupHookTest()
+	md.projects = nil
+
+	rec := postADOHook(t, app, "workitem.created", basicAuth("ado", "hunter2"))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, md.queued)
+}
+
+func TestADOHookRetriedOnQueueError(t *testing.T) {
+	app, md := setupHookTest()
+	md.enqueueErr = assert.AnError
+
+	rec := postADOHook(t, app, "workitem.created", basicAuth("ado", "hunter2"))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code, "ADO delivers the event again")
+}
+
+func TestHookHandlerDisabledWithoutCredentials(t *testing.T) {
+	app := &App{Hooks: HookConfig{Addr: ":8080"}}
+	assert.Nil(t, app.hookHandler())
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	log "github.com/sirupsen/logrus"
+)
+
+// controllerLease is the name of the lease held by the replica running the
+// controller.
+const controllerLease = "controller"
+
+// Elector keeps a Mongo lease so a single replica leads. The lease is
+// renewed every third of its TTL; a leader that dies or loses Mongo is
+// replaced once its lease expires.
+type Elector struct {
+	db     db.DBInterface
+	name   string
+	holder string
+	ttl    time.Duration
+	leader atomic.Bool
+	// until is when the lease taken at the last renewal expires, in Unix
+	// nanoseconds. It is counted from before the renewal was sent, so it is
+	// never later than the expiry stored in Mongo.
+	until atomic.Int64
+}
+
+// NewElector returns an Elector campaigning for the named lease as holder.
+func NewElector(store db.DBInterface, name, holder string, ttl time.Duration) *Elector {
+	return &Elector{db: store, name: name, holder: holder, ttl: ttl}
+}
+
+// IsLeader reports whether the lease was held at the last renewal and has
+// not expired since. Another replica may take over an expired lease even if
+// this one is still running, for instance when its renewals are stuck on
+// Mongo, so writes only the leader may make check it right before.
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load() && time.Now().UnixNano() < e.until.Load()
+}
+
+// Campaign tries once to take or renew the lease.
+func (e *Elector) Campaign(ctx context.Context) {
+	until := time.Now().Add(e.ttl)
+	ok, err := e.db.AcquireLease(ctx, e.name, e.holder, e.ttl)
+	if err != nil {
+		log.WithError(err).WithField("lease", e.name).Warn("error renewing lease")
+		ok = false
+	}
+	if ok {
+		e.until.Store(until.UnixNano())
+	}
+	if was := e.leader.Swap(ok); was != ok {
+		log.WithField("lease", e.name).WithField("leader", ok).Info("leadership changed")
+	}
+}
+
+// Run campaigns until ctx is cancelled, then releases the lease.
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			e.leader.Store(false)
+			// The parent context is done; give the release its own deadline.
+			rctx, cancel := context.WithTimeout(context.Background(), db.Timeout)
+			defer cancel()
+			if err := e.db.ReleaseLease(rctx, e.name, e.holder); err != nil {
+				log.WithError(err).WithField("lease", e.name).Warn("error releasing lease")
+			}
+			return
+		case <-ticker.C:
+			e.Campaign(ctx)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+// newLeader returns an Elector holding the controller lease of store.
+func newLeader(store db.DBInterface) *Elector {
+	e := NewElector(store, controllerLease, "test", time.Minute)
+	e.Campaign(context.Background())
+	return e
+}
+
+func TestElectorSingleLeader(t *testing.T) {
+	store := newEnhancedMockDB()
+	a := NewElector(store, controllerLease, "a", time.Minute)
+	b := NewElector(store, controllerLease, "b", time.Minute)
+
+	a.Campaign(context.Background())
+	b.Campaign(context.Background())
+
+	assert.True(t, a.IsLeader())
+	assert.False(t, b.IsLeader())
+
+	a.Campaign(context.Background())
+	assert.True(t, a.IsLeader(), "the leader renews its lease")
+}
+
+func TestElectorTakesOverExpiredLease(t *testing.T) {
+	store := newEnhancedMockDB()
+	a := NewElector(store, controllerLease, "a", time.Minute)
+	b := NewElector(store, controllerLease, "b", time.Minute)
+	a.Campaign(context.Background())
+
+	lease := store.leases[controllerLease]
+	lease.ExpiresAt = time.Now().Add(-time.Second)
+	store.leases[controllerLease] = lease
+	b.Campaign(context.Background())
+
+	assert.True(t, b.IsLeader())
+	a.Campaign(context.Background())
+	assert.False(t, a.IsLeader(), "the old leader steps down")
+}
+
+func TestElectorStepsDownOnError(t *testing.T) {
+	store := newEnhancedMockDB()
+	e := NewElector(store, controllerLease, "a", time.Minute)
+	e.Campaign(context.Background())
+
+	store.errors["AcquireLease"] = fmt.Errorf("mongo down")
+	e.Campaign(context.Background())
+
+	assert.False(t, e.IsLeader())
+}
+
+func TestElectorStepsDownWhenLeaseExpires(t *testing.T) {
+	store := newEnhancedMockDB()
+	e := NewElector(store, controllerLease, "a", time.Minute)
+	e.Campaign(context.Background())
+	assert.True(t, e.IsLeader())
+
+	e.until.Store(time.Now().Add(-time.Second).UnixNano())
+
+	assert.False(t, e.IsLeader(), "the lease may be taken over without a renewal")
+}
+
+func TestElectorReleasesLeaseOnStop(t *testing.T) {
+	store := newEnhancedMockDB()
+	e := NewElector(store, controllerLease, "a", time.Minute)
+	e.Campaign(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	e.Run(ctx)
+
+	assert.False(t, e.IsLeader())
+	assert.NotContains(t, store.leases, controllerLease)
+}
@@ -7,11 +7,15 @@ import (
 	"os/signal"
 	"path"
 	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
 	"github.com/ADO-Asana-Sync/sync-engine/internal/azure"
 	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/ratelimit"
 	log "github.com/sirupsen/logrus"
 	"github.com/uptrace/uptrace-go/uptrace"
 	"go.opentelemetry.io/otel"
@@ -27,13 +31,34 @@ var (
 )
 
 type App struct {
-	Asana           asana.AsanaInterface
-	Azure           azure.AzureInterface
-	DB              db.DBInterface
-	CacheTTL        time.Duration
-	SyncedTags      map[string]asana.Tag
-	Tracer          trace.Tracer
-	UptraceShutdown func(ctx context.Context) error
+	Asana asana.AsanaInterface
+	// AsanaUserGID is the Asana user the service writes as, whose own
+	// events are not synced back.
+	AsanaUserGID     string
+	Azure            azure.AzureInterface
+	DB               db.DBInterface
+	CacheTTL         time.Duration
+	Resolver         *Resolver
+	WatermarkOverlap time.Duration
+	Queue            QueueConfig
+	InstanceID       string
+	Elector          *Elector
+	Breaker          *Breaker
+	Portfolio        PortfolioConfig
+	FieldPolicy      FieldPolicy
+	Hooks            HookConfig
+	EventsInterval   time.Duration
+	Reconcile        ReconcileConfig
+	SyncedTags       map[string]asana.Tag
+	Tracer           trace.Tracer
+	UptraceShutdown  func(ctx context.Context) error
+
+	// renamesCheckedAt is when the project mappings were last checked for
+	// upstream renames.
+	renamesCheckedAt time.Time
+
+	// fatal carries an unrecoverable error hit by a worker to the run loop.
+	fatal chan error
 }
 
 func init() {
@@ -50,8 +75,8 @@ func init() {
 }
 
 func main() {
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
-	defer cancel()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	log.Infof("Sync process started. Version: %v, commit: %v, date: %v", Version, Commit, Date)
 	app := &App{}
@@ -59,40 +84,195 @@ func main() {
 	if err != nil {
 		log.WithError(err).Fatal("error setting up the app")
 	}
-	defer func(ctx context.Context) {
-		err := app.DB.Disconnect(ctx)
-		if err != nil {
-			log.WithError(err).Fatal("error disconnecting from the DB")
-		}
-		err = app.UptraceShutdown(ctx)
-		if err != nil {
-			log.WithError(err).Fatal("error shutting down Uptrace")
-		}
-	}(ctx)
 
-	// Create the worker pool.
+	// Jobs get a context of their own so a shutdown lets the jobs in flight
+	// finish their Asana writes. It is only cancelled once the grace period
+	// is over.
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelJobs()
+
+	// Create the worker pool. Workers lease jobs from the Mongo queue filled
+	// by the controller.
 	numWorkers := 10 // Set the number of concurrent workers here or use an environment variable
-	taskCh := make(chan SyncTask)
 
+	var workers sync.WaitGroup
 	for i := 0; i < numWorkers; i++ {
-		go app.worker(ctx, i, taskCh)
+		workers.Add(1)
+		go func(id int) {
+			defer workers.Done()
+			app.worker(ctx, jobCtx, id)
+		}(i)
+	}
+
+	// Only the replica holding the controller lease runs the controller;
+	// every replica runs workers.
+	app.Elector.Campaign(ctx)
+	electorDone := make(chan struct{})
+	go func() {
+		app.Elector.Run(ctx)
+		close(electorDone)
+	}()
+
+	// Webhooks queue changes straight away; the controller remains the
+	// safety net for the ones missed.
+	hooksDone := make(chan struct{})
+	go func() {
+		app.serveHooks(ctx)
+		close(hooksDone)
+	}()
+
+	// Without a public URL for webhooks, Asana changes are polled instead.
+	pollDone := make(chan struct{})
+	go func() {
+		app.pollAsanaEvents(ctx, app.EventsInterval)
+		close(pollDone)
+	}()
+
+	// Reconciliations can take long enough to delay the controller, so they
+	// run on their own.
+	reconcileDone := make(chan struct{})
+	go func() {
+		app.reconcileLoop(ctx, getSleepTime())
+		close(reconcileDone)
+	}()
+
+	runErr := app.run(ctx)
+	if runErr != nil {
+		log.WithError(runErr).Error("unrecoverable error")
+		stop()
+	}
+
+	log.Info("shutting down")
+	grace := getDuration("SHUTDOWN_GRACE_PERIOD", 30*time.Second)
+	if !waitForWorkers(&workers, cancelJobs, grace) {
+		log.Warnf("jobs still running after %v, cancelled them", grace)
 	}
+	<-electorDone
+	<-hooksDone
+	<-pollDone
+	<-reconcileDone
+	app.shutdown()
+	if runErr != nil {
+		os.Exit(1)
+	}
+}
 
-	// Create the controller.
-	var st time.Duration
+// run runs the controller, then sleeps, until ctx is cancelled. Failed
+// controller cycles are retried with a growing backoff; only an
+// unrecoverable error, from the controller or a worker, stops the loop and
+// is returned.
+func (app *App) run(ctx context.Context) error {
 	for {
 		ctx, span := app.Tracer.Start(ctx, "sync.main")
-		app.controller(ctx, taskCh)
+		span.SetAttributes(attribute.Bool("leader", app.Elector.IsLeader()))
+		st := getSleepTime()
+		if app.Elector.IsLeader() {
+			var err error
+			st, err = app.runController(ctx, st)
+			if err != nil {
+				span.RecordError(err, trace.WithStackTrace(true))
+				span.SetStatus(codes.Error, err.Error())
+				span.End()
+				return err
+			}
+		} else {
+			log.Info("another replica runs the controller")
+		}
 
-		st = getSleepTime()
 		span.SetAttributes(attribute.Int64("sleepTimeSec", int64(st.Seconds())))
 		span.End()
 
 		log.Infof("sleeping for %v", st)
-		time.Sleep(st)
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-app.fatal:
+			return err
+		case <-time.After(st):
+		}
+	}
+}
+
+// stop makes the run loop return err, shutting the service down, after a
+// worker hit an unrecoverable error.
+func (app *App) stop(err error) {
+	select {
+	case app.fatal <- err:
+	default:
 	}
 }
 
+// runController runs one controller cycle and records its outcome. It
+// returns how long to wait before the next cycle, which is interval unless
+// the cycle failed, and the error when it is unrecoverable.
+func (app *App) runController(ctx context.Context, interval time.Duration) (time.Duration, error) {
+	app.reconcileProjects(ctx)
+	app.reconcileWebhooks(ctx)
+	next, err := app.controller(ctx, interval)
+	if ctx.Err() != nil {
+		// Shutting down; the cycle was interrupted rather than failed.
+		return interval, nil
+	}
+	if err != nil && unrecoverableError(err) {
+		return 0, err
+	}
+
+	app.Breaker.Record(err)
+	delay := app.Breaker.Delay(interval)
+	if err == nil {
+		delay = nextDue(next).wait(interval, time.Now())
+	} else {
+		log.WithError(err).WithField("failures", app.Breaker.failures).WithField("retry_in", delay).
+			Warn("controller cycle failed")
+	}
+	status := app.Breaker.Status(app.InstanceID, delay)
+	if status.State == db.ControllerDegraded {
+		log.WithField("failures", status.ConsecutiveFailures).Error("controller degraded")
+	}
+	if err := app.DB.WriteControllerStatus(ctx, status); err != nil {
+		log.WithError(err).Warn("error writing controller status")
+	}
+	return delay, nil
+}
+
+// waitForWorkers waits for the workers to finish the jobs in flight. Once
+// grace has passed the jobs are cancelled, to be retried by whichever
+// worker leases them next. It reports whether the workers finished in time.
+func waitForWorkers(workers *sync.WaitGroup, cancelJobs context.CancelFunc, grace time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(grace):
+		cancelJobs()
+		<-done
+		return false
+	}
+}
+
+// shutdown disconnects from the DB and flushes the telemetry not exported
+// yet. The run context is cancelled by then, so each step gets its own
+// deadline.
+func (app *App) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), db.Timeout)
+	defer cancel()
+	if err := app.DB.Disconnect(ctx); err != nil {
+		log.WithError(err).Error("error disconnecting from the DB")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), db.Timeout)
+	defer cancel()
+	if err := app.UptraceShutdown(ctx); err != nil {
+		log.WithError(err).Error("error shutting down Uptrace")
+	}
+	log.Info("sync process stopped")
+}
+
 func getSleepTime() time.Duration {
 	sleepTime := os.Getenv("SLEEP_TIME")
 	if sleepTime == "" {
@@ -107,6 +287,49 @@ func getSleepTime() time.Duration {
 	return d
 }
 
+// getRequestRate reads a requests per second limit from the environment.
+// Zero disables client-side limiting.
+func getRequestRate(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	r, err := strconv.ParseFloat(v, 64)
+	if err != nil || r < 0 {
+		log.WithField("value", v).Warnf("unable to parse %s, defaulting to %v", key, def)
+		return def
+	}
+	return r
+}
+
+// getInt reads a non-negative integer from the environment.
+func getInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.WithField("value", v).Warnf("unable to parse %s, defaulting to %v", key, def)
+		return def
+	}
+	return n
+}
+
+// getDuration reads a non-negative duration from the environment.
+func getDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.WithField("value", v).Warnf("unable to parse %s, defaulting to %v", key, def)
+		return def
+	}
+	return d
+}
+
 func getCacheTTL() time.Duration {
 	ttl := os.Getenv("PROPERTY_CACHE_TTL")
 	if ttl == "" {
@@ -159,7 +382,13 @@ func (app *App) setup(ctx context.Context) error {
 	// Azure DevOps setup.
 	log.Info("connecting to Azure DevOps")
 	if app.Azure == nil {
-		app.Azure = azure.NewAzure()
+		az := azure.NewAzure()
+		az.Transport = ratelimit.New(nil, ratelimit.Config{
+			Name:       "ado",
+			Rate:       getRequestRate("ADO_REQUESTS_PER_SECOND", 0),
+			MaxRetries: getInt("HTTP_MAX_RETRIES", 5),
+		})
+		app.Azure = az
 	}
 	org := os.Getenv("ADO_ORG_URL")
 	pat := os.Getenv("ADO_PAT")
@@ -168,11 +397,35 @@ func (app *App) setup(ctx context.Context) error {
 	// Asana setup.
 	log.Info("connecting to Asana")
 	if app.Asana == nil {
-		app.Asana = &asana.Asana{}
+		app.Asana = &asana.Asana{
+			Transport: ratelimit.New(nil, ratelimit.Config{
+				Name:       "asana",
+				Rate:       getRequestRate("ASANA_REQUESTS_PER_SECOND", 25),
+				MaxRetries: getInt("HTTP_MAX_RETRIES", 5),
+			}),
+			CacheTTL: getCacheTTL(),
+		}
 	}
 	app.Asana.Connect(ctx, os.Getenv("ASANA_PAT"))
+	if me, err := app.Asana.CurrentUser(ctx); err != nil {
+		log.WithError(err).Warn("unable to look up the Asana user, its own events will be synced back")
+	} else {
+		app.AsanaUserGID = me.GID
+	}
 
 	app.CacheTTL = getCacheTTL()
+	app.Resolver = NewResolver(app.DB, app.CacheTTL)
+	app.WatermarkOverlap = getDuration("WATERMARK_OVERLAP", 2*time.Minute)
+	app.Queue = getQueueConfig()
+	app.InstanceID = instanceID()
+	app.fatal = make(chan error, 1)
+	app.Breaker = getBreaker()
+	app.Elector = NewElector(app.DB, controllerLease, app.InstanceID, getDuration("LEADER_LEASE_TTL", time.Minute))
+	app.Portfolio = getPortfolioConfig()
+	app.FieldPolicy = getFieldPolicy()
+	app.Hooks = getHookConfig()
+	app.EventsInterval = getDuration("ASANA_EVENTS_INTERVAL", 0)
+	app.Reconcile = getReconcileConfig()
 	app.SyncedTags = make(map[string]asana.Tag)
 	app.loadSyncedTags(ctx)
 
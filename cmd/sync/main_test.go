@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"os"
+	"sync"
 	"testing"
 	"time"
 )
@@ -81,3 +83,101 @@ func TestGetCacheTTLWithEnv(t *testing.T) {
 		t.Errorf("expected %v, got %v", expected, got)
 	}
 }
+
+func TestGetDurationDefault(t *testing.T) {
+	os.Unsetenv("WATERMARK_OVERLAP")
+	expected := 2 * time.Minute
+	if got := getDuration("WATERMARK_OVERLAP", 2*time.Minute); got != expected {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestGetDurationWithEnv(t *testing.T) {
+	os.Setenv("WATERMARK_OVERLAP", "30s")
+	defer os.Unsetenv("WATERMARK_OVERLAP")
+	expected := 30 * time.Second
+	if got := getDuration("WATERMARK_OVERLAP", 2*time.Minute); got != expected {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestGetDurationInvalid(t *testing.T) {
+	os.Setenv("WATERMARK_OVERLAP", "soon")
+	defer os.Unsetenv("WATERMARK_OVERLAP")
+	expected := 2 * time.Minute
+	if got := getDuration("WATERMARK_OVERLAP", 2*time.Minute); got != expected {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestGetRequestRate(t *testing.T) {
+	os.Unsetenv("ASANA_REQUESTS_PER_SECOND")
+	if got := getRequestRate("ASANA_REQUESTS_PER_SECOND", 25); got != 25 {
+		t.Errorf("expected default 25, got %v", got)
+	}
+
+	os.Setenv("ASANA_REQUESTS_PER_SECOND", "2.5")
+	defer os.Unsetenv("ASANA_REQUESTS_PER_SECOND")
+	if got := getRequestRate("ASANA_REQUESTS_PER_SECOND", 25); got != 2.5 {
+		t.Errorf("expected 2.5, got %v", got)
+	}
+
+	os.Setenv("ASANA_REQUESTS_PER_SECOND", "-1")
+	if got := getRequestRate("ASANA_REQUESTS_PER_SECOND", 25); got != 25 {
+		t.Errorf("expected default for negative rate, got %v", got)
+	}
+}
+
+func TestGetInt(t *testing.T) {
+	os.Unsetenv("HTTP_MAX_RETRIES")
+	if got := getInt("HTTP_MAX_RETRIES", 5); got != 5 {
+		t.Errorf("expected default 5, got %v", got)
+	}
+
+	os.Setenv("HTTP_MAX_RETRIES", "0")
+	defer os.Unsetenv("HTTP_MAX_RETRIES")
+	if got := getInt("HTTP_MAX_RETRIES", 5); got != 0 {
+		t.Errorf("expected 0, got %v", got)
+	}
+
+	os.Setenv("HTTP_MAX_RETRIES", "-1")
+	if got := getInt("HTTP_MAX_RETRIES", 5); got != 5 {
+		t.Errorf("expected default for negative value, got %v", got)
+	}
+}
+
+// Returns true once the workers finish within the grace period
+func TestWaitForWorkersDrains(t *testing.T) {
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		time.Sleep(10 * time.Millisecond)
+	}()
+	cancelled := false
+
+	if !waitForWorkers(&workers, func() { cancelled = true }, time.Second) {
+		t.Error("Expected the workers to finish in time")
+	}
+	if cancelled {
+		t.Error("Expected the jobs not to be cancelled")
+	}
+}
+
+// Cancels the jobs once the grace period is over
+func TestWaitForWorkersCancelsAfterGrace(t *testing.T) {
+	jobCtx, cancel := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		<-jobCtx.Done()
+	}()
+
+	if waitForWorkers(&workers, cancel, 10*time.Millisecond) {
+		t.Error("Expected the grace period to run out")
+	}
+	if jobCtx.Err() == nil {
+		t.Error("Expected the jobs to be cancelled")
+	}
+}
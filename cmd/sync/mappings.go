@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// reconcileProjects keeps the project mappings pinned to their stable IDs.
+// Mappings without IDs, saved before they were stored or edited in the web
+// UI, have them resolved from their names. Once per RenameInterval, mappings
+// whose project or workspace was renamed upstream also have their names
+// refreshed, so the name based lookups keep working, and the rename
+// recorded for the web UI. Only the ADO projects and Asana projects needed
+// are listed.
+func (app *App) reconcileProjects(ctx context.Context) {
+	ctx, span := app.Tracer.Start(ctx, "sync.reconcileProjects")
+	defer span.End()
+
+	projects, err := app.DB.Projects(ctx)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		log.WithError(err).Error("error getting projects to reconcile")
+		return
+	}
+
+	checkRenames := time.Since(app.renamesCheckedAt) >= app.Reconcile.RenameInterval
+	span.SetAttributes(attribute.Bool("check_renames", checkRenames))
+	var pending []db.Project
+	for _, p := range projects {
+		if checkRenames || p.ADOProjectID == "" || p.AsanaWorkspaceGID == "" || p.AsanaProjectGID == "" {
+			pending = append(pending, p)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	adoNames := make(map[string]string)
+	adoIDs := make(map[string]string)
+	if checkRenames || slices.ContainsFunc(pending, func(p db.Project) bool { return p.ADOProjectID == "" }) {
+		adoProjects, err := app.Azure.GetProjects(ctx)
+		if err != nil {
+			span.RecordError(err, trace.WithStackTrace(true))
+			log.WithError(err).Warn("unable to list ADO projects, mappings not reconciled")
+			return
+		}
+		for _, p := range adoProjects {
+			if p.Id == nil || p.Name == nil {
+				continue
+			}
+			adoNames[p.Id.String()] = *p.Name
+			adoIDs[*p.Name] = p.Id.String()
+		}
+	}
+
+	wsNames, err := app.asanaWorkspaces(ctx)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		log.WithError(err).Warn("unable to list Asana workspaces, mappings not reconciled")
+		return
+	}
+	wsGIDs := make(map[string]string)
+	for gid, name := range wsNames {
+		wsGIDs[name] = gid
+	}
+
+	// Projects are listed once per workspace.
+	asanaProjects := make(map[string][]asana.Project)
+	listProjects := func(workspace string) []asana.Project {
+		if projs, ok := asanaProjects[workspace]; ok {
+			return projs
+		}
+		projs, err := app.Asana.ListProjects(ctx, workspace)
+		if err != nil {
+			log.WithError(err).WithField("workspace", workspace).Warn("unable to list Asana projects")
+		}
+		asanaProjects[workspace] = projs
+		return projs
+	}
+
+	updated := 0
+	for _, p := range pending {
+		orig := p
+		plog := log.WithField("mapping", p.ID.Hex())
+		now := time.Now()
+
+		p.ADOProjectID, p.ADOProjectName = reconcileName(&p, db.RenameADOProject, p.ADOProjectID, p.ADOProjectName, adoNames, adoIDs, now)
+		p.AsanaWorkspaceGID, p.AsanaWorkspaceName = reconcileName(&p, db.RenameAsanaWorkspace, p.AsanaWorkspaceGID, p.AsanaWorkspaceName, wsNames, wsGIDs, now)
+
+		projNames := make(map[string]string)
+		projGIDs := make(map[string]string)
+		if checkRenames || p.AsanaProjectGID == "" {
+			for _, ap := range listProjects(p.AsanaWorkspaceName) {
+				projNames[ap.GID] = ap.Name
+				projGIDs[ap.Name] = ap.GID
+			}
+		}
+		p.AsanaProjectGID, p.AsanaProjectName = reconcileName(&p, db.RenameAsanaProject, p.AsanaProjectGID, p.AsanaProjectName, projNames, projGIDs, now)
+
+		if len(p.Renames) == len(orig.Renames) && p.ADOProjectID == orig.ADOProjectID &&
+			p.AsanaWorkspaceGID == orig.AsanaWorkspaceGID && p.AsanaProjectGID == orig.AsanaProjectGID {
+			continue
+		}
+		for _, r := range p.Renames[len(orig.Renames):] {
+			plog.WithField("kind", r.Kind).WithField("from", r.From).WithField("to", r.To).Warn("renamed upstream")
+		}
+		if p.ADOProjectName != orig.ADOProjectName {
+			app.moveCheckpoint(ctx, orig.ADOProjectName, p.ADOProjectName)
+			if err := app.DB.RenameTasksProject(ctx, orig.ADOProjectName, p.ADOProjectName); err != nil {
+				plog.WithError(err).Warn("error moving task mappings to the new project name")
+			}
+		}
+		if err := app.DB.UpdateProject(ctx, p); err != nil {
+			span.RecordError(err, trace.WithStackTrace(true))
+			plog.WithError(err).Error("error updating project mapping")
+			continue
+		}
+		updated++
+	}
+	if checkRenames {
+		app.renamesCheckedAt = time.Now()
+	}
+	span.SetAttributes(attribute.Int("updated", updated))
+}
+
+// asanaWorkspaces returns the names of the Asana workspaces by GID, cached
+// by the resolver.
+func (app *App) asanaWorkspaces(ctx context.Context) (map[string]string, error) {
+	v, err := app.Resolver.Lookup(ctx, workspacesKey, func(ctx context.Context) (map[string]interface{}, error) {
+		workspaces, err := app.Asana.ListWorkspaces(ctx)
+		if err != nil {
+			return nil, err
+		}
+		names := make(map[string]interface{}, len(workspaces))
+		for _, ws := range workspaces {
+			names[strconv.FormatInt(ws.ID, 10)] = ws.Name
+		}
+		return names, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(v))
+	for gid, name := range v {
+		if s, ok := name.(string); ok {
+			names[gid] = s
+		}
+	}
+	return names, nil
+}
+
+// reconcileName returns the ID and name of a project or workspace. A missing
+// ID is resolved from the name; a known ID has its current name looked up
+// and a rename recorded on p when it changed. Objects that cannot be found
+// are left untouched.
+func reconcileName(p *db.Project, kind, id, name string, names, ids map[string]string, now time.Time) (string, string) {
+	if id == "" {
+		return ids[name], name
+	}
+	current, ok := names[id]
+	if !ok || current == name {
+		return id, name
+	}
+	p.Renames = append(p.Renames, db.ProjectRename{Kind: kind, From: name, To: current, At: now})
+	return id, current
+}
+
+// moveCheckpoint carries the sync checkpoint of a renamed ADO project over
+// to its new name.
+func (app *App) moveCheckpoint(ctx context.Context, from, to string) {
+	cp, err := app.DB.SyncCheckpoint(ctx, from)
+	if err != nil {
+		log.WithError(err).WithField("project", from).Warn("error getting sync checkpoint to move")
+		return
+	}
+	if cp.UpdatedAt.IsZero() {
+		return
+	}
+	if !app.Elector.IsLeader() {
+		log.WithField("project", to).Warn("controller lease lost, not moving sync checkpoint")
+		return
+	}
+	if err := app.DB.WriteSyncCheckpoint(ctx, to, cp.Time); err != nil {
+		log.WithError(err).WithField("project", to).Warn("error moving sync checkpoint")
+	}
+}
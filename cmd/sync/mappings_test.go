@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func setupMappingsApp(adoName, workspace, project string) (*App, *enhancedMockDB, uuid.UUID) {
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	id := uuid.New()
+	app.Azure.(*enhancedMockAzure).projects = []core.TeamProjectReference{{Id: &id, Name: &adoName}}
+	mockAsana := app.Asana.(*enhancedMockAsana)
+	mockAsana.workspaces = []asana.Workspace{{ID: 42, Name: workspace}}
+	mockAsana.projects[workspace] = map[string]string{project: "P1"}
+	return app, mockDB, id
+}
+
+func TestReconcileProjectsResolvesIDs(t *testing.T) {
+	app, mockDB, id := setupMappingsApp("ADO", "WS", "Proj")
+	mockDB.projects = []db.Project{{ID: primitive.NewObjectID(), ADOProjectName: "ADO", AsanaWorkspaceName: "WS", AsanaProjectName: "Proj"}}
+
+	app.reconcileProjects(context.Background())
+
+	require.Len(t, mockDB.updateProjectCalls, 1)
+	p := mockDB.updateProjectCalls[0]
+	assert.Equal(t, id.String(), p.ADOProjectID)
+	assert.Equal(t, "42", p.AsanaWorkspaceGID)
+	assert.Equal(t, "P1", p.AsanaProjectGID)
+	assert.Empty(t, p.Renames)
+}
+
+func TestReconcileProjectsRecordsRenames(t *testing.T) {
+	app, mockDB, id := setupMappingsApp("ADO renamed", "WS", "Proj renamed")
+	mockDB.projects = []db.Project{{
+		ID:                 primitive.NewObjectID(),
+		ADOProjectID:       id.String(),
+		ADOProjectName:     "ADO",
+		AsanaWorkspaceGID:  "42",
+		AsanaWorkspaceName: "WS",
+		AsanaProjectGID:    "P1",
+		AsanaProjectName:   "Proj",
+	}}
+	checkpoint := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mockDB.checkpoints["ADO"] = db.SyncCheckpoint{ADOProjectName: "ADO", Time: checkpoint, UpdatedAt: time.Now()}
+	mockDB.tasks[7] = db.TaskMapping{ADOProjectID: "ADO", ADOTaskID: 7, AsanaTaskID: "t7"}
+
+	app.reconcileProjects(context.Background())
+
+	require.Len(t, mockDB.updateProjectCalls, 1)
+	p := mockDB.updateProjectCalls[0]
+	assert.Equal(t, "ADO renamed", p.ADOProjectName)
+	assert.Equal(t, "Proj renamed", p.AsanaProjectName)
+	require.Len(t, p.Renames, 2)
+	assert.Equal(t, db.RenameADOProject, p.Renames[0].Kind)
+	assert.Equal(t, "ADO", p.Renames[0].From)
+	assert.Equal(t, "ADO renamed", p.Renames[0].To)
+	assert.Equal(t, db.RenameAsanaProject, p.Renames[1].Kind)
+	assert.Equal(t, checkpoint, mockDB.checkpoints["ADO renamed"].Time)
+	assert.Equal(t, "ADO renamed", mockDB.tasks[7].ADOProjectID, "task mappings follow the rename")
+}
+
+func TestReconcileProjectsUnchanged(t *testing.T) {
+	app, mockDB, id := setupMappingsApp("ADO", "WS", "Proj")
+	mockDB.projects = []db.Project{{
+		ID:                 primitive.NewObjectID(),
+		ADOProjectID:       id.String(),
+		ADOProjectName:     "ADO",
+		AsanaWorkspaceGID:  "42",
+		AsanaWorkspaceName: "WS",
+		AsanaProjectGID:    "P1",
+		AsanaProjectName:   "Proj",
+	}}
+
+	app.reconcileProjects(context.Background())
+
+	assert.Empty(t, mockDB.updateProjectCalls)
+}
+
+func TestReconcileProjectsChecksRenamesOnInterval(t *testing.T) {
+	app, mockDB, id := setupMappingsApp("ADO", "WS", "Proj")
+	app.Reconcile.RenameInterval = time.Hour
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+	pinned := db.Project{
+		ID:                 primitive.NewObjectID(),
+		ADOProjectID:       id.String(),
+		ADOProjectName:     "ADO",
+		AsanaWorkspaceGID:  "42",
+		AsanaWorkspaceName: "WS",
+		AsanaProjectGID:    "P1",
+		AsanaProjectName:   "Proj",
+	}
+	mockDB.projects = []db.Project{pinned}
+	app.reconcileProjects(context.Background())
+	require.Equal(t, 1, mockAzure.getProjectsCalls)
+
+	// Renames are only looked for once the interval has passed.
+	renamed := "ADO renamed"
+	mockAzure.projects[0].Name = &renamed
+	app.reconcileProjects(context.Background())
+	assert.Equal(t, 1, mockAzure.getProjectsCalls)
+	assert.Equal(t, 1, mockAsana.listProjectsCalls)
+	assert.Empty(t, mockDB.updateProjectCalls)
+
+	// Missing IDs are resolved straight away, listing only what they need.
+	unresolved := db.Project{ID: primitive.NewObjectID(), ADOProjectID: id.String(), ADOProjectName: "ADO", AsanaWorkspaceGID: "42", AsanaWorkspaceName: "WS", AsanaProjectName: "Proj"}
+	mockDB.projects = []db.Project{pinned, unresolved}
+	app.reconcileProjects(context.Background())
+	assert.Equal(t, 1, mockAzure.getProjectsCalls)
+	assert.Equal(t, 2, mockAsana.listProjectsCalls)
+	require.Len(t, mockDB.updateProjectCalls, 1)
+	assert.Equal(t, "P1", mockDB.updateProjectCalls[0].AsanaProjectGID)
+	assert.Empty(t, mockDB.updateProjectCalls[0].Renames)
+
+	mockDB.projects = []db.Project{pinned}
+	app.renamesCheckedAt = time.Now().Add(-2 * time.Hour)
+	app.reconcileProjects(context.Background())
+	assert.Equal(t, 2, mockAzure.getProjectsCalls)
+	require.Len(t, mockDB.updateProjectCalls, 2)
+	assert.Equal(t, renamed, mockDB.updateProjectCalls[1].ADOProjectName)
+	assert.Equal(t, 1, mockAsana.listWorkspacesCalls, "workspaces are cached")
+}
+
+func TestAsanaProjectForADOUsesStoredGID(t *testing.T) {
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockDB.projects = []db.Project{{ADOProjectName: "ADO", AsanaWorkspaceName: "WS", AsanaProjectName: "Old name", AsanaProjectGID: "P1"}}
+
+	gid, workspace, err := app.asanaProjectForADO(context.Background(), "ADO")
+
+	require.NoError(t, err)
+	assert.Equal(t, "P1", gid)
+	assert.Equal(t, "WS", workspace)
+	assert.Empty(t, mockDB.upsertCacheCalls)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/azure"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PortfolioConfig controls mirroring ADO work items of selected types (such as
+// Epics and Features) as Asana projects inside a portfolio.
+type PortfolioConfig struct {
+	WorkItemTypes map[string]bool
+	PortfolioGID  string
+	TemplateGID   string
+	TeamGID       string
+}
+
+// Enabled reports whether any work item types are mirrored as projects.
+func (c PortfolioConfig) Enabled() bool {
+	return len(c.WorkItemTypes) > 0
+}
+
+func getPortfolioConfig() PortfolioConfig {
+	cfg := PortfolioConfig{
+		WorkItemTypes: make(map[string]bool),
+		PortfolioGID:  os.Getenv("ASANA_PORTFOLIO_GID"),
+		TemplateGID:   os.Getenv("ASANA_PROJECT_TEMPLATE_GID"),
+		TeamGID:       os.Getenv("ASANA_TEAM_GID"),
+	}
+	for _, t := range strings.Split(os.Getenv("PORTFOLIO_WORK_ITEM_TYPES"), ",") {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			cfg.WorkItemTypes[t] = true
+		}
+	}
+	return cfg
+}
+
+// projectStatusForState maps an ADO state to an Asana project status type.
+func projectStatusForState(state string) string {
+	switch strings.ToLower(state) {
+	case "closed", "done", "resolved", "completed":
+		return asana.StatusComplete
+	case "removed", "cut":
+		return asana.StatusOffTrack
+	case "new", "proposed", "to do":
+		return asana.StatusOnHold
+	default:
+		return asana.StatusOnTrack
+	}
+}
+
+func (app *App) isProjectWorkItem(wi azure.WorkItem) bool {
+	return app.Portfolio.WorkItemTypes[wi.WorkItemType]
+}
+
+// syncWorkItemProject creates or updates the Asana project mirroring the work
+// item and posts a status update when the work item state has changed.
+func (app *App) syncWorkItemProject(ctx context.Context, wi azure.WorkItem, name, desc string) error {
+	wp, err := app.DB.WorkItemProjectByADOID(ctx, wi.ID)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		workspace := app.workspaceForADO(ctx, wi.TeamProject)
+		if workspace == "" {
+			log.WithField("project", wi.TeamProject).Debug("project not mapped to Asana, skipping")
+			return nil
+		}
+		wp, err = app.createWorkItemProject(ctx, wi, workspace, name, desc)
+		if err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		if err := app.Asana.UpdateProject(ctx, wp.AsanaProjectID, name, desc); err != nil {
+			return err
+		}
+	}
+
+	if app.Portfolio.PortfolioGID != "" && wp.PortfolioID != app.Portfolio.PortfolioGID {
+		if err := app.Asana.AddProjectToPortfolio(ctx, app.Portfolio.PortfolioGID, wp.AsanaProjectID); err != nil {
+			return err
+		}
+		wp.PortfolioID = app.Portfolio.PortfolioGID
+	}
+
+	if wp.ADOState != wi.State {
+		status := asana.ProjectStatus{
+			Type:  projectStatusForState(wi.State),
+			Title: fmt.Sprintf("%s is %s", name, wi.State),
+			Text:  fmt.Sprintf("Azure DevOps state changed to %s.", wi.State),
+		}
+		if wp.ADOState != "" {
+			status.Text = fmt.Sprintf("Azure DevOps state changed from %s to %s.", wp.ADOState, wi.State)
+		}
+		if err := app.Asana.CreateProjectStatus(ctx, wp.AsanaProjectID, status); err != nil {
+			return err
+		}
+		wp.ADOState = wi.State
+	}
+
+	wp.ADOLastUpdated = wi.ChangedDate
+	return app.DB.UpsertWorkItemProject(ctx, wp)
+}
+
+func (app *App) createWorkItemProject(ctx context.Context, wi azure.WorkItem, workspace, name, desc string) (db.WorkItemProject, error) {
+	proj, err := app.Asana.CreateProject(ctx, asana.NewProject{
+		WorkspaceName: workspace,
+		TeamGID:       app.Portfolio.TeamGID,
+		TemplateGID:   app.Portfolio.TemplateGID,
+		Name:          name,
+		Notes:         desc,
+	})
+	if err != nil {
+		return db.WorkItemProject{}, err
+	}
+
+	// Record the mapping straight away so a later failure (for example
+	// adding it to the portfolio) does not lead to a duplicate project.
+	wp := db.WorkItemProject{
+		ADOProjectID:       wi.TeamProject,
+		ADOWorkItemID:      wi.ID,
+		ADOLastUpdated:     wi.ChangedDate,
+		AsanaProjectID:     proj.GID,
+		AsanaWorkspaceName: workspace,
+	}
+	if err := app.DB.UpsertWorkItemProject(ctx, wp); err != nil {
+		return wp, err
+	}
+	return wp, nil
+}
+
+// parentAsanaProject returns the Asana project mirroring the work item's
+// parent, if the parent is synced as a project.
+func (app *App) parentAsanaProject(ctx context.Context, wi azure.WorkItem) (string, string, bool) {
+	if !app.Portfolio.Enabled() || wi.ParentID == 0 {
+		return "", "", false
+	}
+	wp, err := app.DB.WorkItemProjectByADOID(ctx, wi.ParentID)
+	if err != nil || wp.AsanaProjectID == "" {
+		return "", "", false
+	}
+	return wp.AsanaProjectID, wp.AsanaWorkspaceName, true
+}
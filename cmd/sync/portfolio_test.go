@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupPortfolioApp() *App {
+	app := setupTestApp()
+	app.Portfolio = PortfolioConfig{
+		WorkItemTypes: map[string]bool{"Epic": true, "Feature": true},
+		PortfolioGID:  "portfolio-1",
+	}
+	mockDB := app.DB.(*enhancedMockDB)
+	mockDB.projects = []db.Project{
+		{ADOProjectName: "TestProject", AsanaWorkspaceName: "workspace1", AsanaProjectName: "AsanaProj"},
+	}
+	mockAsana := app.Asana.(*enhancedMockAsana)
+	mockAsana.projects["workspace1"] = map[string]string{"AsanaProj": "proj-gid-1"}
+	return app
+}
+
+func TestGetPortfolioConfig(t *testing.T) {
+	os.Setenv("PORTFOLIO_WORK_ITEM_TYPES", "Epic, Feature,")
+	os.Setenv("ASANA_PORTFOLIO_GID", "pf")
+	defer os.Unsetenv("PORTFOLIO_WORK_ITEM_TYPES")
+	defer os.Unsetenv("ASANA_PORTFOLIO_GID")
+
+	cfg := getPortfolioConfig()
+	assert.True(t, cfg.Enabled())
+	assert.Equal(t, map[string]bool{"Epic": true, "Feature": true}, cfg.WorkItemTypes)
+	assert.Equal(t, "pf", cfg.PortfolioGID)
+}
+
+func TestGetPortfolioConfigDisabled(t *testing.T) {
+	os.Unsetenv("PORTFOLIO_WORK_ITEM_TYPES")
+	assert.False(t, getPortfolioConfig().Enabled())
+}
+
+func TestProjectStatusForState(t *testing.T) {
+	assert.Equal(t, asana.StatusComplete, projectStatusForState("Closed"))
+	assert.Equal(t, asana.StatusComplete, projectStatusForState("Done"))
+	assert.Equal(t, asana.StatusOffTrack, projectStatusForState("Removed"))
+	assert.Equal(t, asana.StatusOnHold, projectStatusForState("New"))
+	assert.Equal(t, asana.StatusOnTrack, projectStatusForState("Active"))
+}
+
+func TestHandleTaskCreatesPortfolioProject(t *testing.T) {
+	app := setupPortfolioApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+
+	wi := createTestWorkItem(10, "Launch", "TestProject", "http://ado.com/10", time.Now())
+	wi.WorkItemType = "Epic"
+	wi.State = "Active"
+	mockAzure.workItems[10] = wi
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 10})
+
+	assert.NoError(t, err)
+	assert.Len(t, mockAsana.projectsCreated, 1)
+	assert.Equal(t, "workspace1", mockAsana.projectsCreated[0].WorkspaceName)
+	assert.Empty(t, mockAsana.tasksCreated, "epics should not become tasks")
+	assert.Equal(t, []string{"project-1"}, mockAsana.portfolioItems["portfolio-1"])
+	assert.Len(t, mockAsana.projectStatuses["project-1"], 1)
+	assert.Equal(t, asana.StatusOnTrack, mockAsana.projectStatuses["project-1"][0].Type)
+
+	wp := mockDB.wiProjects[10]
+	assert.Equal(t, "project-1", wp.AsanaProjectID)
+	assert.Equal(t, "Active", wp.ADOState)
+	assert.Equal(t, "portfolio-1", wp.PortfolioID)
+}
+
+func TestHandleTaskPortfolioLookupErrorDoesNotCreateProject(t *testing.T) {
+	app := setupPortfolioApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+	mockDB.errors["WorkItemProjectByADOID"] = fmt.Errorf("mongo down")
+
+	wi := createTestWorkItem(10, "Launch", "TestProject", "http://ado.com/10", time.Now())
+	wi.WorkItemType = "Epic"
+	mockAzure.workItems[10] = wi
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 10})
+
+	assert.Error(t, err)
+	assert.Empty(t, mockAsana.projectsCreated)
+}
+
+func TestHandleTaskUpdatesPortfolioProjectStatusOnStateChange(t *testing.T) {
+	app := setupPortfolioApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+
+	mockDB.wiProjects[10] = db.WorkItemProject{ADOWorkItemID: 10, AsanaProjectID: "existing", ADOState: "Active", PortfolioID: "portfolio-1"}
+	wi := createTestWorkItem(10, "Launch", "TestProject", "http://ado.com/10", time.Now())
+	wi.WorkItemType = "Epic"
+	wi.State = "Closed"
+	mockAzure.workItems[10] = wi
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 10})
+
+	assert.NoError(t, err)
+	assert.Empty(t, mockAsana.projectsCreated)
+	assert.Equal(t, []string{"existing"}, mockAsana.projectsUpdated)
+	assert.Empty(t, mockAsana.portfolioItems, "already in portfolio")
+	assert.Len(t, mockAsana.projectStatuses["existing"], 1)
+	assert.Equal(t, asana.StatusComplete, mockAsana.projectStatuses["existing"][0].Type)
+	assert.Equal(t, "Closed", mockDB.wiProjects[10].ADOState)
+}
+
+func TestHandleTaskPortfolioProjectUnchangedStateNoStatus(t *testing.T) {
+	app := setupPortfolioApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+
+	mockDB.wiProjects[10] = db.WorkItemProject{ADOWorkItemID: 10, AsanaProjectID: "existing", ADOState: "Active", PortfolioID: "portfolio-1"}
+	wi := createTestWorkItem(10, "Launch", "TestProject", "http://ado.com/10", time.Now())
+	wi.WorkItemType = "Feature"
+	wi.State = "Active"
+	mockAzure.workItems[10] = wi
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 10})
+
+	assert.NoError(t, err)
+	assert.Empty(t, mockAsana.projectStatuses)
+}
+
+func TestHandleTaskPortfolioRetriesFailedPortfolioAdd(t *testing.T) {
+	app := setupPortfolioApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+	mockAsana.errors["AddProjectToPortfolio"] = fmt.Errorf("forbidden")
+
+	wi := createTestWorkItem(10, "Launch", "TestProject", "http://ado.com/10", time.Now())
+	wi.WorkItemType = "Epic"
+	mockAzure.workItems[10] = wi
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 10})
+	assert.Error(t, err)
+	assert.Equal(t, "project-1", mockDB.wiProjects[10].AsanaProjectID, "mapping kept despite failure")
+
+	delete(mockAsana.errors, "AddProjectToPortfolio")
+	_, err = app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 10})
+	assert.NoError(t, err)
+	assert.Len(t, mockAsana.projectsCreated, 1, "should not create a second project")
+	assert.Equal(t, []string{"project-1"}, mockAsana.portfolioItems["portfolio-1"])
+}
+
+func TestHandleTaskChildGoesIntoParentProject(t *testing.T) {
+	app := setupPortfolioApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+
+	mockDB.wiProjects[10] = db.WorkItemProject{ADOWorkItemID: 10, AsanaProjectID: "epic-project", AsanaWorkspaceName: "workspace1"}
+	child := createTestWorkItem(11, "Story", "TestProject", "http://ado.com/11", time.Now())
+	child.ParentID = 10
+	mockAzure.workItems[11] = child
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 11})
+
+	assert.NoError(t, err)
+	assert.Len(t, mockAsana.tasksCreated, 1)
+	assert.Equal(t, "epic-project", mockDB.addTaskCalls[0].AsanaProjectID)
+}
+
+func TestHandleTaskChildWithoutProjectParentUsesMapping(t *testing.T) {
+	app := setupPortfolioApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+
+	child := createTestWorkItem(11, "Story", "TestProject", "http://ado.com/11", time.Now())
+	child.ParentID = 99
+	mockAzure.workItems[11] = child
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 11})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "proj-gid-1", mockDB.addTaskCalls[0].AsanaProjectID)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/azure"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	log "github.com/sirupsen/logrus"
+)
+
+// QueueConfig controls how the workers consume the Mongo job queue.
+type QueueConfig struct {
+	// BatchSize is the number of jobs a worker leases, and hydrates, at once.
+	BatchSize int
+	// LeaseTimeout is how long a worker holds its jobs before another worker
+	// may take them over.
+	LeaseTimeout time.Duration
+	// PollInterval is how long an idle worker waits before polling again.
+	PollInterval time.Duration
+	// RetryDelay is how long a failed job waits before its first retry. The
+	// delay doubles with every attempt up to MaxRetryDelay.
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	// MaxAttempts is the number of attempts after which a job is moved to
+	// the dead-letter queue.
+	MaxAttempts int
+	// Shard restricts the workers of this replica to a share of the jobs.
+	Shard db.Shard
+}
+
+func getQueueConfig() QueueConfig {
+	return QueueConfig{
+		BatchSize:     min(getInt("JOB_BATCH_SIZE", 20), azure.MaxBatchSize),
+		LeaseTimeout:  getDuration("JOB_LEASE_TIMEOUT", 10*time.Minute),
+		PollInterval:  getDuration("JOB_POLL_INTERVAL", 5*time.Second),
+		RetryDelay:    getDuration("JOB_RETRY_DELAY", 5*time.Minute),
+		MaxRetryDelay: getDuration("JOB_MAX_RETRY_DELAY", 6*time.Hour),
+		MaxAttempts:   getInt("JOB_MAX_ATTEMPTS", 10),
+		Shard:         getShard(),
+	}
+}
+
+// getShard reads the shard of this replica. Replicas sharing the queue set
+// the same SYNC_SHARD_COUNT and each a different SYNC_SHARD_INDEX.
+func getShard() db.Shard {
+	shard := db.Shard{Count: getInt("SYNC_SHARD_COUNT", 0), Index: getInt("SYNC_SHARD_INDEX", 0)}
+	if shard.Count > 1 && (shard.Index < 0 || shard.Index >= shard.Count) {
+		log.Warnf("SYNC_SHARD_INDEX %d is out of range for %d shards, sharding disabled", shard.Index, shard.Count)
+		return db.Shard{}
+	}
+	return shard
+}
+
+// instanceID identifies this process in job leases.
+func instanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "sync"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// backoff returns how long a job waits before its next attempt: the retry
+// delay doubled for every attempt made, capped at the maximum retry delay.
+func (c QueueConfig) backoff(attempts int) time.Duration {
+	d := c.RetryDelay
+	for i := 1; i < attempts && d < c.MaxRetryDelay; i++ {
+		d *= 2
+	}
+	return min(d, c.MaxRetryDelay)
+}
+
+// Error classes recorded on failed jobs and dead letters.
+const (
+	errorClassValidation      = "validation"
+	errorClassPremiumRequired = "premium_required"
+	errorClassNotFound        = "not_found"
+	errorClassUnauthorized    = "unauthorized"
+	errorClassRateLimited     = "rate_limited"
+	errorClassOther           = "other"
+)
+
+// errorClass groups a sync error by its cause so failures can be triaged in
+// the web UI.
+func errorClass(err error) string {
+	switch {
+	case errors.Is(err, asana.ErrValidation), errors.Is(err, azure.ErrValidation):
+		return errorClassValidation
+	case errors.Is(err, asana.ErrPremiumRequired):
+		return errorClassPremiumRequired
+	case errors.Is(err, asana.ErrNotFound), errors.Is(err, azure.ErrNotFound):
+		return errorClassNotFound
+	case errors.Is(err, asana.ErrUnauthorized), errors.Is(err, azure.ErrUnauthorized):
+		return errorClassUnauthorized
+	case errors.Is(err, asana.ErrRateLimited), errors.Is(err, azure.ErrRateLimited):
+		return errorClassRateLimited
+	}
+	return errorClassOther
+}
+
+// finishJob releases a job after its work item was synced: done when err is
+// nil, otherwise queued for a retry with exponential backoff, or after the
+// delay the API asked for when it was throttled. Errors that would recur on
+// every retry, and jobs out of attempts, are moved to the dead-letter queue.
+// A rejected token fails every job until the service is reconfigured, so the
+// job is put back in the queue and the service stopped.
+func (app *App) finishJob(ctx context.Context, wlog *log.Entry, job db.Job, err error) {
+	jlog := wlog.WithField("ado_task_id", job.ADOTaskID).WithField("attempts", job.Attempts)
+	if err == nil {
+		if err := app.DB.CompleteJob(ctx, job); err != nil {
+			jlog.WithError(err).Error("error completing job")
+		}
+		return
+	}
+
+	if unrecoverableError(err) {
+		if err := app.DB.ReturnJob(ctx, job); err != nil {
+			jlog.WithError(err).Error("error returning job")
+		}
+		app.stop(err)
+		return
+	}
+
+	class := errorClass(err)
+	rateLimited := class == errorClassRateLimited
+	if permanentError(err) || (!rateLimited && job.Attempts >= app.Queue.MaxAttempts) {
+		jlog.WithField("error_class", class).Warn("moving job to the dead-letter queue")
+		if err := app.DB.DeadLetterJob(ctx, job, class, err.Error()); err != nil {
+			jlog.WithError(err).Error("error dead-lettering job")
+		}
+		return
+	}
+
+	delay := app.Queue.backoff(job.Attempts)
+	if wait := asana.RetryAfter(err); rateLimited && wait > 0 {
+		delay = wait
+	}
+	if err := app.DB.FailJob(ctx, job, class, err.Error(), time.Now().Add(delay)); err != nil {
+		jlog.WithError(err).Error("error failing job")
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/azure"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetQueueConfig(t *testing.T) {
+	t.Setenv("JOB_BATCH_SIZE", "500")
+	t.Setenv("JOB_LEASE_TIMEOUT", "1m")
+	t.Setenv("JOB_MAX_ATTEMPTS", "bogus")
+
+	cfg := getQueueConfig()
+
+	assert.Equal(t, azure.MaxBatchSize, cfg.BatchSize, "batches are capped to one ADO request")
+	assert.Equal(t, time.Minute, cfg.LeaseTimeout)
+	assert.Equal(t, 5*time.Second, cfg.PollInterval)
+	assert.Equal(t, 5*time.Minute, cfg.RetryDelay)
+	assert.Equal(t, 6*time.Hour, cfg.MaxRetryDelay)
+	assert.Equal(t, 10, cfg.MaxAttempts)
+}
+
+func TestQueueBackoff(t *testing.T) {
+	cfg := QueueConfig{RetryDelay: time.Minute, MaxRetryDelay: 10 * time.Minute}
+
+	assert.Equal(t, time.Minute, cfg.backoff(1))
+	assert.Equal(t, 2*time.Minute, cfg.backoff(2))
+	assert.Equal(t, 8*time.Minute, cfg.backoff(4))
+	assert.Equal(t, 10*time.Minute, cfg.backoff(5), "capped")
+	assert.Equal(t, 10*time.Minute, cfg.backoff(100))
+}
+
+func TestErrorClass(t *testing.T) {
+	assert.Equal(t, errorClassValidation, errorClass(&asana.APIError{StatusCode: 400}))
+	assert.Equal(t, errorClassPremiumRequired, errorClass(&asana.APIError{StatusCode: 402}))
+	assert.Equal(t, errorClassNotFound, errorClass(fmt.Errorf("wrapped: %w", &azure.APIError{StatusCode: 404, Err: fmt.Errorf("gone")})))
+	assert.Equal(t, errorClassUnauthorized, errorClass(&azure.APIError{StatusCode: 401, Err: fmt.Errorf("denied")}))
+	assert.Equal(t, errorClassRateLimited, errorClass(&asana.APIError{StatusCode: 429}))
+	assert.Equal(t, errorClassOther, errorClass(fmt.Errorf("connection reset")))
+}
+
+func TestFinishJob(t *testing.T) {
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	wlog := log.WithField("test", "queue")
+	ctx := context.Background()
+
+	app.finishJob(ctx, wlog, db.Job{ADOTaskID: 1, Attempts: 1}, nil)
+	assert.Equal(t, db.JobDone, mockDB.jobs[1].State)
+
+	app.finishJob(ctx, wlog, db.Job{ADOTaskID: 2, Attempts: 2}, &asana.APIError{StatusCode: 429})
+	assert.Equal(t, db.JobQueued, mockDB.jobs[2].State)
+	assert.Equal(t, errorClassRateLimited, mockDB.jobs[2].ErrorClass)
+	assert.WithinDuration(t, time.Now().Add(2*app.Queue.RetryDelay), mockDB.jobs[2].VisibleAt, time.Second, "backs off")
+	assert.Empty(t, mockDB.deadLetters)
+
+	app.finishJob(ctx, wlog, db.Job{ADOTaskID: 3, Attempts: app.Queue.MaxAttempts}, fmt.Errorf("asana down"))
+	assert.Equal(t, db.JobFailed, mockDB.jobs[3].State, "out of attempts")
+	assert.Equal(t, errorClassOther, mockDB.deadLetters[3].ErrorClass)
+
+	app.finishJob(ctx, wlog, db.Job{ADOTaskID: 4, Attempts: 1}, &asana.APIError{StatusCode: 400})
+	assert.Equal(t, db.JobFailed, mockDB.jobs[4].State, "permanent errors are not retried")
+	assert.Equal(t, errorClassValidation, mockDB.deadLetters[4].ErrorClass)
+
+	app.finishJob(ctx, wlog, mockDB.jobs[4], nil)
+	assert.NotContains(t, mockDB.deadLetters, 4, "success clears the dead letter")
+}
+
+func TestFinishJobRateLimited(t *testing.T) {
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	wlog := log.WithField("test", "queue")
+	ctx := context.Background()
+
+	app.finishJob(ctx, wlog, db.Job{ADOTaskID: 1, Attempts: 3}, &asana.APIError{StatusCode: 429, RetryAfter: 30 * time.Second})
+	assert.Equal(t, db.JobQueued, mockDB.jobs[1].State)
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), mockDB.jobs[1].VisibleAt, time.Second, "honours Retry-After")
+
+	app.finishJob(ctx, wlog, db.Job{ADOTaskID: 2, Attempts: app.Queue.MaxAttempts}, &asana.APIError{StatusCode: 429})
+	assert.Equal(t, db.JobQueued, mockDB.jobs[2].State, "throttling does not use up attempts")
+	assert.Empty(t, mockDB.deadLetters)
+}
+
+func TestFinishJobUnauthorizedStopsService(t *testing.T) {
+	app := setupTestApp()
+	app.fatal = make(chan error, 1)
+	mockDB := app.DB.(*enhancedMockDB)
+
+	err := &asana.APIError{StatusCode: 401}
+	app.finishJob(context.Background(), log.WithField("test", "queue"), db.Job{ADOTaskID: 1, Attempts: 1}, err)
+
+	assert.Equal(t, db.JobQueued, mockDB.jobs[1].State)
+	assert.Equal(t, 0, mockDB.jobs[1].Attempts, "the attempt is not counted")
+	assert.Empty(t, mockDB.deadLetters)
+	select {
+	case got := <-app.fatal:
+		assert.ErrorIs(t, got, asana.ErrUnauthorized)
+	default:
+		t.Fatal("service not stopped")
+	}
+}
+
+func TestGetShard(t *testing.T) {
+	t.Setenv("SYNC_SHARD_COUNT", "3")
+	t.Setenv("SYNC_SHARD_INDEX", "2")
+	assert.Equal(t, db.Shard{Count: 3, Index: 2}, getShard())
+
+	t.Setenv("SYNC_SHARD_INDEX", "3")
+	assert.Equal(t, db.Shard{}, getShard(), "out of range disables sharding")
+}
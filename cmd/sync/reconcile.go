@@ -0,0 +1,474 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/azure"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/schedule"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ReconcileConfig says when the mapped projects are reconciled besides the
+// reconciliations requested from the web UI.
+type ReconcileConfig struct {
+	// Schedule is nil when projects are only reconciled on request.
+	Schedule *schedule.Schedule
+	// Repair repairs the drift found by scheduled reconciliations.
+	Repair bool
+	// RenameInterval is how often the project mappings are checked for
+	// projects and workspaces renamed upstream.
+	RenameInterval time.Duration
+}
+
+func getReconcileConfig() ReconcileConfig {
+	cfg := ReconcileConfig{RenameInterval: getDuration("RENAME_CHECK_INTERVAL", time.Hour)}
+	if expr := os.Getenv("RECONCILE_SCHEDULE"); expr != "" {
+		s, err := schedule.New(expr, "")
+		if err != nil {
+			log.WithError(err).Warn("invalid RECONCILE_SCHEDULE, reconciling on request only")
+		} else {
+			cfg.Schedule = &s
+		}
+	}
+	if v := os.Getenv("RECONCILE_REPAIR"); v != "" {
+		repair, err := strconv.ParseBool(v)
+		if err != nil {
+			log.WithError(err).Warn("unable to parse RECONCILE_REPAIR, reporting drift only")
+		}
+		cfg.Repair = repair
+	}
+	return cfg
+}
+
+// reconcileLoop runs the due reconciliations every interval until ctx is
+// cancelled. It runs beside the controller, which a reconciliation listing
+// every work item and Asana task of a large project would otherwise hold
+// up. Only the controller replica reconciles.
+func (app *App) reconcileLoop(ctx context.Context, interval time.Duration) {
+	for {
+		if app.Elector.IsLeader() {
+			app.runReconciliations(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// runReconciliations reconciles the mapped ADO projects requested from the
+// web UI, and those due on the reconciliation schedule. Each report
+// replaces the previous one of the project. It stops once ctx is cancelled
+// or the replica loses the controller lease, leaving the remaining projects
+// to the next run.
+func (app *App) runReconciliations(ctx context.Context) {
+	ctx, span := app.Tracer.Start(ctx, "sync.runReconciliations")
+	defer span.End()
+
+	projects, err := app.DB.Projects(ctx)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		log.WithError(err).Warn("error getting projects to reconcile")
+		return
+	}
+	recs, err := app.DB.Reconciliations(ctx)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		log.WithError(err).Warn("error getting reconciliations")
+		return
+	}
+	last := make(map[string]db.Reconciliation)
+	for _, r := range recs {
+		last[r.ADOProjectName] = r
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range projects {
+		if ctx.Err() != nil {
+			return
+		}
+		if !app.Elector.IsLeader() {
+			log.Warn("controller lease lost, stopping reconciliations")
+			return
+		}
+		if seen[p.ADOProjectName] {
+			continue
+		}
+		seen[p.ADOProjectName] = true
+
+		prev := last[p.ADOProjectName]
+		repair := app.Reconcile.Repair
+		switch {
+		case !prev.RequestedAt.IsZero():
+			repair = prev.Repair
+		case app.Reconcile.Schedule == nil:
+			continue
+		default:
+			now := time.Now()
+			if at := app.Reconcile.Schedule.Next(prev.FinishedAt, now, 0); at.IsZero() || now.Before(at) {
+				continue
+			}
+		}
+
+		rec := app.reconcileProject(ctx, p, repair)
+		if err := app.DB.WriteReconciliation(ctx, rec); err != nil {
+			span.RecordError(err)
+			log.WithError(err).WithField("project", p.ADOProjectName).Warn("error writing reconciliation")
+		}
+	}
+}
+
+// drift is an issue found by a reconciliation, with the repair for it. The
+// issues without a repair are only reported: repairs never delete Asana
+// tasks.
+type drift struct {
+	issue  db.ReconcileIssue
+	repair func(ctx context.Context) error
+}
+
+// reconcileProject compares every work item of the ADO project with its task
+// mapping and Asana task, and repairs the drift found when repair is set.
+// Repairs mostly queue the work items for the workers to sync again.
+func (app *App) reconcileProject(ctx context.Context, p db.Project, repair bool) db.Reconciliation {
+	ctx, span := app.Tracer.Start(ctx, "sync.reconcileProject")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("project", p.ADOProjectName), attribute.Bool("repair", repair))
+	plog := log.WithField("project", p.ADOProjectName)
+	plog.Info("reconciling project")
+
+	rec := db.Reconciliation{ADOProjectName: p.ADOProjectName, StartedAt: time.Now(), Repaired: repair}
+	found, checked, err := app.findDrift(ctx, p)
+	rec.Checked = checked
+	if err != nil {
+		// A partial comparison would report what it could not read as
+		// drift, and repair it.
+		span.RecordError(err, trace.WithStackTrace(true))
+		plog.WithError(err).Error("error reconciling project")
+		rec.Error = err.Error()
+		found = nil
+	}
+
+	rec.Issues = make([]db.ReconcileIssue, 0, len(found))
+	for _, d := range found {
+		if repair && d.repair != nil {
+			if err := d.repair(ctx); err != nil {
+				plog.WithError(err).WithField("ado_task_id", d.issue.ADOTaskID).Warn("error repairing drift")
+			} else {
+				d.issue.Repaired = true
+			}
+		}
+		rec.Issues = append(rec.Issues, d.issue)
+	}
+	rec.FinishedAt = time.Now()
+	span.SetAttributes(attribute.Int("checked", rec.Checked), attribute.Int("issues", len(rec.Issues)))
+	plog.WithField("checked", rec.Checked).WithField("issues", len(rec.Issues)).Info("reconciled project")
+	return rec
+}
+
+// findDrift lists the issues of the ADO project: work items without an
+// Asana task, Asana task names behind the synced revision, mappings and
+// Asana tasks of work items gone from ADO, and work items mapped to several
+// Asana tasks. It returns them with the number of work items compared.
+func (app *App) findDrift(ctx context.Context, p db.Project) ([]drift, int, error) {
+	project := p.ADOProjectName
+	mappings, err := app.DB.Tasks(ctx, project)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting task mappings: %w", err)
+	}
+	mapped := make(map[int][]db.TaskMapping)
+	for _, m := range mappings {
+		mapped[m.ADOTaskID] = append(mapped[m.ADOTaskID], m)
+	}
+
+	refs, _, err := app.Azure.GetChangedWorkItems(ctx, project, db.FirstSync)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting work items: %w", err)
+	}
+	live := make(map[int]bool, len(refs))
+	for _, r := range refs {
+		live[*r.Id] = true
+	}
+	ids := make([]int, 0, len(live)+len(mapped))
+	for id := range live {
+		ids = append(ids, id)
+	}
+	for id := range mapped {
+		if !live[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	wis, err := app.readWorkItems(ctx, ids)
+	if err != nil {
+		return nil, len(ids), err
+	}
+
+	// Asana tasks of the projects the work items were synced to, by GID.
+	asanaProjects := []string{p.AsanaProjectGID}
+	for _, m := range mappings {
+		asanaProjects = append(asanaProjects, m.AsanaProjectID)
+	}
+	sort.Strings(asanaProjects)
+	var listed []asana.Task
+	for _, gid := range slices.Compact(asanaProjects) {
+		if gid == "" {
+			continue
+		}
+		tasks, err := app.Asana.ListProjectTasks(ctx, gid)
+		if err != nil {
+			return nil, len(ids), fmt.Errorf("error listing tasks of Asana project %s: %w", gid, err)
+		}
+		listed = append(listed, tasks...)
+	}
+	tasks := make(map[string]asana.Task, len(listed))
+	for _, t := range listed {
+		tasks[t.GID] = t
+	}
+
+	var found []drift
+	mappedGIDs := make(map[string]bool)
+	for _, id := range ids {
+		ms := mapped[id]
+		wi, ok := wis[id]
+		switch {
+		case len(ms) == 0 && ok && wi.TeamProject == project:
+			d, ok, err := app.unmappedDrift(ctx, wi)
+			if err != nil {
+				return nil, len(ids), err
+			}
+			if ok {
+				found = append(found, d)
+			}
+			continue
+		case len(ms) == 0:
+			continue
+		}
+
+		existing := make(map[string]bool)
+		for _, m := range ms {
+			mappedGIDs[m.AsanaTaskID] = true
+			if _, ok := tasks[m.AsanaTaskID]; ok {
+				existing[m.AsanaTaskID] = true
+				continue
+			}
+			// The task may have moved to an unmapped Asana project.
+			t, err := app.Asana.TaskByGID(ctx, m.AsanaTaskID)
+			if errors.Is(err, asana.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, len(ids), fmt.Errorf("error reading Asana task %s: %w", m.AsanaTaskID, err)
+			}
+			tasks[m.AsanaTaskID] = t
+			existing[m.AsanaTaskID] = true
+		}
+
+		m, extras := keepMapping(ms, existing)
+		for _, extra := range extras {
+			found = append(found, drift{
+				issue: db.ReconcileIssue{
+					Kind:        db.IssueDuplicate,
+					ADOTaskID:   id,
+					AsanaTaskID: extra.AsanaTaskID,
+					Detail:      fmt.Sprintf("also mapped to Asana task %s; the extra mapping is removed", m.AsanaTaskID),
+				},
+				repair: app.removeMapping(extra),
+			})
+		}
+
+		switch {
+		case !ok:
+			found = append(found, drift{
+				issue: db.ReconcileIssue{
+					Kind:        db.IssueOrphan,
+					ADOTaskID:   id,
+					AsanaTaskID: m.AsanaTaskID,
+					Detail:      "work item deleted or unreadable in ADO; the mapping is removed and the Asana task kept",
+				},
+				repair: app.removeMapping(m),
+			})
+		case wi.TeamProject != project:
+			// Moved to another ADO project; the workers keep syncing it.
+		case !existing[m.AsanaTaskID]:
+			remove := app.removeMapping(m)
+			found = append(found, drift{
+				issue: db.ReconcileIssue{
+					Kind:        db.IssueMissingTask,
+					ADOTaskID:   id,
+					AsanaTaskID: m.AsanaTaskID,
+					Detail:      "Asana task deleted; the work item is synced to a new task",
+				},
+				repair: func(ctx context.Context) error {
+					if err := remove(ctx); err != nil {
+						return err
+					}
+					return app.DB.EnqueueJob(ctx, id, project, time.Time{})
+				},
+			})
+		default:
+			if d, ok := staleTitle(wi, m, tasks[m.AsanaTaskID]); ok {
+				d.repair = func(ctx context.Context) error { return app.DB.ForceResync(ctx, id) }
+				found = append(found, d)
+			}
+		}
+	}
+
+	stray, err := app.strayTaskDrift(ctx, listed, mappedGIDs, mapped, live)
+	if err != nil {
+		return nil, len(ids), err
+	}
+	return append(found, stray...), len(ids), nil
+}
+
+// readWorkItems reads the work items in batches. Deleted work items are
+// missing from the result.
+func (app *App) readWorkItems(ctx context.Context, ids []int) (map[int]azure.WorkItem, error) {
+	wis := make(map[int]azure.WorkItem, len(ids))
+	for start := 0; start < len(ids); start += azure.MaxBatchSize {
+		chunk := ids[start:min(start+azure.MaxBatchSize, len(ids))]
+		items, err := app.Azure.GetWorkItemsBatch(ctx, chunk, false)
+		if err != nil {
+			return nil, fmt.Errorf("error reading work items: %w", err)
+		}
+		for _, wi := range items {
+			wis[wi.ID] = wi
+		}
+	}
+	return wis, nil
+}
+
+// unmappedDrift reports a work item of the project without an Asana task.
+// Work items mirrored by Asana projects and those mapped under another ADO
+// project are fine.
+func (app *App) unmappedDrift(ctx context.Context, wi azure.WorkItem) (drift, bool, error) {
+	if app.isProjectWorkItem(wi) {
+		return drift{}, false, nil
+	}
+	_, err := app.DB.TaskByADOTaskID(ctx, wi.ID)
+	if err == nil {
+		return drift{}, false, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return drift{}, false, fmt.Errorf("error getting task mapping of work item %d: %w", wi.ID, err)
+	}
+	return drift{
+		issue: db.ReconcileIssue{
+			Kind:      db.IssueMissingTask,
+			ADOTaskID: wi.ID,
+			Detail:    "work item never synced to Asana",
+		},
+		repair: func(ctx context.Context) error {
+			return app.DB.EnqueueJob(ctx, wi.ID, wi.TeamProject, time.Time{})
+		},
+	}, true, nil
+}
+
+// strayTaskDrift reports the Asana tasks linking a work item that are not
+// mapped: duplicates of a mapped task, or orphans of a work item gone from
+// ADO. Tasks of live work items without a mapping are adopted by the
+// workers once the work item is queued, so they are not reported. Asana
+// tasks are left for someone to delete.
+func (app *App) strayTaskDrift(ctx context.Context, listed []asana.Task, mappedGIDs map[string]bool, mapped map[int][]db.TaskMapping, live map[int]bool) ([]drift, error) {
+	var found []drift
+	for _, t := range listed {
+		id, ok := linkedWorkItem(t)
+		if !ok || mappedGIDs[t.GID] {
+			continue
+		}
+		ms := mapped[id]
+		if len(ms) == 0 {
+			if live[id] {
+				continue
+			}
+			// The work item may belong to another ADO project mapped to the
+			// same Asana project.
+			m, err := app.DB.TaskByADOTaskID(ctx, id)
+			switch {
+			case err == nil:
+				if m.AsanaTaskID == t.GID {
+					continue
+				}
+				ms = []db.TaskMapping{m}
+			case !errors.Is(err, mongo.ErrNoDocuments):
+				return nil, fmt.Errorf("error getting task mapping of work item %d: %w", id, err)
+			}
+		}
+
+		issue := db.ReconcileIssue{ADOTaskID: id, AsanaTaskID: t.GID}
+		if len(ms) > 0 {
+			issue.Kind = db.IssueDuplicate
+			issue.Detail = fmt.Sprintf("Asana task duplicates the mapped task %s", ms[0].AsanaTaskID)
+		} else {
+			issue.Kind = db.IssueOrphan
+			issue.Detail = "Asana task of a work item deleted in ADO"
+		}
+		found = append(found, drift{issue: issue})
+	}
+	return found, nil
+}
+
+// keepMapping picks the mapping to keep among those of a work item: the
+// most recently updated one whose Asana task exists, or the most recently
+// updated one when none does. The others are returned as extras.
+func keepMapping(ms []db.TaskMapping, existing map[string]bool) (db.TaskMapping, []db.TaskMapping) {
+	best := 0
+	for i, m := range ms[1:] {
+		b := ms[best]
+		if existing[m.AsanaTaskID] != existing[b.AsanaTaskID] {
+			if existing[m.AsanaTaskID] {
+				best = i + 1
+			}
+			continue
+		}
+		if m.UpdatedAt.After(b.UpdatedAt) {
+			best = i + 1
+		}
+	}
+	extras := make([]db.TaskMapping, 0, len(ms)-1)
+	for i, m := range ms {
+		if i != best {
+			extras = append(extras, m)
+		}
+	}
+	return ms[best], extras
+}
+
+// staleTitle reports an Asana task whose name differs from the work item
+// title although its revision was synced. Names owned by Asana and
+// revisions still waiting to be synced are fine.
+func staleTitle(wi azure.WorkItem, m db.TaskMapping, t asana.Task) (drift, bool) {
+	if slices.Contains(m.AsanaOwnedFields, fingerprintName) || m.ADORevision < wi.Rev {
+		return drift{}, false
+	}
+	name, err := wi.FormatTitle()
+	if err != nil || name == t.Name {
+		return drift{}, false
+	}
+	return drift{issue: db.ReconcileIssue{
+		Kind:        db.IssueStaleTitle,
+		ADOTaskID:   wi.ID,
+		AsanaTaskID: m.AsanaTaskID,
+		Detail:      fmt.Sprintf("%q in Asana, %q in ADO; the work item is resynced", t.Name, name),
+	}}, true
+}
+
+// removeMapping returns a repair removing the task mapping.
+func (app *App) removeMapping(m db.TaskMapping) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return app.DB.RemoveTask(ctx, m.ID)
+	}
+}
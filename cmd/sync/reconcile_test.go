@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/schedule"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var reconcileProject = db.Project{ADOProjectName: "TestProject", AsanaProjectGID: "proj-1"}
+
+// setupReconcileTest maps work items of TestProject with every kind of
+// drift:
+//   - 123 is in sync, but also mapped to a deleted task and duplicated by an
+//     unmapped Asana task;
+//   - 124 was never synced, though an Asana task was created for it;
+//   - 125 is mapped to a task deleted in Asana;
+//   - 126 has a stale name in Asana and 127 a change still to sync;
+//   - 130 was deleted in ADO, and 999 left an Asana task behind.
+func setupReconcileTest(t *testing.T) (*App, *enhancedMockDB, *enhancedMockAsana) {
+	t.Helper()
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockDB.projects = []db.Project{reconcileProject}
+
+	for id := 123; id <= 127; id++ {
+		wi := createTestWorkItem(id, "Task", "TestProject", "http://ado.com", time.Now())
+		wi.Rev = 2
+		mockAzure.workItems[id] = wi
+	}
+	mapping := func(adoID int, taskGID string, rev int) db.TaskMapping {
+		return db.TaskMapping{
+			ID:             primitive.NewObjectID(),
+			ADOProjectID:   "TestProject",
+			ADOTaskID:      adoID,
+			ADORevision:    rev,
+			AsanaProjectID: "proj-1",
+			AsanaTaskID:    taskGID,
+		}
+	}
+	mockDB.tasks[123] = mapping(123, "task-1", 2)
+	mockDB.duplicates = []db.TaskMapping{mapping(123, "task-dup", 2)}
+	mockDB.tasks[125] = mapping(125, "task-5", 2)
+	mockDB.tasks[126] = mapping(126, "task-6", 2)
+	mockDB.tasks[127] = mapping(127, "task-7", 1)
+	mockDB.tasks[130] = mapping(130, "task-30", 2)
+
+	mockAsana.gone["task-dup"] = true
+	mockAsana.gone["task-5"] = true
+	mockAsana.tasks["proj-1"] = []asana.Task{
+		{GID: "task-1", Name: "User Story 123: Task", HTMLNotes: `<body><a href="https://dev.azure.com/org/_apis/wit/workItems/123">User Story 123:</a> Task</body>`},
+		{GID: "task-6", Name: "Old name"},
+		{GID: "task-7", Name: "Old name"},
+		{GID: "task-30", Name: "User Story 130: Gone"},
+		{GID: "task-x", Name: "User Story 123: Task", HTMLNotes: `<body><a href="https://dev.azure.com/org/_apis/wit/workItems/123">User Story 123:</a> Task</body>`},
+		{GID: "task-z", Name: "User Story 124: Task", HTMLNotes: `<body><a href="https://dev.azure.com/org/_apis/wit/workItems/124">User Story 124:</a> Task</body>`},
+		{GID: "task-old", Name: "User Story 999: Gone", HTMLNotes: `<body><a href="https://dev.azure.com/org/_apis/wit/workItems/999">User Story 999:</a> Task</body>`},
+		{GID: "task-manual", Name: "Added by hand"},
+	}
+	return app, mockDB, mockAsana
+}
+
+// issuesByTask keys the issues by kind and Asana task, or work item when
+// there is no task.
+func issuesByTask(issues []db.ReconcileIssue) map[string]db.ReconcileIssue {
+	byTask := make(map[string]db.ReconcileIssue)
+	for _, i := range issues {
+		key := i.AsanaTaskID
+		if key == "" {
+			key = fmt.Sprintf("work item %d", i.ADOTaskID)
+		}
+		byTask[i.Kind+" "+key] = i
+	}
+	return byTask
+}
+
+func TestReconcileProjectReportsDrift(t *testing.T) {
+	app, mockDB, _ := setupReconcileTest(t)
+
+	rec := app.reconcileProject(context.Background(), reconcileProject, false)
+
+	assert.Empty(t, rec.Error)
+	assert.Equal(t, 6, rec.Checked)
+	assert.False(t, rec.Repaired)
+	issues := issuesByTask(rec.Issues)
+	assert.ElementsMatch(t, []string{
+		"duplicate task-dup",
+		"duplicate task-x",
+		"missing task work item 124",
+		"missing task task-5",
+		"stale title task-6",
+		"orphan task-30",
+		"orphan task-old",
+	}, slices.Collect(maps.Keys(issues)))
+	assert.Equal(t, 123, issues["duplicate task-x"].ADOTaskID)
+	assert.Equal(t, 999, issues["orphan task-old"].ADOTaskID)
+	assert.Contains(t, issues["stale title task-6"].Detail, `"Old name" in Asana`)
+	for _, i := range rec.Issues {
+		assert.False(t, i.Repaired)
+	}
+	assert.Empty(t, mockDB.jobs)
+	assert.Len(t, mockDB.tasks, 5)
+	assert.Len(t, mockDB.duplicates, 1)
+}
+
+func TestReconcileProjectRepairsDrift(t *testing.T) {
+	app, mockDB, _ := setupReconcileTest(t)
+
+	rec := app.reconcileProject(context.Background(), reconcileProject, true)
+
+	assert.True(t, rec.Repaired)
+	issues := issuesByTask(rec.Issues)
+	for key, repaired := range map[string]bool{
+		"duplicate task-dup":         true,
+		"duplicate task-x":           false,
+		"missing task work item 124": true,
+		"missing task task-5":        true,
+		"stale title task-6":         true,
+		"orphan task-30":             true,
+		"orphan task-old":            false,
+	} {
+		assert.Equal(t, repaired, issues[key].Repaired, key)
+	}
+
+	assert.Empty(t, mockDB.duplicates)
+	assert.NotContains(t, mockDB.tasks, 125, "the work item is synced to a new task")
+	assert.NotContains(t, mockDB.tasks, 130)
+	assert.Equal(t, "task-1", mockDB.tasks[123].AsanaTaskID)
+	assert.ElementsMatch(t, []int{124, 125, 126}, slices.Collect(maps.Keys(mockDB.jobs)))
+	assert.True(t, mockDB.jobs[126].Force)
+}
+
+func TestReconcileProjectStopsOnReadError(t *testing.T) {
+	app, mockDB, mockAsana := setupReconcileTest(t)
+	mockAsana.errors["ListProjectTasks"] = assert.AnError
+
+	rec := app.reconcileProject(context.Background(), reconcileProject, true)
+
+	assert.Contains(t, rec.Error, "error listing tasks of Asana project proj-1")
+	assert.Empty(t, rec.Issues, "nothing is repaired from a partial comparison")
+	assert.Empty(t, mockDB.jobs)
+	assert.Len(t, mockDB.tasks, 5)
+	assert.False(t, rec.FinishedAt.IsZero())
+}
+
+func TestReconcileProjectStopsOnMappingLookupError(t *testing.T) {
+	app, mockDB, _ := setupReconcileTest(t)
+	mockDB.errors["TaskByADOTaskID"] = assert.AnError
+
+	rec := app.reconcileProject(context.Background(), reconcileProject, true)
+
+	assert.Contains(t, rec.Error, "error getting task mapping of work item")
+	assert.Empty(t, rec.Issues, "a failed lookup is not reported as a missing task")
+	assert.Empty(t, mockDB.jobs)
+}
+
+func TestRunReconciliations(t *testing.T) {
+	app, mockDB, _ := setupReconcileTest(t)
+	ctx := context.Background()
+	mockDB.projects = append(mockDB.projects, db.Project{ADOProjectName: "Other"})
+
+	app.runReconciliations(ctx)
+	assert.Empty(t, mockDB.recs, "nothing runs unless requested or scheduled")
+
+	assert.NoError(t, mockDB.RequestReconciliation(ctx, "TestProject", true))
+	app.runReconciliations(ctx)
+	rec := mockDB.recs["TestProject"]
+	assert.True(t, rec.Repaired)
+	assert.NotEmpty(t, rec.Issues)
+	assert.True(t, rec.RequestedAt.IsZero(), "the request is done")
+	assert.NotContains(t, mockDB.recs, "Other")
+
+	daily, err := schedule.New("@daily", "")
+	assert.NoError(t, err)
+	app.Reconcile = ReconcileConfig{Schedule: &daily}
+	app.runReconciliations(ctx)
+	assert.False(t, mockDB.recs["Other"].FinishedAt.IsZero(), "never reconciled projects are due")
+	assert.False(t, mockDB.recs["Other"].Repaired)
+	assert.Equal(t, rec.FinishedAt, mockDB.recs["TestProject"].FinishedAt, "not due again until midnight")
+}
+
+func TestRunReconciliationsStopsWhenLeaseLost(t *testing.T) {
+	app, mockDB, _ := setupReconcileTest(t)
+	ctx := context.Background()
+	assert.NoError(t, mockDB.RequestReconciliation(ctx, "TestProject", true))
+	app.Elector.until.Store(time.Now().UnixNano())
+
+	app.runReconciliations(ctx)
+
+	assert.True(t, mockDB.recs["TestProject"].FinishedAt.IsZero(), "the new leader runs the request")
+	assert.Empty(t, mockDB.jobs)
+}
+
+func TestKeepMapping(t *testing.T) {
+	now := time.Now()
+	a := db.TaskMapping{AsanaTaskID: "a", UpdatedAt: now.Add(-time.Hour)}
+	b := db.TaskMapping{AsanaTaskID: "b", UpdatedAt: now}
+	c := db.TaskMapping{AsanaTaskID: "c", UpdatedAt: now.Add(-2 * time.Hour)}
+
+	keep, extras := keepMapping([]db.TaskMapping{a, b, c}, map[string]bool{"a": true, "c": true})
+	assert.Equal(t, a, keep, "mappings to existing tasks win")
+	assert.Equal(t, []db.TaskMapping{b, c}, extras)
+
+	keep, extras = keepMapping([]db.TaskMapping{a, b, c}, nil)
+	assert.Equal(t, b, keep, "then the latest")
+	assert.Equal(t, []db.TaskMapping{a, c}, extras)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+// Resolver caches Asana name lookups in memory in front of the Mongo cache
+// collection, so the workers do not resolve the same project or custom field
+// for every work item and a restart does not start cold. Entries expire
+// after the TTL and are dropped when Asana reports the object as not found.
+type Resolver struct {
+	db  db.DBInterface
+	ttl time.Duration
+
+	mu    sync.RWMutex
+	items map[string]db.CacheItem
+	loads singleflight.Group
+}
+
+// NewResolver returns a Resolver persisting its entries through store.
+func NewResolver(store db.DBInterface, ttl time.Duration) *Resolver {
+	return &Resolver{db: store, ttl: ttl, items: make(map[string]db.CacheItem)}
+}
+
+// projectGIDKey is the cache key of the GID of an Asana project by name.
+func projectGIDKey(workspace, project string) string {
+	return fmt.Sprintf("workspace:%s:project:%s:gid", workspace, project)
+}
+
+// workspacesKey is the cache key of the names of the Asana workspaces by GID.
+const workspacesKey = "workspaces"
+
+// linkFieldKey is the cache key of the "link" custom field of a project.
+func linkFieldKey(projectGID string) string {
+	return fmt.Sprintf("project:%s:link_field", projectGID)
+}
+
+// Lookup returns the value cached under key, calling load on a miss and
+// caching its result. Concurrent misses for the same key share one load.
+func (r *Resolver) Lookup(ctx context.Context, key string, load func(ctx context.Context) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	r.mu.RLock()
+	item, ok := r.items[key]
+	r.mu.RUnlock()
+	if ok && time.Since(item.UpdatedAt) < r.ttl {
+		return item.Value, nil
+	}
+
+	v, err, _ := r.loads.Do(key, func() (interface{}, error) {
+		if item, err := r.db.GetCacheItem(ctx, key); err == nil && time.Since(item.UpdatedAt) < r.ttl {
+			r.remember(item)
+			return item.Value, nil
+		}
+
+		value, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		item := db.CacheItem{Key: key, Value: value, UpdatedAt: time.Now()}
+		if err := r.db.UpsertCacheItem(ctx, item); err != nil {
+			log.WithError(err).WithField("key", key).Warn("failed to store cache item")
+		}
+		r.remember(item)
+		return value, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]interface{}), nil
+}
+
+func (r *Resolver) remember(item db.CacheItem) {
+	r.mu.Lock()
+	r.items[item.Key] = item
+	r.mu.Unlock()
+}
+
+// Forget drops the entries for the Asana object with the given GID: the
+// entries resolving to it and the ones keyed by it.
+func (r *Resolver) Forget(ctx context.Context, gid string) {
+	if gid == "" {
+		return
+	}
+	var keys []string
+	r.mu.Lock()
+	for key, item := range r.items {
+		if item.Value["gid"] == gid || strings.Contains(key, ":"+gid+":") {
+			keys = append(keys, key)
+			delete(r.items, key)
+		}
+	}
+	r.mu.Unlock()
+
+	// The link field is keyed by the project GID even when it is only
+	// cached in Mongo.
+	if k := linkFieldKey(gid); !slices.Contains(keys, k) {
+		keys = append(keys, k)
+	}
+	for _, key := range keys {
+		if err := r.db.DeleteCacheItem(ctx, key); err != nil {
+			log.WithError(err).WithField("key", key).Warn("failed to delete cache item")
+		}
+	}
+}
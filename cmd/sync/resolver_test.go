@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/asana"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func constLoad(calls *atomic.Int32, value map[string]interface{}) func(context.Context) (map[string]interface{}, error) {
+	return func(context.Context) (map[string]interface{}, error) {
+		calls.Add(1)
+		return value, nil
+	}
+}
+
+func TestResolverLookupCachesInMemory(t *testing.T) {
+	mockDB := newEnhancedMockDB()
+	r := NewResolver(mockDB, time.Hour)
+	var calls atomic.Int32
+
+	for i := 0; i < 3; i++ {
+		v, err := r.Lookup(context.Background(), "k", constLoad(&calls, map[string]interface{}{"gid": "1"}))
+		assert.NoError(t, err)
+		assert.Equal(t, "1", v["gid"])
+	}
+
+	assert.EqualValues(t, 1, calls.Load())
+	assert.Len(t, mockDB.upsertCacheCalls, 1, "stored in Mongo once")
+}
+
+func TestResolverLookupUsesMongo(t *testing.T) {
+	mockDB := newEnhancedMockDB()
+	mockDB.cache["k"] = db.CacheItem{Key: "k", Value: map[string]interface{}{"gid": "mongo"}, UpdatedAt: time.Now()}
+	r := NewResolver(mockDB, time.Hour)
+	var calls atomic.Int32
+
+	v, err := r.Lookup(context.Background(), "k", constLoad(&calls, map[string]interface{}{"gid": "asana"}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "mongo", v["gid"])
+	assert.Zero(t, calls.Load())
+
+	mockDB.errors["GetCacheItem"] = fmt.Errorf("should not be called")
+	v, err = r.Lookup(context.Background(), "k", constLoad(&calls, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "mongo", v["gid"], "served from memory")
+}
+
+func TestResolverLookupExpired(t *testing.T) {
+	mockDB := newEnhancedMockDB()
+	mockDB.cache["k"] = db.CacheItem{Key: "k", Value: map[string]interface{}{"gid": "old"}, UpdatedAt: time.Now().Add(-2 * time.Hour)}
+	r := NewResolver(mockDB, time.Hour)
+	var calls atomic.Int32
+
+	v, err := r.Lookup(context.Background(), "k", constLoad(&calls, map[string]interface{}{"gid": "new"}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "new", v["gid"])
+	assert.EqualValues(t, 1, calls.Load())
+}
+
+func TestResolverLookupErrorNotCached(t *testing.T) {
+	r := NewResolver(newEnhancedMockDB(), time.Hour)
+	var calls atomic.Int32
+	fail := func(context.Context) (map[string]interface{}, error) {
+		calls.Add(1)
+		return nil, fmt.Errorf("asana down")
+	}
+
+	_, err := r.Lookup(context.Background(), "k", fail)
+	assert.Error(t, err)
+	_, err = r.Lookup(context.Background(), "k", fail)
+	assert.Error(t, err)
+	assert.EqualValues(t, 2, calls.Load())
+}
+
+func TestResolverLookupConcurrentMissesShareLoad(t *testing.T) {
+	r := NewResolver(&lockedCacheDB{enhancedMockDB: newEnhancedMockDB()}, time.Hour)
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func(context.Context) (map[string]interface{}, error) {
+		calls.Add(1)
+		<-release
+		return map[string]interface{}{"gid": "1"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := r.Lookup(context.Background(), "k", load)
+			assert.NoError(t, err)
+			assert.Equal(t, "1", v["gid"])
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.LessOrEqual(t, calls.Load(), int32(2), "misses in flight together share a load")
+}
+
+func TestResolverForget(t *testing.T) {
+	mockDB := newEnhancedMockDB()
+	r := NewResolver(mockDB, time.Hour)
+	var calls atomic.Int32
+	ctx := context.Background()
+
+	_, _ = r.Lookup(ctx, projectGIDKey("ws", "Proj"), constLoad(&calls, map[string]interface{}{"gid": "42"}))
+	_, _ = r.Lookup(ctx, linkFieldKey("42"), constLoad(&calls, map[string]interface{}{"gid": "cf", "name": "link"}))
+	_, _ = r.Lookup(ctx, projectGIDKey("ws", "Other"), constLoad(&calls, map[string]interface{}{"gid": "7"}))
+
+	r.Forget(ctx, "42")
+
+	assert.NotContains(t, mockDB.cache, projectGIDKey("ws", "Proj"))
+	assert.NotContains(t, mockDB.cache, linkFieldKey("42"))
+	assert.Contains(t, mockDB.cache, projectGIDKey("ws", "Other"))
+
+	_, _ = r.Lookup(ctx, projectGIDKey("ws", "Proj"), constLoad(&calls, map[string]interface{}{"gid": "43"}))
+	assert.EqualValues(t, 4, calls.Load(), "resolved again after Forget")
+}
+
+func TestAsanaProjectForADOResolvesOnce(t *testing.T) {
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+	mockDB.projects = []db.Project{{ADOProjectName: "A", AsanaWorkspaceName: "ws", AsanaProjectName: "Proj"}}
+	mockAsana.projects["ws"] = map[string]string{"Proj": "42"}
+
+	gid, _, err := app.asanaProjectForADO(context.Background(), "A")
+	assert.NoError(t, err)
+	assert.Equal(t, "42", gid)
+
+	mockAsana.errors["ProjectGIDByName"] = fmt.Errorf("should not be called")
+	gid, _, err = app.asanaProjectForADO(context.Background(), "A")
+	assert.NoError(t, err)
+	assert.Equal(t, "42", gid)
+}
+
+func TestCreateAndMapTaskForgetsMissingProject(t *testing.T) {
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+	mockDB.projects = []db.Project{{ADOProjectName: "A", AsanaWorkspaceName: "ws", AsanaProjectName: "Proj"}}
+	mockAsana.projects["ws"] = map[string]string{"Proj": "42"}
+	ctx := context.Background()
+
+	gid, _, _ := app.asanaProjectForADO(ctx, "A")
+	mockAsana.errors["CreateTaskWithCustomFields"] = &asana.APIError{StatusCode: 404}
+	wi := createTestWorkItem(1, "Task", "A", "http://ado.com/1", time.Now())
+
+	err := app.createAndMapTask(ctx, gid, "ws", wi, "name", "desc")
+	assert.ErrorIs(t, err, asana.ErrNotFound)
+
+	mockAsana.projects["ws"]["Proj"] = "43"
+	gid, _, err = app.asanaProjectForADO(ctx, "A")
+	assert.NoError(t, err)
+	assert.Equal(t, "43", gid, "project resolved again after the 404")
+}
+
+// lockedCacheDB serialises the cache methods of enhancedMockDB for
+// concurrent tests.
+type lockedCacheDB struct {
+	*enhancedMockDB
+	mu sync.Mutex
+}
+
+func (m *lockedCacheDB) GetCacheItem(ctx context.Context, key string) (db.CacheItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.enhancedMockDB.GetCacheItem(ctx, key)
+}
+
+func (m *lockedCacheDB) UpsertCacheItem(ctx context.Context, item db.CacheItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.enhancedMockDB.UpsertCacheItem(ctx, item)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/schedule"
+	log "github.com/sirupsen/logrus"
+)
+
+// projectSchedule returns the schedule of a project mapping. An invalid
+// schedule, which the web UI refuses to save, falls back to syncing the
+// project on every run.
+func projectSchedule(p db.Project) schedule.Schedule {
+	s, err := schedule.New(p.SyncSchedule, p.QuietHours)
+	if err != nil {
+		log.WithError(err).WithField("project", p.ADOProjectName).Warn("invalid sync schedule, syncing on every run")
+		return schedule.Schedule{}
+	}
+	return s
+}
+
+// nextDue tracks the earliest time one of the projects is due.
+type nextDue time.Time
+
+// add records a project due at t; the zero time means never.
+func (n *nextDue) add(t time.Time) {
+	if !t.IsZero() && (time.Time(*n).IsZero() || t.Before(time.Time(*n))) {
+		*n = nextDue(t)
+	}
+}
+
+// wait returns how long to sleep before the next cycle: interval, or less
+// when a project is due sooner.
+func (n nextDue) wait(interval time.Duration, now time.Time) time.Duration {
+	if time.Time(n).IsZero() {
+		return interval
+	}
+	return max(min(interval, time.Time(n).Sub(now)), time.Second)
+}
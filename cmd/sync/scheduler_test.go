@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/schedule"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+)
+
+func TestControllerSkipsProjectsNotDue(t *testing.T) {
+	md := newMockDB("A", "B")
+	md.projects[1].SyncSchedule = "@daily"
+	lastRun := time.Now().Add(-time.Minute)
+	md.lastRuns["A"], md.lastRuns["B"] = lastRun, lastRun
+	ma := newMockAzure()
+	ma.ids["A"] = []int{1}
+	ma.ids["B"] = []int{2}
+	app := &App{Azure: ma, DB: md, Elector: newLeader(md), Tracer: otel.Tracer("test")}
+
+	next, err := app.controller(context.Background(), time.Minute)
+
+	assert.NoError(t, err)
+	assert.Len(t, md.queued, 1)
+	assert.Contains(t, md.queued, 1, "A is due a minute after its last run")
+	assert.NotEqual(t, lastRun, md.lastRuns["A"])
+	assert.Equal(t, lastRun, md.lastRuns["B"], "B only runs at midnight")
+	daily, _ := schedule.Parse("@daily")
+	assert.Equal(t, daily.Next(lastRun), md.nextRuns["B"], "the next run of B is recorded for the web UI")
+	assert.WithinDuration(t, time.Now().Add(time.Minute), next, time.Second)
+}
+
+func TestControllerRunsProjectWithInvalidSchedule(t *testing.T) {
+	md := newMockDB("A")
+	md.projects[0].SyncSchedule = "not a schedule"
+	ma := newMockAzure()
+	ma.ids["A"] = []int{1}
+	app := &App{Azure: ma, DB: md, Elector: newLeader(md), Tracer: otel.Tracer("test")}
+
+	_, err := app.controller(context.Background(), time.Minute)
+
+	assert.NoError(t, err)
+	assert.Len(t, md.queued, 1, "an invalid schedule falls back to the interval")
+}
+
+func TestNextDueWait(t *testing.T) {
+	now := time.Now()
+	var n nextDue
+	assert.Equal(t, time.Hour, n.wait(time.Hour, now), "nothing scheduled")
+
+	n.add(time.Time{})
+	n.add(now.Add(10 * time.Minute))
+	n.add(now.Add(20 * time.Minute))
+	assert.Equal(t, 10*time.Minute, n.wait(time.Hour, now))
+	assert.Equal(t, 5*time.Minute, n.wait(5*time.Minute, now))
+	assert.Equal(t, time.Second, n.wait(time.Hour, now.Add(time.Hour)), "overdue projects run soon")
+}
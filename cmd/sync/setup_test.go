@@ -127,6 +127,7 @@ func TestSetupSuccess(t *testing.T) {
 	assert.NotNil(t, app.Tracer)
 	assert.NotNil(t, app.UptraceShutdown)
 	assert.Equal(t, 1*time.Hour, app.CacheTTL)
+	assert.Equal(t, "sync-user", app.AsanaUserGID)
 }
 
 func TestSetupDBConnectFail(t *testing.T) {
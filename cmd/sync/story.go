@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/azure"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+)
+
+// postChangeStory compares the work item with the revision last synced to
+// Asana and, when any tracked field changed, posts a story on the task
+// summarising the before and after values.
+func (app *App) postChangeStory(ctx context.Context, wi azure.WorkItem, mapping db.TaskMapping) error {
+	if mapping.ADORevision == 0 || wi.Rev <= mapping.ADORevision {
+		return nil
+	}
+
+	before, err := app.Azure.GetWorkItemRevision(ctx, wi.ID, mapping.ADORevision)
+	if err != nil {
+		return err
+	}
+
+	changes := azure.ChangedFields(before, wi)
+	if len(changes) == 0 {
+		return nil
+	}
+	return app.Asana.AddStoryToTask(ctx, mapping.AsanaTaskID, formatChangeStory(wi, mapping.ADORevision, changes))
+}
+
+// formatChangeStory renders the tracked field changes as Asana rich text.
+func formatChangeStory(wi azure.WorkItem, fromRev int, changes []azure.FieldChange) string {
+	value := func(v string) string {
+		if v == "" {
+			return "<em>none</em>"
+		}
+		return html.EscapeString(v)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<strong>Azure DevOps %s %d updated</strong> (revision %d → %d)<ul>",
+		html.EscapeString(wi.WorkItemType), wi.ID, fromRev, wi.Rev)
+	for _, c := range changes {
+		fmt.Fprintf(&b, "<li><strong>%s</strong>: %s → %s</li>", c.Field, value(c.Old), value(c.New))
+	}
+	b.WriteString("</ul>")
+	return b.String()
+}
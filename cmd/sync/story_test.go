@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/azure"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatChangeStory(t *testing.T) {
+	wi := azure.WorkItem{ID: 7, Rev: 5, WorkItemType: "Bug"}
+	changes := []azure.FieldChange{
+		{Field: "State", Old: "New", New: "Active"},
+		{Field: "Assigned To", Old: "", New: "Bob <b>"},
+	}
+
+	got := formatChangeStory(wi, 3, changes)
+
+	assert.Contains(t, got, "Azure DevOps Bug 7 updated</strong> (revision 3 → 5)")
+	assert.Contains(t, got, "<li><strong>State</strong>: New → Active</li>")
+	assert.Contains(t, got, "<li><strong>Assigned To</strong>: <em>none</em> → Bob &lt;b&gt;</li>")
+}
+
+func TestUpdateExistingTaskPostsChangeStory(t *testing.T) {
+	app := setupTestApp()
+	ctx := context.Background()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+
+	mockAzure.revisions[123] = map[int]azure.WorkItem{
+		2: {ID: 123, Rev: 2, State: "New", AssignedTo: "Alice"},
+	}
+	wi := createTestWorkItem(123, "Task", "TestProject", "http://ado.com/123", time.Now())
+	wi.Rev = 4
+	wi.State = "Active"
+	wi.AssignedTo = "Alice"
+	mapping := db.TaskMapping{ADOTaskID: 123, ADORevision: 2, AsanaProjectID: "proj-1", AsanaTaskID: "task-1"}
+
+	err := app.updateExistingTask(ctx, wi, mapping, "name", "desc", false)
+
+	assert.NoError(t, err)
+	assert.Len(t, mockAsana.stories["task-1"], 1)
+	assert.Contains(t, mockAsana.stories["task-1"][0], "<strong>State</strong>: New → Active")
+	assert.NotContains(t, mockAsana.stories["task-1"][0], "Assigned To")
+	assert.Equal(t, 4, mockDB.updateTaskCalls[0].ADORevision)
+}
+
+func TestUpdateExistingTaskNoStoryWhenUntrackedFieldsChange(t *testing.T) {
+	app := setupTestApp()
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+
+	mockAzure.revisions[123] = map[int]azure.WorkItem{2: {ID: 123, Rev: 2, Title: "Old"}}
+	wi := createTestWorkItem(123, "New title", "TestProject", "http://ado.com/123", time.Now())
+	wi.Rev = 3
+	mapping := db.TaskMapping{ADOTaskID: 123, ADORevision: 2, AsanaTaskID: "task-1"}
+
+	err := app.updateExistingTask(context.Background(), wi, mapping, "name", "desc", false)
+
+	assert.NoError(t, err)
+	assert.Empty(t, mockAsana.stories)
+}
+
+func TestUpdateExistingTaskNoStoryWithoutPreviousRevision(t *testing.T) {
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+	mockAzure.errors["GetWorkItemRevision"] = fmt.Errorf("should not be called")
+
+	wi := createTestWorkItem(123, "Task", "TestProject", "http://ado.com/123", time.Now())
+	wi.Rev = 3
+	mapping := db.TaskMapping{ADOTaskID: 123, AsanaTaskID: "task-1"}
+
+	err := app.updateExistingTask(context.Background(), wi, mapping, "name", "desc", false)
+
+	assert.NoError(t, err)
+	assert.Empty(t, mockAsana.stories)
+	assert.Equal(t, 3, mockDB.updateTaskCalls[0].ADORevision, "revision recorded for next time")
+}
+
+func TestUpdateExistingTaskStoryErrorKeepsSync(t *testing.T) {
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+	mockAsana.errors["AddStoryToTask"] = fmt.Errorf("asana down")
+
+	mockAzure.revisions[123] = map[int]azure.WorkItem{2: {ID: 123, Rev: 2, State: "New"}}
+	wi := createTestWorkItem(123, "Task", "TestProject", "http://ado.com/123", time.Now())
+	wi.Rev = 3
+	wi.State = "Closed"
+	mapping := db.TaskMapping{ADOTaskID: 123, ADORevision: 2, AsanaTaskID: "task-1"}
+
+	err := app.updateExistingTask(context.Background(), wi, mapping, "name", "desc", false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, mockDB.updateTaskCalls[0].ADORevision)
+}
+
+func TestUpdateExistingTaskNoStoryWhenSaveFails(t *testing.T) {
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+	mockDB.errors["UpdateTask"] = fmt.Errorf("mongo down")
+
+	mockAzure.revisions[123] = map[int]azure.WorkItem{2: {ID: 123, Rev: 2, State: "New"}}
+	wi := createTestWorkItem(123, "Task", "TestProject", "http://ado.com/123", time.Now())
+	wi.Rev = 3
+	wi.State = "Closed"
+	mapping := db.TaskMapping{ADOTaskID: 123, ADORevision: 2, AsanaTaskID: "task-1"}
+
+	err := app.updateExistingTask(context.Background(), wi, mapping, "name", "desc", false)
+
+	assert.Error(t, err)
+	assert.Empty(t, mockAsana.stories, "the retry posts the story once the revision is saved")
+}
+
+func TestCreateAndMapTaskRecordsRevision(t *testing.T) {
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+
+	wi := createTestWorkItem(123, "New Task", "TestProject", "http://ado.com/123", time.Now())
+	wi.Rev = 6
+
+	err := app.createAndMapTask(context.Background(), "proj-1", "workspace1", wi, "Task Name", "Task Desc")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 6, mockDB.addTaskCalls[0].ADORevision)
+}
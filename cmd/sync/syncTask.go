@@ -1,11 +1,23 @@
 package main
 
-import "time"
+import (
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/azure"
+)
 
 type SyncTask struct {
 	ADOTaskID        int
 	ADOLastUpdated   time.Time
 	AsanaTaskID      string
 	AsanaLastUpdated time.Time
-	Result           chan error
+	// WorkItem is the work item hydrated with the rest of its batch. When
+	// nil the worker fetches it itself.
+	WorkItem *azure.WorkItem
+	// Force syncs the work item even if its revision was synced already and
+	// sends every Asana field, not only those that changed.
+	Force bool
+	// CheckAsana looks for edits made in the Asana task even if the work
+	// item did not change, after the task was seen changing in Asana.
+	CheckAsana bool
 }
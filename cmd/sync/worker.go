@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,76 +10,194 @@ import (
 	"github.com/ADO-Asana-Sync/sync-engine/internal/azure"
 	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
-func (app *App) worker(ctx context.Context, id int, syncTasks <-chan SyncTask) {
+// worker leases jobs from the queue and syncs their work items until ctx is
+// cancelled, polling the queue while it is empty. Jobs are synced with
+// jobCtx, so a job in flight when ctx is cancelled runs to completion unless
+// jobCtx is cancelled too.
+func (app *App) worker(ctx, jobCtx context.Context, id int) {
+	owner := fmt.Sprintf("%s/%d", app.InstanceID, id)
 	wlog := log.WithField("worker", id)
 	wlog.Infof("worker started")
 
-	for task := range syncTasks {
-		err := app.handleTask(ctx, wlog, task)
+	for ctx.Err() == nil {
+		if app.processJobs(ctx, jobCtx, wlog, owner) > 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(app.Queue.PollInterval):
+		}
+	}
+	wlog.Info("worker stopped")
+}
+
+// processJobs leases a batch of jobs, hydrates their work items in a single
+// request and syncs them one by one with jobCtx. Once ctx is cancelled the
+// jobs not started yet are put back in the queue. It returns the number of
+// jobs leased.
+func (app *App) processJobs(ctx, jobCtx context.Context, wlog *log.Entry, owner string) int {
+	jobs, err := app.DB.LeaseJobs(jobCtx, owner, app.Queue.Shard, app.Queue.BatchSize, app.Queue.LeaseTimeout)
+	if err != nil {
+		wlog.WithError(err).Error("error leasing jobs")
+	}
+	if len(jobs) == 0 {
+		return 0
+	}
+
+	ids := make([]int, len(jobs))
+	for i, job := range jobs {
+		ids[i] = job.ADOTaskID
+	}
+	hydrated := app.hydrate(jobCtx, ids)
+
+	for i, job := range jobs {
+		if ctx.Err() != nil {
+			rctx, cancel := context.WithTimeout(context.Background(), db.Timeout)
+			app.returnJobs(rctx, wlog, jobs[i:])
+			cancel()
+			break
+		}
+		task := SyncTask{ADOTaskID: job.ADOTaskID, Force: job.Force, CheckAsana: job.CheckAsana}
+		if wi, ok := hydrated[job.ADOTaskID]; ok {
+			task.WorkItem = &wi
+		}
+		_, err := app.handleTask(jobCtx, wlog, task)
 		if err != nil {
 			wlog.WithError(err).Error("task sync failed")
 		}
-		if task.Result != nil {
-			task.Result <- err
+		// jobCtx is cancelled once the grace period is over, which must not
+		// stop the job from being released.
+		rctx, cancel := context.WithTimeout(context.Background(), db.Timeout)
+		app.finishJob(rctx, wlog, job, err)
+		cancel()
+	}
+	return len(jobs)
+}
+
+// returnJobs puts leased jobs back in the queue so another worker or replica
+// picks them up without waiting for their lease to expire.
+func (app *App) returnJobs(ctx context.Context, wlog *log.Entry, jobs []db.Job) {
+	for _, job := range jobs {
+		if err := app.DB.ReturnJob(ctx, job); err != nil {
+			wlog.WithError(err).WithField("ado_task_id", job.ADOTaskID).Error("error returning job")
 		}
 	}
+	wlog.WithField("jobs", len(jobs)).Info("returned unstarted jobs to the queue")
 }
 
-func (app *App) handleTask(ctx context.Context, wlog *log.Entry, task SyncTask) error {
+// handleTask syncs a single work item. The work item is returned as fetched
+// from ADO. Work items deleted or hidden in ADO are logged and skipped rather
+// than returned as errors, as there is nothing left to sync.
+func (app *App) handleTask(ctx context.Context, wlog *log.Entry, task SyncTask) (azure.WorkItem, error) {
 	tctx, span := app.Tracer.Start(ctx, "sync.worker.taskItem")
 	defer span.End()
 
 	wlog.Infof("syncing ADO work item %v", task.ADOTaskID)
 
-	mapping, wi, name, desc, err := app.prepWorkItem(tctx, task.ADOTaskID)
+	wi, err := app.syncWorkItem(tctx, wlog, task)
+	if err != nil && errors.Is(err, azure.ErrNotFound) {
+		span.AddEvent("skipped", trace.WithAttributes(attribute.String("error", err.Error())))
+		wlog.WithError(err).Warn("work item cannot be synced, skipping until it changes")
+		return wi, nil
+	}
+	return wi, err
+}
+
+// permanentError reports whether retrying the work item would fail the same
+// way until it or the configuration changes: Asana rejected the data sent,
+// or the workspace plan lacks a feature. Rate limits, outages and access
+// errors are worth retrying.
+func permanentError(err error) bool {
+	return errors.Is(err, asana.ErrValidation) ||
+		errors.Is(err, asana.ErrPremiumRequired)
+}
+
+// syncWorkItem creates, updates or skips the Asana counterpart of the work
+// item.
+func (app *App) syncWorkItem(ctx context.Context, wlog *log.Entry, task SyncTask) (azure.WorkItem, error) {
+	span := trace.SpanFromContext(ctx)
+
+	mapping, wi, name, desc, err := app.prepWorkItem(ctx, task)
 	if err != nil {
 		span.RecordError(err, trace.WithStackTrace(true))
 		span.SetStatus(codes.Error, err.Error())
 		wlog.WithError(err).Error("failure preparing work item")
-		return err
+		return wi, err
+	}
+
+	if mapping != nil && !task.Force && !task.CheckAsana && wi.Rev > 0 && mapping.ADORevision >= wi.Rev {
+		wlog.WithField("rev", wi.Rev).Debug("revision already synced, skipping")
+		return wi, nil
+	}
+	if mapping != nil && task.Force {
+		// Without a fingerprint every field is sent again.
+		mapping.AsanaFingerprint = nil
+	}
+
+	if app.isProjectWorkItem(wi) {
+		return wi, app.syncWorkItemProject(ctx, wi, name, desc)
 	}
 
 	if mapping != nil {
-		return app.updateExistingTask(tctx, wi, *mapping, name, desc)
+		return wi, app.updateExistingTask(ctx, wi, *mapping, name, desc, task.CheckAsana)
 	}
 
-	asanaProj, workspace, err := app.asanaProjectForADO(tctx, wi.TeamProject)
+	asanaProj, workspace, err := app.asanaProjectForWorkItem(ctx, wi)
 	if err != nil {
 		span.RecordError(err, trace.WithStackTrace(true))
 		span.SetStatus(codes.Error, err.Error())
 		wlog.WithError(err).WithField("project", wi.TeamProject).Error("error getting Asana project for ADO project")
-		return err
+		return wi, err
 	}
 	if asanaProj == "" {
 		wlog.WithField("project", wi.TeamProject).Debug("project not mapped to Asana, skipping")
-		return nil
+		return wi, nil
 	}
 
-	updated, err := app.tryUpdateExistingAsanaTask(tctx, asanaProj, workspace, wi, name, desc)
+	recovered, err := app.recoverCreatedTask(ctx, workspace, wi, name, desc)
 	if err != nil {
 		span.RecordError(err, trace.WithStackTrace(true))
 		span.SetStatus(codes.Error, err.Error())
-		wlog.WithError(err).WithField("project", wi.TeamProject).Error("error updating existing Asana task")
-		return err
+		wlog.WithError(err).Error("error recovering interrupted Asana task creation")
+		return wi, err
+	}
+	if recovered {
+		return wi, nil
+	}
+
+	adopted, err := app.adoptExistingTask(ctx, asanaProj, workspace, wi, name, desc)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		wlog.WithError(err).WithField("project", wi.TeamProject).Error("error adopting existing Asana task")
+		return wi, err
 	}
-	if updated {
-		return nil
+	if adopted {
+		return wi, nil
 	}
 
-	return app.createAndMapTask(tctx, asanaProj, workspace, wi, name, desc)
+	return wi, app.createAndMapTask(ctx, asanaProj, workspace, wi, name, desc)
 }
 
-func (app *App) prepWorkItem(ctx context.Context, id int) (*db.TaskMapping, azure.WorkItem, string, string, error) {
-	mapping, err := app.DB.TaskByADOTaskID(ctx, id)
+// prepWorkItem loads the task mapping and the work item, using the work item
+// hydrated by the controller when present.
+func (app *App) prepWorkItem(ctx context.Context, task SyncTask) (*db.TaskMapping, azure.WorkItem, string, string, error) {
+	mapping, err := app.DB.TaskByADOTaskID(ctx, task.ADOTaskID)
 	found := err == nil
 
-	wi, err := app.Azure.GetWorkItem(ctx, id)
-	if err != nil {
-		return nil, azure.WorkItem{}, "", "", err
+	var wi azure.WorkItem
+	if task.WorkItem != nil {
+		wi = *task.WorkItem
+	} else {
+		wi, err = app.Azure.GetWorkItem(ctx, task.ADOTaskID)
+		if err != nil {
+			return nil, azure.WorkItem{}, "", "", err
+		}
 	}
 
 	name, err := wi.FormatTitle()
@@ -97,32 +216,85 @@ func (app *App) prepWorkItem(ctx context.Context, id int) (*db.TaskMapping, azur
 	return nil, wi, name, desc, nil
 }
 
-func (app *App) updateExistingTask(ctx context.Context, wi azure.WorkItem, mapping db.TaskMapping, name, desc string) error {
+// updateExistingTask sends the Asana fields whose rendered value changed
+// since the task was last synced, and records the synced revision. With
+// checkAsana set, fields edited in Asana are handled even when they did not
+// change in ADO.
+func (app *App) updateExistingTask(ctx context.Context, wi azure.WorkItem, mapping db.TaskMapping, name, desc string, checkAsana bool) error {
 	cf, ok := app.getLinkCustomField(ctx, mapping.AsanaProjectID)
 	customFields := map[string]string{}
 	if ok {
 		customFields[cf.GID] = wi.URL
 	}
 
-	if len(customFields) > 0 {
-		if err := app.Asana.UpdateTaskWithCustomFields(ctx, mapping.AsanaTaskID, name, desc, customFields); err != nil {
-			return err
-		}
-	} else {
-		if err := app.Asana.UpdateTask(ctx, mapping.AsanaTaskID, name, desc); err != nil {
-			return err
-		}
+	sendName, sendDesc, sendFields := changedFields(mapping.AsanaFingerprint, name, desc, customFields)
+	rendered := map[string]string{fingerprintName: name, fingerprintNotes: desc}
+	send := map[string]string{fingerprintName: sendName, fingerprintNotes: sendDesc}
+	if err := app.applyFieldPolicy(ctx, wi, &mapping, rendered, send, checkAsana); err != nil {
+		return err
+	}
+	sendName, sendDesc = send[fingerprintName], send[fingerprintNotes]
+	sent := sendName != "" || sendDesc != "" || len(sendFields) > 0
+	var err error
+	switch {
+	case len(sendFields) > 0:
+		err = app.Asana.UpdateTaskWithCustomFields(ctx, mapping.AsanaTaskID, sendName, sendDesc, sendFields)
+	case sent:
+		err = app.Asana.UpdateTask(ctx, mapping.AsanaTaskID, sendName, sendDesc)
+	default:
+		log.WithField("ado_task_id", wi.ID).Debug("Asana fields unchanged, skipping update")
 	}
+	if err != nil {
+		app.forgetOnNotFound(ctx, err, mapping.AsanaProjectID)
+		return err
+	}
+	synced := mapping
+	// The work item carries the current name of its project, which the
+	// mapping may predate.
+	mapping.ADOProjectID = wi.TeamProject
 	mapping.ADOLastUpdated = wi.ChangedDate
-	mapping.AsanaLastUpdated = time.Now()
+	mapping.ADORevision = wi.Rev
+	fp := taskFingerprint(name, desc, customFields)
+	keepOwnedFingerprint(fp, mapping.AsanaFingerprint, mapping.AsanaOwnedFields)
+	mapping.AsanaFingerprint = fp
+	var stored []string
+	if sendName != "" {
+		stored = append(stored, fingerprintName)
+	}
+	if sendDesc != "" {
+		stored = append(stored, fingerprintNotes)
+	}
+	switch {
+	case len(stored) > 0:
+		app.readBack(ctx, &mapping, stored...)
+	case sent:
+		mapping.AsanaLastUpdated = time.Now()
+	}
 	if err := app.DB.UpdateTask(ctx, mapping); err != nil {
 		return err
 	}
-	workspace := app.workspaceForADO(ctx, mapping.ADOProjectID)
-	app.addSyncedTag(ctx, workspace, mapping.AsanaTaskID)
+	// The story is posted once the new revision is saved, so a retry after
+	// a failed save does not post it twice.
+	if err := app.postChangeStory(ctx, wi, synced); err != nil {
+		log.WithError(err).WithField("ado_task_id", wi.ID).Warn("failed to post change story")
+	}
+	if sent {
+		workspace := app.workspaceForADO(ctx, mapping.ADOProjectID)
+		app.addSyncedTag(ctx, workspace, mapping.AsanaTaskID)
+	}
 	return nil
 }
 
+// asanaProjectForWorkItem returns the Asana project a new task for the work
+// item belongs in: the project mirroring its parent when there is one,
+// otherwise the project mapped to its ADO project.
+func (app *App) asanaProjectForWorkItem(ctx context.Context, wi azure.WorkItem) (string, string, error) {
+	if gid, workspace, ok := app.parentAsanaProject(ctx, wi); ok {
+		return gid, workspace, nil
+	}
+	return app.asanaProjectForADO(ctx, wi.TeamProject)
+}
+
 func (app *App) asanaProjectForADO(ctx context.Context, adoProj string) (string, string, error) {
 	projects, err := app.DB.Projects(ctx)
 	if err != nil {
@@ -130,33 +302,26 @@ func (app *App) asanaProjectForADO(ctx context.Context, adoProj string) (string,
 	}
 	for _, p := range projects {
 		if p.ADOProjectName == adoProj {
-			gid, err := app.Asana.ProjectGIDByName(ctx, p.AsanaWorkspaceName, p.AsanaProjectName)
-			return gid, p.AsanaWorkspaceName, err
+			if p.AsanaProjectGID != "" {
+				return p.AsanaProjectGID, p.AsanaWorkspaceName, nil
+			}
+			v, err := app.Resolver.Lookup(ctx, projectGIDKey(p.AsanaWorkspaceName, p.AsanaProjectName), func(ctx context.Context) (map[string]interface{}, error) {
+				gid, err := app.Asana.ProjectGIDByName(ctx, p.AsanaWorkspaceName, p.AsanaProjectName)
+				return map[string]interface{}{"gid": gid}, err
+			})
+			if err != nil {
+				return "", p.AsanaWorkspaceName, err
+			}
+			gid, _ := v["gid"].(string)
+			return gid, p.AsanaWorkspaceName, nil
 		}
 	}
 	log.WithField("project", adoProj).Debug("no project mapping found")
 	return "", "", nil
 }
 
-func (app *App) tryUpdateExistingAsanaTask(ctx context.Context, asanaProj, workspace string, wi azure.WorkItem, name, desc string) (bool, error) {
-	tasks, err := app.Asana.ListProjectTasks(ctx, asanaProj)
-	if err != nil {
-		return false, err
-	}
-	for _, t := range tasks {
-		if t.Name != name {
-			continue
-		}
-		if err := app.updateExistingByName(ctx, t.GID, asanaProj, workspace, wi, name, desc); err != nil {
-			return false, err
-		}
-		return true, nil
-	}
-	return false, nil
-}
-
-// updateExistingByName updates an Asana task and records a new mapping entry.
-func (app *App) updateExistingByName(ctx context.Context, taskID, projectID, workspace string, wi azure.WorkItem, name, desc string) error {
+// adoptTask updates an existing Asana task and records a new mapping entry.
+func (app *App) adoptTask(ctx context.Context, taskID, projectID, workspace string, wi azure.WorkItem, name, desc string) error {
 	cf, ok := app.getLinkCustomField(ctx, projectID)
 	customFields := map[string]string{}
 	if ok {
@@ -170,6 +335,7 @@ func (app *App) updateExistingByName(ctx context.Context, taskID, projectID, wor
 		err = app.Asana.UpdateTask(ctx, taskID, name, desc)
 	}
 	if err != nil {
+		app.forgetOnNotFound(ctx, err, projectID)
 		return err
 	}
 
@@ -177,10 +343,12 @@ func (app *App) updateExistingByName(ctx context.Context, taskID, projectID, wor
 		ADOProjectID:     wi.TeamProject,
 		ADOTaskID:        wi.ID,
 		ADOLastUpdated:   wi.ChangedDate,
+		ADORevision:      wi.Rev,
 		AsanaProjectID:   projectID,
 		AsanaTaskID:      taskID,
-		AsanaLastUpdated: time.Now(),
+		AsanaFingerprint: taskFingerprint(name, desc, customFields),
 	}
+	app.readBack(ctx, &m, fingerprintName, fingerprintNotes)
 	if err := app.DB.AddTask(ctx, m); err != nil {
 		return err
 	}
@@ -188,6 +356,44 @@ func (app *App) updateExistingByName(ctx context.Context, taskID, projectID, wor
 	return nil
 }
 
+// recoverCreatedTask maps the Asana task left behind by a creation that was
+// interrupted before its mapping was saved. The task is recognized by the
+// link to the work item it was created with, in its link field or notes. It
+// reports whether a task was recovered; when several tasks link the work
+// item, adoption lists them for review.
+func (app *App) recoverCreatedTask(ctx context.Context, workspace string, wi azure.WorkItem, name, desc string) (bool, error) {
+	intent, ok, err := app.DB.CreationIntent(ctx, wi.ID)
+	if err != nil || !ok {
+		return false, err
+	}
+	tasks, err := app.Asana.ListProjectTasks(ctx, intent.AsanaProjectID)
+	if err != nil {
+		app.forgetOnNotFound(ctx, err, intent.AsanaProjectID)
+		return false, err
+	}
+	var created []asana.Task
+	for _, t := range tasks {
+		if id, ok := linkedWorkItem(t); ok && id == wi.ID {
+			created = append(created, t)
+		}
+	}
+	if len(created) != 1 {
+		// The task was never created, or cannot be told apart; createAndMapTask
+		// records a new intent.
+		return false, nil
+	}
+	t := created[0]
+	log.WithField("ado_task_id", wi.ID).WithField("asana_task_id", t.GID).Info("recovering Asana task from interrupted creation")
+	if err := app.adoptTask(ctx, t.GID, intent.AsanaProjectID, workspace, wi, name, desc); err != nil {
+		return false, err
+	}
+	app.removeCreationIntent(ctx, wi.ID)
+	return true, nil
+}
+
+// createAndMapTask creates the Asana task for a work item and maps it. The
+// creation is recorded first, so a task created by a process that dies
+// before saving the mapping is recovered rather than created again.
 func (app *App) createAndMapTask(ctx context.Context, asanaProj, workspace string, wi azure.WorkItem, name, desc string) error {
 	cf, ok := app.getLinkCustomField(ctx, asanaProj)
 	customFields := map[string]string{}
@@ -195,35 +401,48 @@ func (app *App) createAndMapTask(ctx context.Context, asanaProj, workspace strin
 		customFields[cf.GID] = wi.URL
 	}
 
-	var (
-		newTask asana.Task
-		err     error
-	)
-	if len(customFields) > 0 {
-		newTask, err = app.Asana.CreateTaskWithCustomFields(ctx, asanaProj, name, desc, customFields)
-	} else {
-		newTask, err = app.Asana.CreateTask(ctx, asanaProj, name, desc)
+	intent := db.CreationIntent{
+		ADOTaskID:      wi.ID,
+		ADOProjectID:   wi.TeamProject,
+		AsanaProjectID: asanaProj,
+	}
+	if err := app.DB.AddCreationIntent(ctx, intent); err != nil {
+		return err
 	}
+
+	newTask, err := app.Asana.CreateTaskWithCustomFields(ctx, asanaProj, name, desc, customFields)
 	if err != nil {
+		app.forgetOnNotFound(ctx, err, asanaProj)
 		return err
 	}
 	m := db.TaskMapping{
 		ADOProjectID:     wi.TeamProject,
 		ADOTaskID:        wi.ID,
 		ADOLastUpdated:   wi.ChangedDate,
+		ADORevision:      wi.Rev,
 		AsanaProjectID:   asanaProj,
 		AsanaTaskID:      newTask.GID,
-		AsanaLastUpdated: time.Now(),
+		AsanaFingerprint: taskFingerprint(name, desc, customFields),
 		CreatedAt:        time.Now(),
 		UpdatedAt:        time.Now(),
 	}
+	app.readBack(ctx, &m, fingerprintName, fingerprintNotes)
 	if err := app.DB.AddTask(ctx, m); err != nil {
 		return err
 	}
+	app.removeCreationIntent(ctx, wi.ID)
 	app.addSyncedTag(ctx, workspace, newTask.GID)
 	return nil
 }
 
+// removeCreationIntent clears the intent of a creation whose mapping was
+// saved. A leftover intent is harmless as the mapping takes precedence.
+func (app *App) removeCreationIntent(ctx context.Context, adoTaskID int) {
+	if err := app.DB.RemoveCreationIntent(ctx, adoTaskID); err != nil {
+		log.WithError(err).WithField("ado_task_id", adoTaskID).Warn("failed to remove creation intent")
+	}
+}
+
 func (app *App) addSyncedTag(ctx context.Context, workspace, taskID string) {
 	tag, ok := app.resolveSyncedTag(ctx, workspace)
 	if !ok {
@@ -234,29 +453,32 @@ func (app *App) addSyncedTag(ctx context.Context, workspace, taskID string) {
 	}
 }
 
+// forgetOnNotFound drops the cached lookups for the Asana project when err
+// shows that the project, or the link field resolved for it, no longer
+// exists, so the next attempt resolves them again.
+func (app *App) forgetOnNotFound(ctx context.Context, err error, projectGID string) {
+	if errors.Is(err, asana.ErrNotFound) {
+		app.Resolver.Forget(ctx, projectGID)
+	}
+}
+
 // getLinkCustomField retrieves the "link" custom field for the specified
 // project, using a cached value when available. The boolean return indicates
 // whether the field was found.
 func (app *App) getLinkCustomField(ctx context.Context, projectID string) (asana.CustomField, bool) {
-	key := fmt.Sprintf("project:%s:link_field", projectID)
-	item, err := app.DB.GetCacheItem(ctx, key)
-	if err == nil && time.Since(item.UpdatedAt) < app.CacheTTL {
-		gid, _ := item.Value["gid"].(string)
-		name, _ := item.Value["name"].(string)
-		if gid != "" {
-			return asana.CustomField{GID: gid, Name: name}, true
-		}
-	}
-
-	cf, err := app.Asana.ProjectCustomFieldByName(ctx, projectID, "link")
+	v, err := app.Resolver.Lookup(ctx, linkFieldKey(projectID), func(ctx context.Context) (map[string]interface{}, error) {
+		cf, err := app.Asana.ProjectCustomFieldByName(ctx, projectID, "link")
+		return map[string]interface{}{"gid": cf.GID, "name": cf.Name}, err
+	})
 	if err != nil {
 		return asana.CustomField{}, false
 	}
-	_ = app.DB.UpsertCacheItem(ctx, db.CacheItem{
-		Key:   key,
-		Value: map[string]interface{}{"gid": cf.GID, "name": cf.Name},
-	})
-	return cf, true
+	gid, _ := v["gid"].(string)
+	name, _ := v["name"].(string)
+	if gid == "" {
+		return asana.CustomField{}, false
+	}
+	return asana.CustomField{GID: gid, Name: name}, true
 }
 
 func (app *App) workspaceForADO(ctx context.Context, adoProj string) string {
@@ -3,6 +3,10 @@ package main
 import (
 	"context"
 	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
 	"testing"
 	"time"
 
@@ -14,6 +18,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.opentelemetry.io/otel"
 )
 
@@ -23,13 +28,26 @@ type enhancedMockDB struct {
 	tasks         map[int]db.TaskMapping
 	cache         map[string]db.CacheItem
 	workspaceTags map[string]db.WorkspaceTag
+	wiProjects    map[int]db.WorkItemProject
 	lastSync      db.LastSync
+	checkpoints   map[string]db.SyncCheckpoint
+	jobs          map[int]db.Job
+	deadLetters   map[int]db.DeadLetter
+	leases        map[string]db.Lease
+	intents       map[int]db.CreationIntent
+	reviews       map[int]db.AdoptionReview
+	conflicts     map[string]db.Conflict     // "<ado task ID>/<field>" → conflict
+	webhooks      map[string]db.AsanaWebhook // Asana project GID → webhook
+	eventSyncs    map[string]string          // Asana project GID → sync token
+	duplicates    []db.TaskMapping           // extra mappings of work items already in tasks
+	recs          map[string]db.Reconciliation
 
 	// Test tracking
-	addTaskCalls     []db.TaskMapping
-	updateTaskCalls  []db.TaskMapping
-	upsertCacheCalls []db.CacheItem
-	errors           map[string]error // Function name → error to return
+	updateProjectCalls []db.Project
+	addTaskCalls       []db.TaskMapping
+	updateTaskCalls    []db.TaskMapping
+	upsertCacheCalls   []db.CacheItem
+	errors             map[string]error // Function name → error to return
 }
 
 func newEnhancedMockDB() *enhancedMockDB {
@@ -38,6 +56,17 @@ func newEnhancedMockDB() *enhancedMockDB {
 		tasks:            make(map[int]db.TaskMapping),
 		cache:            make(map[string]db.CacheItem),
 		workspaceTags:    make(map[string]db.WorkspaceTag),
+		wiProjects:       make(map[int]db.WorkItemProject),
+		checkpoints:      make(map[string]db.SyncCheckpoint),
+		jobs:             make(map[int]db.Job),
+		deadLetters:      make(map[int]db.DeadLetter),
+		leases:           make(map[string]db.Lease),
+		intents:          make(map[int]db.CreationIntent),
+		reviews:          make(map[int]db.AdoptionReview),
+		conflicts:        make(map[string]db.Conflict),
+		webhooks:         make(map[string]db.AsanaWebhook),
+		eventSyncs:       make(map[string]string),
+		recs:             make(map[string]db.Reconciliation),
 		addTaskCalls:     []db.TaskMapping{},
 		updateTaskCalls:  []db.TaskMapping{},
 		upsertCacheCalls: []db.CacheItem{},
@@ -65,6 +94,10 @@ func (m *enhancedMockDB) RemoveProject(ctx context.Context, id primitive.ObjectI
 }
 
 func (m *enhancedMockDB) UpdateProject(ctx context.Context, project db.Project) error {
+	if err := m.errors["UpdateProject"]; err != nil {
+		return err
+	}
+	m.updateProjectCalls = append(m.updateProjectCalls, project)
 	return nil
 }
 
@@ -76,6 +109,187 @@ func (m *enhancedMockDB) WriteLastSync(ctx context.Context, timestamp time.Time)
 	return nil
 }
 
+func (m *enhancedMockDB) SyncCheckpoint(ctx context.Context, project string) (db.SyncCheckpoint, error) {
+	if err := m.errors["SyncCheckpoint"]; err != nil {
+		return db.SyncCheckpoint{}, err
+	}
+	if cp, ok := m.checkpoints[project]; ok {
+		return cp, nil
+	}
+	return db.SyncCheckpoint{ADOProjectName: project, Time: m.lastSync.Time}, nil
+}
+
+func (m *enhancedMockDB) WriteSyncCheckpoint(ctx context.Context, project string, timestamp time.Time) error {
+	cp := m.checkpoints[project]
+	cp.ADOProjectName, cp.Time, cp.UpdatedAt = project, timestamp, time.Now()
+	m.checkpoints[project] = cp
+	return nil
+}
+
+func (m *enhancedMockDB) WriteSyncRun(ctx context.Context, project string, lastRun, nextRun time.Time) error {
+	cp := m.checkpoints[project]
+	cp.ADOProjectName, cp.LastRunAt, cp.NextRunAt = project, lastRun, nextRun
+	m.checkpoints[project] = cp
+	return nil
+}
+
+func (m *enhancedMockDB) SyncCheckpoints(ctx context.Context) ([]db.SyncCheckpoint, error) {
+	if err, ok := m.errors["SyncCheckpoints"]; ok {
+		return nil, err
+	}
+	var cps []db.SyncCheckpoint
+	for _, cp := range m.checkpoints {
+		cps = append(cps, cp)
+	}
+	return cps, nil
+}
+
+func (m *enhancedMockDB) EnqueueJob(ctx context.Context, adoTaskID int, project string, changed time.Time) error {
+	if err := m.errors["EnqueueJob"]; err != nil {
+		return err
+	}
+	if changed.IsZero() {
+		changed = m.jobs[adoTaskID].ChangedDate
+	}
+	m.jobs[adoTaskID] = db.Job{ADOTaskID: adoTaskID, ADOProjectName: project, State: db.JobQueued, ChangedDate: changed, VisibleAt: time.Now()}
+	return nil
+}
+
+func (m *enhancedMockDB) LeaseJobs(ctx context.Context, owner string, shard db.Shard, limit int, lease time.Duration) ([]db.Job, error) {
+	if err := m.errors["LeaseJobs"]; err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(m.jobs))
+	for id := range m.jobs {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	var jobs []db.Job
+	for _, id := range ids {
+		job := m.jobs[id]
+		if len(jobs) == limit || job.State != db.JobQueued || job.VisibleAt.After(time.Now()) {
+			continue
+		}
+		if shard.Count > 1 && int(db.ShardKey(job.ADOProjectName)%int64(shard.Count)) != shard.Index {
+			continue
+		}
+		job.State = db.JobLeased
+		job.LeasedBy = owner
+		job.LeaseExpiresAt = time.Now().Add(lease)
+		job.Attempts++
+		m.jobs[id] = job
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (m *enhancedMockDB) CompleteJob(ctx context.Context, job db.Job) error {
+	job.State = db.JobDone
+	job.Attempts = 0
+	job.Error = ""
+	job.ErrorClass = ""
+	job.Force = false
+	job.CheckAsana = false
+	m.jobs[job.ADOTaskID] = job
+	delete(m.deadLetters, job.ADOTaskID)
+	return nil
+}
+
+func (m *enhancedMockDB) ReturnJob(ctx context.Context, job db.Job) error {
+	job.State = db.JobQueued
+	job.Attempts = max(job.Attempts-1, 0)
+	job.LeasedBy = ""
+	m.jobs[job.ADOTaskID] = job
+	return nil
+}
+
+func (m *enhancedMockDB) FailJob(ctx context.Context, job db.Job, class, cause string, retryAt time.Time) error {
+	job.State = db.JobQueued
+	job.VisibleAt = retryAt
+	job.Error = cause
+	job.ErrorClass = class
+	m.jobs[job.ADOTaskID] = job
+	return nil
+}
+
+func (m *enhancedMockDB) DeadLetterJob(ctx context.Context, job db.Job, class, cause string) error {
+	job.State = db.JobFailed
+	job.Error = cause
+	job.ErrorClass = class
+	m.jobs[job.ADOTaskID] = job
+	m.deadLetters[job.ADOTaskID] = db.DeadLetter{ADOTaskID: job.ADOTaskID, ADOProjectName: job.ADOProjectName, ErrorClass: class, Error: cause, Attempts: job.Attempts}
+	return nil
+}
+
+func (m *enhancedMockDB) ForceResync(ctx context.Context, adoTaskID int) error {
+	task, ok := m.tasks[adoTaskID]
+	if !ok {
+		return fmt.Errorf("task not found")
+	}
+	if err := m.EnqueueJob(ctx, adoTaskID, task.ADOProjectID, time.Time{}); err != nil {
+		return err
+	}
+	job := m.jobs[adoTaskID]
+	job.Force = true
+	m.jobs[adoTaskID] = job
+	return nil
+}
+
+func (m *enhancedMockDB) DeadLetters(ctx context.Context) ([]db.DeadLetter, error) {
+	var letters []db.DeadLetter
+	for _, l := range m.deadLetters {
+		letters = append(letters, l)
+	}
+	return letters, nil
+}
+
+func (m *enhancedMockDB) RetryDeadLetter(ctx context.Context, adoTaskID int) error {
+	if job, ok := m.jobs[adoTaskID]; ok && job.State == db.JobFailed {
+		job.State = db.JobQueued
+		job.Attempts = 0
+		job.VisibleAt = time.Now()
+		m.jobs[adoTaskID] = job
+	}
+	delete(m.deadLetters, adoTaskID)
+	return nil
+}
+
+func (m *enhancedMockDB) IgnoreDeadLetter(ctx context.Context, adoTaskID int) error {
+	delete(m.deadLetters, adoTaskID)
+	return nil
+}
+
+func (m *enhancedMockDB) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	if err := m.errors["AcquireLease"]; err != nil {
+		return false, err
+	}
+	if l, ok := m.leases[name]; ok && l.Holder != holder && l.ExpiresAt.After(time.Now()) {
+		return false, nil
+	}
+	m.leases[name] = db.Lease{Name: name, Holder: holder, ExpiresAt: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (m *enhancedMockDB) ReleaseLease(ctx context.Context, name, holder string) error {
+	if l, ok := m.leases[name]; ok && l.Holder == holder {
+		delete(m.leases, name)
+	}
+	return nil
+}
+
+func (m *enhancedMockDB) Tasks(ctx context.Context, projectIDs ...string) ([]db.TaskMapping, error) {
+	if err := m.errors["Tasks"]; err != nil {
+		return nil, err
+	}
+	var tasks []db.TaskMapping
+	for _, t := range append(slices.Collect(maps.Values(m.tasks)), m.duplicates...) {
+		if len(projectIDs) == 0 || slices.Contains(projectIDs, t.ADOProjectID) {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks, nil
+}
+
 func (m *enhancedMockDB) TaskByADOTaskID(ctx context.Context, id int) (db.TaskMapping, error) {
 	if err := m.errors["TaskByADOTaskID"]; err != nil {
 		return db.TaskMapping{}, err
@@ -83,7 +297,7 @@ func (m *enhancedMockDB) TaskByADOTaskID(ctx context.Context, id int) (db.TaskMa
 	if task, ok := m.tasks[id]; ok {
 		return task, nil
 	}
-	return db.TaskMapping{}, fmt.Errorf("not found")
+	return db.TaskMapping{}, fmt.Errorf(db.ErrorFmtFindingTask, mongo.ErrNoDocuments)
 }
 
 func (m *enhancedMockDB) AddTask(ctx context.Context, task db.TaskMapping) error {
@@ -104,6 +318,137 @@ func (m *enhancedMockDB) UpdateTask(ctx context.Context, task db.TaskMapping) er
 	return nil
 }
 
+func (m *enhancedMockDB) RemoveTask(ctx context.Context, id primitive.ObjectID) error {
+	for i, t := range m.duplicates {
+		if t.ID == id {
+			m.duplicates = slices.Delete(m.duplicates, i, i+1)
+			return nil
+		}
+	}
+	for adoID, t := range m.tasks {
+		if t.ID == id {
+			delete(m.tasks, adoID)
+			return nil
+		}
+	}
+	return fmt.Errorf("task mapping does not exist")
+}
+
+func (m *enhancedMockDB) RenameTasksProject(ctx context.Context, from, to string) error {
+	for id, t := range m.tasks {
+		if t.ADOProjectID == from {
+			t.ADOProjectID = to
+			m.tasks[id] = t
+		}
+	}
+	for id, p := range m.wiProjects {
+		if p.ADOProjectID == from {
+			p.ADOProjectID = to
+			m.wiProjects[id] = p
+		}
+	}
+	return nil
+}
+
+func (m *enhancedMockDB) CreationIntent(ctx context.Context, adoTaskID int) (db.CreationIntent, bool, error) {
+	if err := m.errors["CreationIntent"]; err != nil {
+		return db.CreationIntent{}, false, err
+	}
+	intent, ok := m.intents[adoTaskID]
+	return intent, ok, nil
+}
+
+func (m *enhancedMockDB) AddCreationIntent(ctx context.Context, intent db.CreationIntent) error {
+	if err := m.errors["AddCreationIntent"]; err != nil {
+		return err
+	}
+	m.intents[intent.ADOTaskID] = intent
+	return nil
+}
+
+func (m *enhancedMockDB) RemoveCreationIntent(ctx context.Context, adoTaskID int) error {
+	delete(m.intents, adoTaskID)
+	return nil
+}
+
+func (m *enhancedMockDB) ControllerStatus(ctx context.Context) (db.ControllerStatus, bool, error) {
+	return db.ControllerStatus{}, false, nil
+}
+
+func (m *enhancedMockDB) WriteControllerStatus(ctx context.Context, status db.ControllerStatus) error {
+	return nil
+}
+
+func conflictKey(adoTaskID int, field string) string {
+	return fmt.Sprintf("%d/%s", adoTaskID, field)
+}
+
+func (m *enhancedMockDB) Conflicts(ctx context.Context) ([]db.Conflict, error) {
+	var conflicts []db.Conflict
+	for _, c := range m.conflicts {
+		conflicts = append(conflicts, c)
+	}
+	return conflicts, nil
+}
+
+func (m *enhancedMockDB) AddConflict(ctx context.Context, c db.Conflict) error {
+	if err := m.errors["AddConflict"]; err != nil {
+		return err
+	}
+	m.conflicts[conflictKey(c.ADOTaskID, c.Field)] = c
+	return nil
+}
+
+func (m *enhancedMockDB) ResolveConflict(ctx context.Context, adoTaskID int, field string, useADO bool) error {
+	delete(m.conflicts, conflictKey(adoTaskID, field))
+	if !useADO {
+		return nil
+	}
+	task := m.tasks[adoTaskID]
+	task.AsanaOwnedFields = slices.DeleteFunc(task.AsanaOwnedFields, func(f string) bool { return f == field })
+	m.tasks[adoTaskID] = task
+	return m.ForceResync(ctx, adoTaskID)
+}
+
+func (m *enhancedMockDB) AdoptionReviews(ctx context.Context) ([]db.AdoptionReview, error) {
+	var reviews []db.AdoptionReview
+	for _, r := range m.reviews {
+		if r.Decision == "" {
+			reviews = append(reviews, r)
+		}
+	}
+	return reviews, nil
+}
+
+func (m *enhancedMockDB) AdoptionReviewByADOTaskID(ctx context.Context, adoTaskID int) (db.AdoptionReview, bool, error) {
+	if err := m.errors["AdoptionReviewByADOTaskID"]; err != nil {
+		return db.AdoptionReview{}, false, err
+	}
+	r, ok := m.reviews[adoTaskID]
+	return r, ok, nil
+}
+
+func (m *enhancedMockDB) AddAdoptionReview(ctx context.Context, review db.AdoptionReview) error {
+	review.Decision, review.AsanaTaskID = "", ""
+	m.reviews[review.ADOTaskID] = review
+	return nil
+}
+
+func (m *enhancedMockDB) DecideAdoptionReview(ctx context.Context, adoTaskID int, decision, asanaTaskID string) error {
+	r, ok := m.reviews[adoTaskID]
+	if !ok {
+		return fmt.Errorf("review not found")
+	}
+	r.Decision, r.AsanaTaskID = decision, asanaTaskID
+	m.reviews[adoTaskID] = r
+	return m.EnqueueJob(ctx, adoTaskID, r.ADOProjectName, time.Time{})
+}
+
+func (m *enhancedMockDB) RemoveAdoptionReview(ctx context.Context, adoTaskID int) error {
+	delete(m.reviews, adoTaskID)
+	return nil
+}
+
 func (m *enhancedMockDB) GetCacheItem(ctx context.Context, key string) (db.CacheItem, error) {
 	if err := m.errors["GetCacheItem"]; err != nil {
 		return db.CacheItem{}, err
@@ -123,6 +468,11 @@ func (m *enhancedMockDB) UpsertCacheItem(ctx context.Context, item db.CacheItem)
 	return nil
 }
 
+func (m *enhancedMockDB) DeleteCacheItem(ctx context.Context, key string) error {
+	delete(m.cache, key)
+	return nil
+}
+
 func (m *enhancedMockDB) WorkspaceTag(ctx context.Context, workspaceName string) (db.WorkspaceTag, error) {
 	if err := m.errors["WorkspaceTag"]; err != nil {
 		return db.WorkspaceTag{}, err
@@ -138,19 +488,175 @@ func (m *enhancedMockDB) UpsertWorkspaceTag(ctx context.Context, tag db.Workspac
 	return nil
 }
 
+func (m *enhancedMockDB) WorkItemProjectByADOID(ctx context.Context, id int) (db.WorkItemProject, error) {
+	if err := m.errors["WorkItemProjectByADOID"]; err != nil {
+		return db.WorkItemProject{}, err
+	}
+	if p, ok := m.wiProjects[id]; ok {
+		return p, nil
+	}
+	return db.WorkItemProject{}, fmt.Errorf("error finding work item project: %w", mongo.ErrNoDocuments)
+}
+
+func (m *enhancedMockDB) UpsertWorkItemProject(ctx context.Context, p db.WorkItemProject) error {
+	if err := m.errors["UpsertWorkItemProject"]; err != nil {
+		return err
+	}
+	m.wiProjects[p.ADOWorkItemID] = p
+	return nil
+}
+
+func (m *enhancedMockDB) TaskByAsanaTaskID(ctx context.Context, gid string) (db.TaskMapping, bool, error) {
+	if err := m.errors["TaskByAsanaTaskID"]; err != nil {
+		return db.TaskMapping{}, false, err
+	}
+	for _, task := range m.tasks {
+		if task.AsanaTaskID == gid {
+			return task, true, nil
+		}
+	}
+	return db.TaskMapping{}, false, nil
+}
+
+func (m *enhancedMockDB) CheckAsanaTask(ctx context.Context, adoTaskID int, project string) error {
+	if err := m.EnqueueJob(ctx, adoTaskID, project, time.Time{}); err != nil {
+		return err
+	}
+	job := m.jobs[adoTaskID]
+	job.CheckAsana = true
+	m.jobs[adoTaskID] = job
+	return nil
+}
+
+func (m *enhancedMockDB) AsanaWebhooks(ctx context.Context) ([]db.AsanaWebhook, error) {
+	var hooks []db.AsanaWebhook
+	for _, h := range m.webhooks {
+		hooks = append(hooks, h)
+	}
+	return hooks, nil
+}
+
+func (m *enhancedMockDB) AsanaWebhook(ctx context.Context, projectGID string) (db.AsanaWebhook, bool, error) {
+	h, ok := m.webhooks[projectGID]
+	return h, ok, nil
+}
+
+func (m *enhancedMockDB) RequestAsanaWebhook(ctx context.Context, projectGID, target string) error {
+	m.webhooks[projectGID] = db.AsanaWebhook{AsanaProjectGID: projectGID, Target: target, RequestedAt: time.Now()}
+	return nil
+}
+
+func (m *enhancedMockDB) SetAsanaWebhookSecret(ctx context.Context, projectGID, secret string, since time.Time) (bool, error) {
+	h, ok := m.webhooks[projectGID]
+	if !ok || h.Secret != "" || h.RequestedAt.Before(since) {
+		return false, nil
+	}
+	h.Secret = secret
+	m.webhooks[projectGID] = h
+	return true, nil
+}
+
+func (m *enhancedMockDB) WriteAsanaWebhookGID(ctx context.Context, projectGID, webhookGID string) error {
+	h := m.webhooks[projectGID]
+	h.WebhookGID, h.CreatedAt = webhookGID, time.Now()
+	m.webhooks[projectGID] = h
+	return nil
+}
+
+func (m *enhancedMockDB) TouchAsanaWebhook(ctx context.Context, projectGID string) error {
+	h := m.webhooks[projectGID]
+	h.LastEventAt = time.Now()
+	m.webhooks[projectGID] = h
+	return nil
+}
+
+func (m *enhancedMockDB) RemoveAsanaWebhook(ctx context.Context, projectGID string) error {
+	delete(m.webhooks, projectGID)
+	return nil
+}
+
+func (m *enhancedMockDB) Reconciliations(ctx context.Context) ([]db.Reconciliation, error) {
+	var recs []db.Reconciliation
+	for _, r := range m.recs {
+		recs = append(recs, r)
+	}
+	return recs, nil
+}
+
+func (m *enhancedMockDB) RequestReconciliation(ctx context.Context, adoProjectName string, repair bool) error {
+	r := m.recs[adoProjectName]
+	r.ADOProjectName, r.RequestedAt, r.Repair = adoProjectName, time.Now(), repair
+	m.recs[adoProjectName] = r
+	return nil
+}
+
+func (m *enhancedMockDB) WriteReconciliation(ctx context.Context, rec db.Reconciliation) error {
+	prev := m.recs[rec.ADOProjectName]
+	rec.RequestedAt, rec.Repair = prev.RequestedAt, prev.Repair
+	if !rec.RequestedAt.After(rec.StartedAt) {
+		rec.RequestedAt = time.Time{}
+	}
+	m.recs[rec.ADOProjectName] = rec
+	return nil
+}
+
+func (m *enhancedMockDB) AsanaEventSyncs(ctx context.Context) ([]db.AsanaEventSync, error) {
+	var syncs []db.AsanaEventSync
+	for gid, token := range m.eventSyncs {
+		syncs = append(syncs, db.AsanaEventSync{AsanaProjectGID: gid, SyncToken: token})
+	}
+	return syncs, nil
+}
+
+func (m *enhancedMockDB) WriteAsanaSyncToken(ctx context.Context, projectGID, token string) error {
+	if err := m.errors["WriteAsanaSyncToken"]; err != nil {
+		return err
+	}
+	m.eventSyncs[projectGID] = token
+	return nil
+}
+
+func (m *enhancedMockDB) RemoveAsanaEventSync(ctx context.Context, projectGID string) error {
+	delete(m.eventSyncs, projectGID)
+	return nil
+}
+
 // Enhanced mockAsana with realistic behavior
 type enhancedMockAsana struct {
+	workspaces   []asana.Workspace
 	projects     map[string]map[string]string   // workspace → project name → GID
 	tasks        map[string][]asana.Task        // project GID → tasks
 	customFields map[string][]asana.CustomField // project GID → custom fields
 	tags         map[string]asana.Tag           // workspace → tag
+	remote       map[string]asana.Task          // task GID → task as currently in Asana
+	gone         map[string]bool                // task GIDs deleted in Asana
 
 	// Test tracking
-	tasksCreated       []asana.Task
-	tasksUpdated       []string            // task GIDs
-	tasksUpdatedWithCF []string            // task GIDs updated with custom fields
-	tagsAdded          map[string][]string // task GID → tag GIDs
-	errors             map[string]error
+	tasksCreated        []asana.Task
+	tasksUpdated        []string            // task GIDs
+	tasksUpdatedWithCF  []string            // task GIDs updated with custom fields
+	taskChanges         []taskChange        // fields sent by each task update
+	tagsAdded           map[string][]string // task GID → tag GIDs
+	projectsCreated     []asana.NewProject
+	projectsUpdated     []string                         // project GIDs
+	portfolioItems      map[string][]string              // portfolio GID → project GIDs
+	projectStatuses     map[string][]asana.ProjectStatus // project GID → status updates
+	stories             map[string][]string              // task GID → story HTML
+	webhooksCreated     []string                         // resource GIDs
+	webhooksDeleted     []string                         // webhook GIDs
+	eventPages          map[string][]asana.EventPage     // project GID → pages still to read
+	eventSyncs          []string                         // sync tokens events were read with
+	listProjectsCalls   int
+	listWorkspacesCalls int
+	errors              map[string]error
+}
+
+// taskChange records the fields sent by a task update.
+type taskChange struct {
+	GID          string
+	Name         string
+	Notes        string
+	CustomFields map[string]string
 }
 
 func newEnhancedMockAsana() *enhancedMockAsana {
@@ -159,10 +665,16 @@ func newEnhancedMockAsana() *enhancedMockAsana {
 		tasks:              make(map[string][]asana.Task),
 		customFields:       make(map[string][]asana.CustomField),
 		tags:               make(map[string]asana.Tag),
+		remote:             make(map[string]asana.Task),
+		gone:               make(map[string]bool),
 		tasksCreated:       []asana.Task{},
 		tasksUpdated:       []string{},
 		tasksUpdatedWithCF: []string{},
 		tagsAdded:          make(map[string][]string),
+		portfolioItems:     make(map[string][]string),
+		projectStatuses:    make(map[string][]asana.ProjectStatus),
+		stories:            make(map[string][]string),
+		eventPages:         make(map[string][]asana.EventPage),
 		errors:             make(map[string]error),
 	}
 }
@@ -182,7 +694,12 @@ func (m *enhancedMockAsana) ProjectGIDByName(ctx context.Context, workspace, pro
 }
 
 func (m *enhancedMockAsana) ListProjects(ctx context.Context, workspace string) ([]asana.Project, error) {
-	return nil, nil
+	m.listProjectsCalls++
+	var projects []asana.Project
+	for name, gid := range m.projects[workspace] {
+		projects = append(projects, asana.Project{GID: gid, Name: name})
+	}
+	return projects, nil
 }
 
 func (m *enhancedMockAsana) ListProjectTasks(ctx context.Context, projectGID string) ([]asana.Task, error) {
@@ -209,6 +726,7 @@ func (m *enhancedMockAsana) UpdateTask(ctx context.Context, taskGID, name, notes
 		return err
 	}
 	m.tasksUpdated = append(m.tasksUpdated, taskGID)
+	m.taskChanges = append(m.taskChanges, taskChange{GID: taskGID, Name: name, Notes: notes})
 	return nil
 }
 
@@ -218,14 +736,29 @@ func (m *enhancedMockAsana) CreateTaskWithCustomFields(ctx context.Context, proj
 	}
 	task := asana.Task{GID: fmt.Sprintf("task-cf-%d", len(m.tasksCreated)+1), Name: name}
 	m.tasksCreated = append(m.tasksCreated, task)
+	listed := task
+	listed.HTMLNotes = notes
+	listed.CustomFields = customFields
+	m.tasks[projectGID] = append(m.tasks[projectGID], listed)
 	return task, nil
 }
 
+func (m *enhancedMockAsana) TaskByGID(ctx context.Context, taskGID string) (asana.Task, error) {
+	if err := m.errors["TaskByGID"]; err != nil {
+		return asana.Task{}, err
+	}
+	if m.gone[taskGID] {
+		return asana.Task{}, &asana.APIError{StatusCode: http.StatusNotFound}
+	}
+	return m.remote[taskGID], nil
+}
+
 func (m *enhancedMockAsana) UpdateTaskWithCustomFields(ctx context.Context, taskGID, name, notes string, customFields map[string]string) error {
 	if err := m.errors["UpdateTaskWithCustomFields"]; err != nil {
 		return err
 	}
 	m.tasksUpdatedWithCF = append(m.tasksUpdatedWithCF, taskGID)
+	m.taskChanges = append(m.taskChanges, taskChange{GID: taskGID, Name: name, Notes: notes, CustomFields: customFields})
 	return nil
 }
 
@@ -270,26 +803,125 @@ func (m *enhancedMockAsana) AddTagToTask(ctx context.Context, taskGID, tagGID st
 }
 
 func (m *enhancedMockAsana) ListWorkspaces(ctx context.Context) ([]asana.Workspace, error) {
-	return nil, nil
+	m.listWorkspacesCalls++
+	return m.workspaces, nil
+}
+
+func (m *enhancedMockAsana) AddStoryToTask(ctx context.Context, taskGID, text string) error {
+	if err := m.errors["AddStoryToTask"]; err != nil {
+		return err
+	}
+	m.stories[taskGID] = append(m.stories[taskGID], text)
+	return nil
+}
+
+func (m *enhancedMockAsana) CreateWebhook(ctx context.Context, resourceGID, target string) (asana.Webhook, error) {
+	if err := m.errors["CreateWebhook"]; err != nil {
+		return asana.Webhook{}, err
+	}
+	m.webhooksCreated = append(m.webhooksCreated, resourceGID)
+	return asana.Webhook{GID: "webhook-" + resourceGID, Target: target, Active: true}, nil
+}
+
+func (m *enhancedMockAsana) DeleteWebhook(ctx context.Context, webhookGID string) error {
+	if err := m.errors["DeleteWebhook"]; err != nil {
+		return err
+	}
+	m.webhooksDeleted = append(m.webhooksDeleted, webhookGID)
+	return nil
+}
+
+// Events answers a missing token with a fresh one, like Asana, then reads
+// the queued pages. The token expires when Events is set in errors.
+func (m *enhancedMockAsana) CurrentUser(ctx context.Context) (asana.User, error) {
+	return asana.User{GID: "sync-user"}, nil
+}
+
+func (m *enhancedMockAsana) Events(ctx context.Context, resourceGID, sync string) (asana.EventPage, error) {
+	m.eventSyncs = append(m.eventSyncs, sync)
+	if err := m.errors["Events"]; err != nil {
+		return asana.EventPage{Sync: "fresh"}, err
+	}
+	if sync == "" {
+		return asana.EventPage{Sync: "fresh"}, &asana.APIError{StatusCode: http.StatusPreconditionFailed}
+	}
+	pages := m.eventPages[resourceGID]
+	if len(pages) == 0 {
+		return asana.EventPage{Sync: sync}, nil
+	}
+	m.eventPages[resourceGID] = pages[1:]
+	return pages[0], nil
+}
+
+func (m *enhancedMockAsana) CreateProject(ctx context.Context, p asana.NewProject) (asana.Project, error) {
+	if err := m.errors["CreateProject"]; err != nil {
+		return asana.Project{}, err
+	}
+	m.projectsCreated = append(m.projectsCreated, p)
+	return asana.Project{GID: fmt.Sprintf("project-%d", len(m.projectsCreated)), Name: p.Name}, nil
+}
+
+func (m *enhancedMockAsana) UpdateProject(ctx context.Context, projectGID, name, notes string) error {
+	if err := m.errors["UpdateProject"]; err != nil {
+		return err
+	}
+	m.projectsUpdated = append(m.projectsUpdated, projectGID)
+	return nil
+}
+
+func (m *enhancedMockAsana) AddProjectToPortfolio(ctx context.Context, portfolioGID, projectGID string) error {
+	if err := m.errors["AddProjectToPortfolio"]; err != nil {
+		return err
+	}
+	m.portfolioItems[portfolioGID] = append(m.portfolioItems[portfolioGID], projectGID)
+	return nil
+}
+
+func (m *enhancedMockAsana) CreateProjectStatus(ctx context.Context, projectGID string, status asana.ProjectStatus) error {
+	if err := m.errors["CreateProjectStatus"]; err != nil {
+		return err
+	}
+	m.projectStatuses[projectGID] = append(m.projectStatuses[projectGID], status)
+	return nil
 }
 
 // Enhanced mockAzure
 type enhancedMockAzure struct {
+	projects  []core.TeamProjectReference
 	workItems map[int]azure.WorkItem
+	revisions map[int]map[int]azure.WorkItem // work item ID → revision → work item
 	errors    map[string]error
+
+	// Test tracking
+	batchCalls       [][]int
+	getProjectsCalls int
 }
 
 func newEnhancedMockAzure() *enhancedMockAzure {
 	return &enhancedMockAzure{
 		workItems: make(map[int]azure.WorkItem),
+		revisions: make(map[int]map[int]azure.WorkItem),
 		errors:    make(map[string]error),
 	}
 }
 
 func (m *enhancedMockAzure) Connect(ctx context.Context, orgUrl, pat string) {}
 
-func (m *enhancedMockAzure) GetChangedWorkItems(ctx context.Context, lastSync time.Time) ([]workitemtracking.WorkItemReference, error) {
-	return nil, nil
+func (m *enhancedMockAzure) GetChangedWorkItems(ctx context.Context, project string, lastSync time.Time) ([]workitemtracking.WorkItemReference, time.Time, error) {
+	if err := m.errors["GetChangedWorkItems"]; err != nil {
+		return nil, time.Time{}, err
+	}
+	if lastSync.Before(db.FirstSync) {
+		// ADO rejects dates this early in WIQL queries.
+		return nil, time.Time{}, fmt.Errorf("invalid ChangedDate %v", lastSync)
+	}
+	var refs []workitemtracking.WorkItemReference
+	for id, wi := range m.workItems {
+		if wi.TeamProject == project && wi.ChangedDate.After(lastSync) {
+			refs = append(refs, workitemtracking.WorkItemReference{Id: &id})
+		}
+	}
+	return refs, time.Now(), nil
 }
 
 func (m *enhancedMockAzure) GetWorkItem(ctx context.Context, id int) (azure.WorkItem, error) {
@@ -302,17 +934,46 @@ func (m *enhancedMockAzure) GetWorkItem(ctx context.Context, id int) (azure.Work
 	return azure.WorkItem{}, fmt.Errorf("work item not found")
 }
 
+func (m *enhancedMockAzure) GetWorkItemsBatch(ctx context.Context, ids []int, expandRelations bool) ([]azure.WorkItem, error) {
+	m.batchCalls = append(m.batchCalls, ids)
+	if err := m.errors["GetWorkItemsBatch"]; err != nil {
+		return nil, err
+	}
+	var items []azure.WorkItem
+	for _, id := range ids {
+		if wi, ok := m.workItems[id]; ok {
+			items = append(items, wi)
+		}
+	}
+	return items, nil
+}
+
+func (m *enhancedMockAzure) GetWorkItemRevision(ctx context.Context, id, rev int) (azure.WorkItem, error) {
+	if err := m.errors["GetWorkItemRevision"]; err != nil {
+		return azure.WorkItem{}, err
+	}
+	if wi, ok := m.revisions[id][rev]; ok {
+		return wi, nil
+	}
+	return azure.WorkItem{}, fmt.Errorf("revision not found")
+}
+
 func (m *enhancedMockAzure) GetProjects(ctx context.Context) ([]core.TeamProjectReference, error) {
-	return nil, nil
+	m.getProjectsCalls++
+	return m.projects, nil
 }
 
 // Test helper functions
 func setupTestApp() *App {
+	mockDB := newEnhancedMockDB()
 	return &App{
-		DB:         newEnhancedMockDB(),
+		DB:         mockDB,
+		Elector:    newLeader(mockDB),
 		Asana:      newEnhancedMockAsana(),
 		Azure:      newEnhancedMockAzure(),
 		CacheTTL:   24 * time.Hour,
+		Resolver:   NewResolver(mockDB, 24*time.Hour),
+		Queue:      QueueConfig{BatchSize: 20, LeaseTimeout: time.Minute, PollInterval: 10 * time.Millisecond, RetryDelay: time.Minute, MaxRetryDelay: time.Hour, MaxAttempts: 3},
 		SyncedTags: make(map[string]asana.Tag),
 		Tracer:     otel.Tracer("test"),
 	}
@@ -333,12 +994,8 @@ func createTestWorkItem(id int, title, project, url string, changedDate time.Tim
 // Worker Tests
 // ============================================================================
 
-func TestWorkerProcessesTasks(t *testing.T) {
+func TestWorkerProcessesQueuedJobs(t *testing.T) {
 	app := setupTestApp()
-	taskCh := make(chan SyncTask, 1)
-	resultCh := make(chan error, 1)
-
-	// Setup: Add a work item and project mapping
 	mockDB := app.DB.(*enhancedMockDB)
 	mockAzure := app.Azure.(*enhancedMockAzure)
 	mockAsana := app.Asana.(*enhancedMockAsana)
@@ -347,70 +1004,118 @@ func TestWorkerProcessesTasks(t *testing.T) {
 		{ADOProjectName: "TestProject", AsanaWorkspaceName: "workspace1", AsanaProjectName: "AsanaProj"},
 	}
 	mockAzure.workItems[123] = createTestWorkItem(123, "Test Task", "TestProject", "http://ado.com/123", time.Now())
+	mockAsana.projects["workspace1"] = map[string]string{"AsanaProj": "proj-gid-1"}
+	assert.NoError(t, mockDB.EnqueueJob(context.Background(), 123, "TestProject", time.Time{}))
 
-	if mockAsana.projects["workspace1"] == nil {
-		mockAsana.projects["workspace1"] = make(map[string]string)
-	}
-	mockAsana.projects["workspace1"]["AsanaProj"] = "proj-gid-1"
+	n := app.processJobs(context.Background(), context.Background(), log.WithField("test", "worker"), "test/1")
 
-	// Start worker
-	go app.worker(context.Background(), 1, taskCh)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, [][]int{{123}}, mockAzure.batchCalls, "leased jobs are hydrated in one batch")
+	assert.Len(t, mockAsana.tasksCreated, 1, "should have created 1 Asana task")
+	assert.Equal(t, db.JobDone, mockDB.jobs[123].State)
+	assert.Zero(t, app.processJobs(context.Background(), context.Background(), log.WithField("test", "worker"), "test/1"), "queue is empty")
+}
 
-	// Send task
-	taskCh <- SyncTask{ADOTaskID: 123, Result: resultCh}
+func TestWorkerContinuesAfterError(t *testing.T) {
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAsana := app.Asana.(*enhancedMockAsana)
 
-	// Wait for result
-	select {
-	case err := <-resultCh:
-		assert.NoError(t, err)
-	case <-time.After(1 * time.Second):
-		t.Fatal("timeout waiting for worker to process task")
+	mockDB.projects = []db.Project{
+		{ADOProjectName: "TestProject", AsanaWorkspaceName: "workspace1", AsanaProjectName: "AsanaProj"},
 	}
+	mockAzure.workItems[456] = createTestWorkItem(456, "Second Task", "TestProject", "http://ado.com/456", time.Now())
+	mockAsana.projects["workspace1"] = map[string]string{"AsanaProj": "proj-gid-1"}
+	assert.NoError(t, mockDB.EnqueueJob(context.Background(), 123, "TestProject", time.Time{}))
+	assert.NoError(t, mockDB.EnqueueJob(context.Background(), 456, "TestProject", time.Time{}))
 
-	// Verify task was created
-	assert.Len(t, mockAsana.tasksCreated, 1, "should have created 1 Asana task")
-	close(taskCh)
+	app.processJobs(context.Background(), context.Background(), log.WithField("test", "worker"), "test/1")
+
+	failed := mockDB.jobs[123]
+	assert.Equal(t, db.JobQueued, failed.State, "failed job is retried")
+	assert.Equal(t, "work item not found", failed.Error)
+	assert.True(t, failed.VisibleAt.After(time.Now()), "retry is delayed")
+	assert.Equal(t, db.JobDone, mockDB.jobs[456].State)
+	assert.Len(t, mockAsana.tasksCreated, 1)
 }
 
-func TestWorkerContinuesAfterError(t *testing.T) {
+func TestWorkerStopsWhenCancelled(t *testing.T) {
 	app := setupTestApp()
-	taskCh := make(chan SyncTask, 2)
-	resultCh := make(chan error, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		app.worker(ctx, context.Background(), 1)
+		close(done)
+	}()
 
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop")
+	}
+}
+
+func TestProcessJobsReturnsUnstartedJobsWhenStopping(t *testing.T) {
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
 	mockAzure := app.Azure.(*enhancedMockAzure)
-	mockAzure.errors["GetWorkItem"] = fmt.Errorf("API error")
+	mockAzure.workItems[123] = createTestWorkItem(123, "Test Task", "TestProject", "http://ado.com/123", time.Now())
+	assert.NoError(t, mockDB.EnqueueJob(context.Background(), 123, "TestProject", time.Time{}))
+	assert.NoError(t, mockDB.EnqueueJob(context.Background(), 456, "TestProject", time.Time{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
 
-	go app.worker(context.Background(), 1, taskCh)
+	n := app.processJobs(ctx, context.Background(), log.WithField("test", "worker"), "test/1")
 
-	// Send failing task
-	taskCh <- SyncTask{ADOTaskID: 999, Result: resultCh}
+	assert.Equal(t, 2, n)
+	for _, id := range []int{123, 456} {
+		assert.Equal(t, db.JobQueued, mockDB.jobs[id].State, "job %d is back in the queue", id)
+		assert.Zero(t, mockDB.jobs[id].Attempts, "the attempt is not counted")
+	}
+}
 
-	// First task should fail
-	err := <-resultCh
-	assert.Error(t, err)
+// graceExpiredDB cancels the job context as soon as jobs are leased, as when
+// the shutdown grace period runs out mid-job, and rejects releases made with a
+// cancelled context as Mongo would.
+type graceExpiredDB struct {
+	*enhancedMockDB
+	cancelJobs context.CancelFunc
+}
 
-	// Now fix the error and send another task
-	delete(mockAzure.errors, "GetWorkItem")
-	mockDB := app.DB.(*enhancedMockDB)
-	mockAsana := app.Asana.(*enhancedMockAsana)
+func (m *graceExpiredDB) LeaseJobs(ctx context.Context, owner string, shard db.Shard, limit int, lease time.Duration) ([]db.Job, error) {
+	jobs, err := m.enhancedMockDB.LeaseJobs(ctx, owner, shard, limit, lease)
+	m.cancelJobs()
+	return jobs, err
+}
 
-	mockDB.projects = []db.Project{
-		{ADOProjectName: "TestProject", AsanaWorkspaceName: "workspace1", AsanaProjectName: "AsanaProj"},
+func (m *graceExpiredDB) CompleteJob(ctx context.Context, job db.Job) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	mockAzure.workItems[456] = createTestWorkItem(456, "Second Task", "TestProject", "http://ado.com/456", time.Now())
+	return m.enhancedMockDB.CompleteJob(ctx, job)
+}
 
-	if mockAsana.projects["workspace1"] == nil {
-		mockAsana.projects["workspace1"] = make(map[string]string)
+func (m *graceExpiredDB) FailJob(ctx context.Context, job db.Job, class, cause string, retryAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	mockAsana.projects["workspace1"]["AsanaProj"] = "proj-gid-1"
+	return m.enhancedMockDB.FailJob(ctx, job, class, cause, retryAt)
+}
 
-	taskCh <- SyncTask{ADOTaskID: 456, Result: resultCh}
+func TestProcessJobsReleasesJobsAfterGracePeriod(t *testing.T) {
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	jobCtx, cancelJobs := context.WithCancel(context.Background())
+	app.DB = &graceExpiredDB{enhancedMockDB: mockDB, cancelJobs: cancelJobs}
+	assert.NoError(t, mockDB.EnqueueJob(context.Background(), 123, "TestProject", time.Time{}))
 
-	// Second task should succeed
-	err = <-resultCh
-	assert.NoError(t, err)
+	app.processJobs(context.Background(), jobCtx, log.WithField("test", "worker"), "test/1")
 
-	close(taskCh)
+	assert.Equal(t, db.JobQueued, mockDB.jobs[123].State, "the job is released rather than left leased")
+	assert.NotEmpty(t, mockDB.jobs[123].Error)
 }
 
 // ============================================================================
@@ -436,13 +1141,84 @@ func TestHandleTaskUpdateExisting(t *testing.T) {
 	mockAzure.workItems[123] = createTestWorkItem(123, "Updated Task", "TestProject", "http://ado.com/123", time.Now())
 
 	task := SyncTask{ADOTaskID: 123}
-	err := app.handleTask(ctx, wlog, task)
+	_, err := app.handleTask(ctx, wlog, task)
 
 	assert.NoError(t, err)
 	assert.Len(t, mockAsana.tasksUpdated, 1, "should have updated existing task")
 	assert.Len(t, mockDB.updateTaskCalls, 1, "should have updated DB mapping")
 }
 
+func TestHandleTaskSkipsSyncedRevision(t *testing.T) {
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+
+	mockDB.tasks[123] = db.TaskMapping{ADOTaskID: 123, ADORevision: 5, AsanaTaskID: "existing-task-1"}
+	changed := time.Now()
+	wi := createTestWorkItem(123, "Task", "TestProject", "http://ado.com/123", changed)
+	wi.Rev = 5
+	mockAzure.workItems[123] = wi
+
+	got, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 123})
+
+	assert.NoError(t, err)
+	assert.Equal(t, changed, got.ChangedDate, "work item returned for the watermark")
+	assert.Empty(t, mockAsana.tasksUpdated, "revision already synced")
+	assert.Empty(t, mockDB.updateTaskCalls)
+}
+
+func TestHandleTaskSkipsDeletedWorkItem(t *testing.T) {
+	app := setupTestApp()
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAzure.errors["GetWorkItem"] = &azure.APIError{StatusCode: 404, Err: fmt.Errorf("TF401232: Work item 123 does not exist")}
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 123})
+
+	assert.NoError(t, err, "deleted work items are skipped, not retried")
+}
+
+func TestHandleTaskReturnsRejectedUpdate(t *testing.T) {
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+	mockAsana.errors["UpdateTask"] = &asana.APIError{StatusCode: 400, Body: "name too long"}
+
+	mockDB.tasks[123] = db.TaskMapping{ADOTaskID: 123, ADORevision: 1, AsanaTaskID: "existing-task-1"}
+	wi := createTestWorkItem(123, "Task", "TestProject", "http://ado.com/123", time.Now())
+	wi.Rev = 2
+	mockAzure.workItems[123] = wi
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 123})
+
+	assert.ErrorIs(t, err, asana.ErrValidation, "returned so the job is dead-lettered")
+	assert.True(t, permanentError(err))
+	assert.Empty(t, mockDB.updateTaskCalls, "revision not recorded so the next change is synced")
+}
+
+func TestHandleTaskReturnsRetryableErrors(t *testing.T) {
+	for _, err := range []error{
+		&asana.APIError{StatusCode: 429},
+		&asana.APIError{StatusCode: 401},
+		&asana.APIError{StatusCode: 404},
+		fmt.Errorf("connection reset"),
+	} {
+		app := setupTestApp()
+		mockDB := app.DB.(*enhancedMockDB)
+		mockAzure := app.Azure.(*enhancedMockAzure)
+		mockAsana := app.Asana.(*enhancedMockAsana)
+		mockAsana.errors["UpdateTask"] = err
+
+		mockDB.tasks[123] = db.TaskMapping{ADOTaskID: 123, AsanaTaskID: "existing-task-1"}
+		mockAzure.workItems[123] = createTestWorkItem(123, "Task", "TestProject", "http://ado.com/123", time.Now())
+
+		_, got := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 123})
+
+		assert.ErrorIs(t, got, err)
+	}
+}
+
 func TestHandleTaskCreateNew(t *testing.T) {
 	app := setupTestApp()
 	ctx := context.Background()
@@ -464,7 +1240,7 @@ func TestHandleTaskCreateNew(t *testing.T) {
 	mockAsana.projects["workspace1"]["AsanaProj"] = "proj-gid-1"
 
 	task := SyncTask{ADOTaskID: 123}
-	err := app.handleTask(ctx, wlog, task)
+	_, err := app.handleTask(ctx, wlog, task)
 
 	assert.NoError(t, err)
 	assert.Len(t, mockAsana.tasksCreated, 1, "should have created new Asana task")
@@ -483,13 +1259,13 @@ func TestHandleTaskSkipUnmappedProject(t *testing.T) {
 	mockAzure.workItems[123] = createTestWorkItem(123, "Unmapped Task", "UnmappedProj", "http://ado.com/123", time.Now())
 
 	task := SyncTask{ADOTaskID: 123}
-	err := app.handleTask(ctx, wlog, task)
+	_, err := app.handleTask(ctx, wlog, task)
 
 	assert.NoError(t, err, "should not error for unmapped project")
 	assert.Len(t, mockAsana.tasksCreated, 0, "should not create task")
 }
 
-func TestHandleTaskUpdateExistingByName(t *testing.T) {
+func TestHandleTaskReviewsExistingByName(t *testing.T) {
 	app := setupTestApp()
 	ctx := context.Background()
 	wlog := log.WithField("test", "worker")
@@ -513,12 +1289,13 @@ func TestHandleTaskUpdateExistingByName(t *testing.T) {
 	}
 
 	task := SyncTask{ADOTaskID: 123}
-	err := app.handleTask(ctx, wlog, task)
+	_, err := app.handleTask(ctx, wlog, task)
 
 	assert.NoError(t, err)
-	assert.Len(t, mockAsana.tasksUpdated, 1, "should have updated existing task by name")
-	assert.Len(t, mockDB.addTaskCalls, 1, "should have created new mapping")
+	assert.Len(t, mockAsana.tasksUpdated, 0, "a task is not adopted by its name alone")
+	assert.Len(t, mockDB.addTaskCalls, 0, "should not map the task before review")
 	assert.Len(t, mockAsana.tasksCreated, 0, "should not create new task")
+	assert.Equal(t, "existing-task-gid", mockDB.reviews[123].Candidates[0].AsanaTaskID, "should list the task for review")
 }
 
 // ============================================================================
@@ -535,7 +1312,7 @@ func TestPrepWorkItemExistingMapping(t *testing.T) {
 	mockDB.tasks[123] = db.TaskMapping{ADOTaskID: 123, AsanaTaskID: "task-1"}
 	mockAzure.workItems[123] = createTestWorkItem(123, "Test Task", "TestProject", "http://ado.com/123", time.Now())
 
-	mapping, wi, name, desc, err := app.prepWorkItem(ctx, 123)
+	mapping, wi, name, desc, err := app.prepWorkItem(ctx, SyncTask{ADOTaskID: 123})
 
 	assert.NoError(t, err)
 	assert.NotNil(t, mapping, "should return existing mapping")
@@ -552,7 +1329,7 @@ func TestPrepWorkItemNewWorkItem(t *testing.T) {
 	mockAzure := app.Azure.(*enhancedMockAzure)
 	mockAzure.workItems[456] = createTestWorkItem(456, "New Task", "Project", "http://ado.com/456", time.Now())
 
-	mapping, wi, name, desc, err := app.prepWorkItem(ctx, 456)
+	mapping, wi, name, desc, err := app.prepWorkItem(ctx, SyncTask{ADOTaskID: 456})
 
 	assert.NoError(t, err)
 	assert.Nil(t, mapping, "should return nil mapping for new work item")
@@ -561,6 +1338,19 @@ func TestPrepWorkItemNewWorkItem(t *testing.T) {
 	assert.NotEmpty(t, desc)
 }
 
+func TestPrepWorkItemUsesHydratedWorkItem(t *testing.T) {
+	app := setupTestApp()
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAzure.errors["GetWorkItem"] = fmt.Errorf("should not be called")
+
+	wi := createTestWorkItem(456, "Hydrated", "Project", "http://ado.com/456", time.Now())
+	_, got, name, _, err := app.prepWorkItem(context.Background(), SyncTask{ADOTaskID: 456, WorkItem: &wi})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 456, got.ID)
+	assert.Contains(t, name, "Hydrated")
+}
+
 func TestPrepWorkItemAzureError(t *testing.T) {
 	app := setupTestApp()
 	ctx := context.Background()
@@ -568,7 +1358,7 @@ func TestPrepWorkItemAzureError(t *testing.T) {
 	mockAzure := app.Azure.(*enhancedMockAzure)
 	mockAzure.errors["GetWorkItem"] = fmt.Errorf("Azure API error")
 
-	_, _, _, _, err := app.prepWorkItem(ctx, 999)
+	_, _, _, _, err := app.prepWorkItem(ctx, SyncTask{ADOTaskID: 999})
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "Azure API error")
@@ -598,7 +1388,7 @@ func TestUpdateExistingTaskWithCustomFields(t *testing.T) {
 	}
 	wi := createTestWorkItem(123, "Task", "TestProject", "http://ado.com/123", time.Now())
 
-	err := app.updateExistingTask(ctx, wi, mapping, "Task Name", "Task Desc")
+	err := app.updateExistingTask(ctx, wi, mapping, "Task Name", "Task Desc", false)
 
 	assert.NoError(t, err)
 	assert.Len(t, mockAsana.tasksUpdatedWithCF, 1, "should update with custom fields")
@@ -618,7 +1408,7 @@ func TestUpdateExistingTaskWithoutCustomFields(t *testing.T) {
 	}
 	wi := createTestWorkItem(123, "Task", "TestProject", "http://ado.com/123", time.Now())
 
-	err := app.updateExistingTask(ctx, wi, mapping, "Task Name", "Task Desc")
+	err := app.updateExistingTask(ctx, wi, mapping, "Task Name", "Task Desc", false)
 
 	assert.NoError(t, err)
 	assert.Len(t, mockAsana.tasksUpdated, 1, "should update without custom fields")
@@ -636,7 +1426,7 @@ func TestUpdateExistingTaskAsanaError(t *testing.T) {
 	mapping := db.TaskMapping{AsanaProjectID: "proj-1", AsanaTaskID: "task-1"}
 	wi := createTestWorkItem(123, "Task", "TestProject", "http://ado.com/123", time.Now())
 
-	err := app.updateExistingTask(ctx, wi, mapping, "Task Name", "Task Desc")
+	err := app.updateExistingTask(ctx, wi, mapping, "Task Name", "Task Desc", false)
 
 	assert.Error(t, err)
 	assert.Len(t, mockDB.updateTaskCalls, 0, "should not update DB on Asana error")
@@ -799,6 +1589,70 @@ func TestCreateAndMapTaskWithCustomFields(t *testing.T) {
 	assert.Len(t, mockDB.addTaskCalls, 1)
 }
 
+func TestCreateAndMapTaskRecordsIntent(t *testing.T) {
+	app := setupTestApp()
+	ctx := context.Background()
+	mockAsana := app.Asana.(*enhancedMockAsana)
+	mockDB := app.DB.(*enhancedMockDB)
+	wi := createTestWorkItem(123, "New Task", "TestProject", "http://ado.com/123", time.Now())
+
+	mockDB.errors["AddTask"] = fmt.Errorf("mongo down")
+	err := app.createAndMapTask(ctx, "proj-1", "workspace1", wi, "Task Name", "Task Desc")
+
+	assert.Error(t, err)
+	assert.Equal(t, "proj-1", mockDB.intents[123].AsanaProjectID, "intent kept until the mapping is saved")
+	assert.Len(t, mockAsana.tasksCreated, 1)
+
+	delete(mockDB.errors, "AddTask")
+	assert.NoError(t, app.createAndMapTask(ctx, "proj-1", "workspace1", wi, "Task Name", "Task Desc"))
+	assert.Empty(t, mockDB.intents)
+}
+
+func TestHandleTaskRecoversInterruptedCreation(t *testing.T) {
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+	mockDB.projects = []db.Project{
+		{ADOProjectName: "TestProject", AsanaWorkspaceName: "workspace1", AsanaProjectName: "AsanaProj"},
+	}
+	mockAsana.projects["workspace1"] = map[string]string{"AsanaProj": "proj-gid-1"}
+	mockAzure.workItems[123] = createTestWorkItem(123, "Test Task", "TestProject", "http://ado.com/123", time.Now())
+	mockDB.intents[123] = db.CreationIntent{ADOTaskID: 123, AsanaProjectID: "proj-gid-1"}
+	mockAsana.tasks["proj-gid-1"] = []asana.Task{
+		{GID: "orphan-1", Name: "Old title", HTMLNotes: `<body><a href="http://ado.com/_apis/wit/workItems/123">Story 123:</a> Old title</body>`},
+		{GID: "other-1", Name: "Test Task", HTMLNotes: `<body><a href="http://ado.com/_apis/wit/workItems/124">Story 124:</a> Test Task</body>`},
+	}
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 123})
+
+	assert.NoError(t, err)
+	assert.Empty(t, mockAsana.tasksCreated, "the task from the interrupted run is reused")
+	assert.Equal(t, "orphan-1", mockDB.tasks[123].AsanaTaskID)
+	assert.Equal(t, "proj-gid-1", mockDB.tasks[123].AsanaProjectID)
+	assert.Empty(t, mockDB.intents)
+}
+
+func TestHandleTaskRetriesCreationThatNeverReachedAsana(t *testing.T) {
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAzure := app.Azure.(*enhancedMockAzure)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+	mockDB.projects = []db.Project{
+		{ADOProjectName: "TestProject", AsanaWorkspaceName: "workspace1", AsanaProjectName: "AsanaProj"},
+	}
+	mockAsana.projects["workspace1"] = map[string]string{"AsanaProj": "proj-gid-1"}
+	mockAzure.workItems[123] = createTestWorkItem(123, "Test Task", "TestProject", "http://ado.com/123", time.Now())
+	mockDB.intents[123] = db.CreationIntent{ADOTaskID: 123, AsanaProjectID: "proj-gid-1"}
+
+	_, err := app.handleTask(context.Background(), log.WithField("test", "worker"), SyncTask{ADOTaskID: 123})
+
+	assert.NoError(t, err)
+	assert.Len(t, mockAsana.tasksCreated, 1)
+	assert.Equal(t, mockAsana.tasksCreated[0].GID, mockDB.tasks[123].AsanaTaskID)
+	assert.Empty(t, mockDB.intents)
+}
+
 func TestAddSyncedTagSuccess(t *testing.T) {
 	app := setupTestApp()
 	ctx := context.Background()
@@ -823,3 +1677,20 @@ func TestAddSyncedTagNoTag(t *testing.T) {
 
 	assert.Len(t, mockAsana.tagsAdded, 0, "should not add tag when not resolved")
 }
+
+func TestUpdateExistingTaskTagsAfterProjectRename(t *testing.T) {
+	app := setupTestApp()
+	mockDB := app.DB.(*enhancedMockDB)
+	mockAsana := app.Asana.(*enhancedMockAsana)
+	mockDB.projects = []db.Project{{ADOProjectName: "Renamed", AsanaWorkspaceName: "workspace1", AsanaProjectName: "AsanaProj"}}
+	app.SyncedTags["workspace1"] = asana.Tag{GID: "tag-123", Name: "synced"}
+
+	wi := createTestWorkItem(123, "Task", "Renamed", "http://ado.com/123", time.Now())
+	mapping := db.TaskMapping{ADOProjectID: "Old name", ADOTaskID: 123, AsanaProjectID: "proj-1", AsanaTaskID: "task-1"}
+
+	err := app.updateExistingTask(context.Background(), wi, mapping, "name", "desc", false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Renamed", mockDB.updateTaskCalls[0].ADOProjectID)
+	assert.Equal(t, []string{"tag-123"}, mockAsana.tagsAdded["task-1"])
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type AdoptionReviewsViewData struct {
+	Title       string
+	CurrentPage string
+	Reviews     []db.AdoptionReview
+}
+
+func adoptionReviewsHandler(app *App, c *gin.Context) {
+	ctx, span := app.Tracer.Start(c.Request.Context(), "adoptionReviews.adoptionReviewsHandler")
+	defer span.End()
+
+	reviews, err := app.DB.AdoptionReviews(ctx)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Unable to fetch adoption reviews",
+		})
+		return
+	}
+	span.AddEvent(fmt.Sprintf("%v adoption reviews fetched", len(reviews)))
+
+	c.HTML(http.StatusOK, "adoptionReviews", AdoptionReviewsViewData{
+		Title:       "Adoption Reviews",
+		CurrentPage: "adoption-reviews",
+		Reviews:     reviews,
+	})
+}
+
+func decideAdoptionHandler(app *App, c *gin.Context) {
+	ctx, span := app.Tracer.Start(c.Request.Context(), "adoptionReviews.decideAdoptionHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid work item ID"})
+		return
+	}
+
+	decision := c.Query("decision")
+	if decision != db.AdoptionDecisionAdopt && decision != db.AdoptionDecisionCreate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid decision"})
+		return
+	}
+
+	if err := app.DB.DecideAdoptionReview(ctx, id, decision, c.Query("task")); err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record decision"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
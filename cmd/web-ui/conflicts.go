@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type ConflictsViewData struct {
+	Title       string
+	CurrentPage string
+	Conflicts   []db.Conflict
+}
+
+func conflictsHandler(app *App, c *gin.Context) {
+	ctx, span := app.Tracer.Start(c.Request.Context(), "conflicts.conflictsHandler")
+	defer span.End()
+
+	conflicts, err := app.DB.Conflicts(ctx)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Unable to fetch conflicts",
+		})
+		return
+	}
+	span.AddEvent(fmt.Sprintf("%v conflicts fetched", len(conflicts)))
+
+	c.HTML(http.StatusOK, "conflicts", ConflictsViewData{
+		Title:       "Conflicts",
+		CurrentPage: "conflicts",
+		Conflicts:   conflicts,
+	})
+}
+
+func resolveConflictHandler(app *App, c *gin.Context) {
+	ctx, span := app.Tracer.Start(c.Request.Context(), "conflicts.resolveConflictHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid work item ID"})
+		return
+	}
+
+	field := c.Query("field")
+	if field == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing field"})
+		return
+	}
+
+	keep := c.Query("keep")
+	if keep != "ado" && keep != "asana" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid side"})
+		return
+	}
+
+	if err := app.DB.ResolveConflict(ctx, id, field, keep == "ado"); err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve conflict"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -2,13 +2,58 @@ package main
 
 import (
 	"net/http"
+	"strconv"
 
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func homeHandler(c *gin.Context) {
-	c.HTML(http.StatusOK, "index", gin.H{
-		"Title":       "Dashboard",
-		"CurrentPage": "home",
+type DashboardViewData struct {
+	Title       string
+	CurrentPage string
+	// Controller is the health reported by the controller; HasController
+	// is false until a controller has run once.
+	Controller    db.ControllerStatus
+	HasController bool
+}
+
+func homeHandler(app *App, c *gin.Context) {
+	ctx, span := app.Tracer.Start(c.Request.Context(), "dashboard.homeHandler")
+	defer span.End()
+
+	status, ok, err := app.DB.ControllerStatus(ctx)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Unable to fetch controller status",
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "index", DashboardViewData{
+		Title:         "Dashboard",
+		CurrentPage:   "home",
+		Controller:    status,
+		HasController: ok,
 	})
 }
+
+func forceResyncHandler(app *App, c *gin.Context) {
+	ctx, span := app.Tracer.Start(c.Request.Context(), "dashboard.forceResyncHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid work item ID"})
+		return
+	}
+
+	if err := app.DB.ForceResync(ctx, id); err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to queue work item"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
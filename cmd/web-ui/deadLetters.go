@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type DeadLettersViewData struct {
+	Title       string
+	CurrentPage string
+	DeadLetters []db.DeadLetter
+}
+
+func deadLettersHandler(app *App, c *gin.Context) {
+	ctx, span := app.Tracer.Start(c.Request.Context(), "deadLetters.deadLettersHandler")
+	defer span.End()
+
+	letters, err := app.DB.DeadLetters(ctx)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Unable to fetch dead letters",
+		})
+		return
+	}
+	span.AddEvent(fmt.Sprintf("%v dead letters fetched", len(letters)))
+
+	c.HTML(http.StatusOK, "deadLetters", DeadLettersViewData{
+		Title:       "Dead Letters",
+		CurrentPage: "dead-letters",
+		DeadLetters: letters,
+	})
+}
+
+func retryDeadLetterHandler(app *App, c *gin.Context) {
+	ctx, span := app.Tracer.Start(c.Request.Context(), "deadLetters.retryDeadLetterHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid work item ID"})
+		return
+	}
+
+	if err := app.DB.RetryDeadLetter(ctx, id); err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retry work item"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func ignoreDeadLetterHandler(app *App, c *gin.Context) {
+	ctx, span := app.Tracer.Start(c.Request.Context(), "deadLetters.ignoreDeadLetterHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid work item ID"})
+		return
+	}
+
+	if err := app.DB.IgnoreDeadLetter(ctx, id); err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to ignore work item"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
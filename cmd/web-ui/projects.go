@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"time"
 
 	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	"github.com/ADO-Asana-Sync/sync-engine/internal/schedule"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.opentelemetry.io/otel/trace"
@@ -16,7 +18,9 @@ type ProjectsViewData struct {
 	Title       string
 	CurrentPage string
 	Projects    []db.Project
-	Error       string
+	// NextRuns maps ADO project names to when they are next synced.
+	NextRuns map[string]time.Time
+	Error    string
 }
 
 func fetchProjectsData(ctx context.Context, app *App) (data ProjectsViewData, err error) {
@@ -33,10 +37,21 @@ func fetchProjectsData(ctx context.Context, app *App) (data ProjectsViewData, er
 		return projects[i].ADOProjectName < projects[j].ADOProjectName
 	})
 
+	checkpoints, err := app.DB.SyncCheckpoints(ctx)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		return data, err
+	}
+	nextRuns := make(map[string]time.Time)
+	for _, cp := range checkpoints {
+		nextRuns[cp.ADOProjectName] = cp.NextRunAt
+	}
+
 	data = ProjectsViewData{
 		Title:       "Projects",
 		CurrentPage: "projects",
 		Projects:    projects,
+		NextRuns:    nextRuns,
 	}
 	span.AddEvent(fmt.Sprintf("%v projects fetched", len(projects)))
 	return data, nil
@@ -76,12 +91,20 @@ func addProjectHandler(app *App, c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Asana workspace name is required"})
 		return
 	}
+	syncSchedule := c.Request.FormValue("sync_schedule")
+	quietHours := c.Request.FormValue("quiet_hours")
+	if _, err := schedule.New(syncSchedule, quietHours); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid schedule: %v", err)})
+		return
+	}
 
 	project := db.Project{
 		ID:                 primitive.NewObjectID(),
 		ADOProjectName:     adoProjectName,
 		AsanaProjectName:   asanaProjectName,
 		AsanaWorkspaceName: asanaWorkspaceName,
+		SyncSchedule:       syncSchedule,
+		QuietHours:         quietHours,
 	}
 
 	err := app.DB.AddProject(ctx, project)
@@ -157,6 +180,12 @@ func editProjectHandler(app *App, c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Asana workspace name is required"})
 		return
 	}
+	syncSchedule := c.Request.FormValue("sync_schedule")
+	quietHours := c.Request.FormValue("quiet_hours")
+	if _, err := schedule.New(syncSchedule, quietHours); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid schedule: %v", err)})
+		return
+	}
 
 	// Convert projectID to ObjectID.
 	objID, err := primitive.ObjectIDFromHex(projectID)
@@ -173,6 +202,8 @@ func editProjectHandler(app *App, c *gin.Context) {
 		ADOProjectName:     adoProjectName,
 		AsanaProjectName:   asanaProjectName,
 		AsanaWorkspaceName: asanaWorkspaceName,
+		SyncSchedule:       syncSchedule,
+		QuietHours:         quietHours,
 	}
 
 	err = app.DB.UpdateProject(ctx, project)
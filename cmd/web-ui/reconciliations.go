@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type ReconciliationsViewData struct {
+	Title           string
+	CurrentPage     string
+	Reconciliations []db.Reconciliation
+}
+
+func reconciliationsHandler(app *App, c *gin.Context) {
+	ctx, span := app.Tracer.Start(c.Request.Context(), "reconciliations.reconciliationsHandler")
+	defer span.End()
+
+	projects, err := app.DB.Projects(ctx)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Unable to fetch projects",
+		})
+		return
+	}
+	recs, err := app.DB.Reconciliations(ctx)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Unable to fetch reconciliations",
+		})
+		return
+	}
+	span.AddEvent(fmt.Sprintf("%v reconciliations fetched", len(recs)))
+
+	// Every mapped project is listed, reconciled yet or not.
+	byProject := make(map[string]db.Reconciliation)
+	for _, r := range recs {
+		byProject[r.ADOProjectName] = r
+	}
+	var rows []db.Reconciliation
+	seen := make(map[string]bool)
+	for _, p := range projects {
+		if seen[p.ADOProjectName] {
+			continue
+		}
+		seen[p.ADOProjectName] = true
+		r, ok := byProject[p.ADOProjectName]
+		if !ok {
+			r = db.Reconciliation{ADOProjectName: p.ADOProjectName}
+		}
+		rows = append(rows, r)
+	}
+
+	c.HTML(http.StatusOK, "reconciliations", ReconciliationsViewData{
+		Title:           "Reconciliation",
+		CurrentPage:     "reconciliations",
+		Reconciliations: rows,
+	})
+}
+
+func requestReconciliationHandler(app *App, c *gin.Context) {
+	ctx, span := app.Tracer.Start(c.Request.Context(), "reconciliations.requestReconciliationHandler")
+	defer span.End()
+
+	project := c.Query("project")
+	if project == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing project"})
+		return
+	}
+
+	repair := false
+	if v := c.Query("repair"); v != "" {
+		var err error
+		if repair, err = strconv.ParseBool(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid repair flag"})
+			return
+		}
+	}
+
+	projects, err := app.DB.Projects(ctx)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch projects"})
+		return
+	}
+	mapped := false
+	for _, p := range projects {
+		if p.ADOProjectName == project {
+			mapped = true
+			break
+		}
+	}
+	if !mapped {
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not mapped"})
+		return
+	}
+
+	if err := app.DB.RequestReconciliation(ctx, project, repair); err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to request reconciliation"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -10,7 +10,12 @@ func registerRoutes(router *gin.Engine, app *App) {
 	router.StaticFile("/favicon.ico", "./static/img/favicon.ico")
 
 	// Dashboard routes.
-	router.GET("/", homeHandler)
+	router.GET("/", func(c *gin.Context) {
+		homeHandler(app, c)
+	})
+	router.POST("/force-resync", func(c *gin.Context) {
+		forceResyncHandler(app, c)
+	})
 
 	// Healthcheck route.
 	router.GET("/health", func(c *gin.Context) {
@@ -31,6 +36,41 @@ func registerRoutes(router *gin.Engine, app *App) {
 		editProjectHandler(app, c)
 	})
 
+	// Dead letter routes.
+	router.GET("/dead-letters", func(c *gin.Context) {
+		deadLettersHandler(app, c)
+	})
+	router.POST("/retry-dead-letter", func(c *gin.Context) {
+		retryDeadLetterHandler(app, c)
+	})
+	router.DELETE("/ignore-dead-letter", func(c *gin.Context) {
+		ignoreDeadLetterHandler(app, c)
+	})
+
+	// Adoption review routes.
+	router.GET("/adoption-reviews", func(c *gin.Context) {
+		adoptionReviewsHandler(app, c)
+	})
+	router.POST("/decide-adoption", func(c *gin.Context) {
+		decideAdoptionHandler(app, c)
+	})
+
+	// Conflict routes.
+	router.GET("/conflicts", func(c *gin.Context) {
+		conflictsHandler(app, c)
+	})
+	router.POST("/resolve-conflict", func(c *gin.Context) {
+		resolveConflictHandler(app, c)
+	})
+
+	// Reconciliation routes.
+	router.GET("/reconciliations", func(c *gin.Context) {
+		reconciliationsHandler(app, c)
+	})
+	router.POST("/request-reconciliation", func(c *gin.Context) {
+		requestReconciliationHandler(app, c)
+	})
+
 	// API routes for project selection.
 	router.GET("/ado-projects", func(c *gin.Context) {
 		adoProjectsHandler(app, c)
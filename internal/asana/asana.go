@@ -32,20 +32,51 @@ type AsanaInterface interface {
 	// the task description.
 	CreateTask(ctx context.Context, projectGID, name, notes string) (Task, error)
 	// UpdateTask updates an existing task. The notes parameter should
-	// contain HTML wrapped in a <body> element for the description.
+	// contain HTML wrapped in a <body> element for the description. An
+	// empty name or notes is left unchanged.
 	UpdateTask(ctx context.Context, taskGID, name, notes string) error
-	// CreateTaskWithCustomFields creates a task with additional custom fields.
+	// CreateTaskWithCustomFields creates a task with additional custom
+	// fields.
 	CreateTaskWithCustomFields(ctx context.Context, projectGID, name, notes string, customFields map[string]string) (Task, error)
+	// TaskByGID returns the name, HTML notes and modification time of a task.
+	TaskByGID(ctx context.Context, taskGID string) (Task, error)
 	// UpdateTaskWithCustomFields updates a task and sets custom field values.
+	// An empty name or notes is left unchanged.
 	UpdateTaskWithCustomFields(ctx context.Context, taskGID, name, notes string, customFields map[string]string) error
 	// TagByName returns the tag with the specified name in the workspace.
 	TagByName(ctx context.Context, workspaceName, tagName string) (Tag, error)
 	// AddTagToTask adds a tag to the specified task.
 	AddTagToTask(ctx context.Context, taskGID, tagGID string) error
+	// CreateProject creates a project, optionally from a project template.
+	CreateProject(ctx context.Context, p NewProject) (Project, error)
+	// UpdateProject updates the name and HTML description of a project.
+	UpdateProject(ctx context.Context, projectGID, name, notes string) error
+	// AddProjectToPortfolio adds a project to the given portfolio.
+	AddProjectToPortfolio(ctx context.Context, portfolioGID, projectGID string) error
+	// CreateProjectStatus posts a status update on the given project.
+	CreateProjectStatus(ctx context.Context, projectGID string, status ProjectStatus) error
+	// AddStoryToTask posts an HTML comment story on the given task.
+	AddStoryToTask(ctx context.Context, taskGID, text string) error
+	// CreateWebhook subscribes target to the task events of a resource.
+	CreateWebhook(ctx context.Context, resourceGID, target string) (Webhook, error)
+	// DeleteWebhook removes a webhook.
+	DeleteWebhook(ctx context.Context, webhookGID string) error
+	// Events returns the events of a resource since a sync token.
+	Events(ctx context.Context, resourceGID, sync string) (EventPage, error)
+	// CurrentUser returns the user the client is authenticated as.
+	CurrentUser(ctx context.Context) (User, error)
 }
 
 type Asana struct {
 	Client *http.Client
+	// Transport, when set before Connect, carries the authenticated
+	// requests instead of http.DefaultTransport.
+	Transport http.RoundTripper
+	// CacheTTL is how long workspace IDs are cached. Zero disables the
+	// cache.
+	CacheTTL time.Duration
+
+	workspaces workspaceCache
 }
 
 func (a *Asana) Connect(ctx context.Context, pat string) {
@@ -55,6 +86,10 @@ func (a *Asana) Connect(ctx context.Context, pat string) {
 	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
+	if a.Transport != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: a.Transport})
+	}
+
 	tok := &oauth2.Token{AccessToken: pat}
 	conf := &oauth2.Config{}
 	a.Client = conf.Client(ctx, tok)
@@ -0,0 +1,25 @@
+package asana
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectUsesTransport(t *testing.T) {
+	var got *http.Request
+	a := &Asana{Transport: testutil.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got = req
+		return jsonResponse(http.StatusOK, `{"data":[]}`), nil
+	})}
+	a.Connect(context.Background(), "pat")
+
+	_, err := a.ListWorkspaces(context.Background())
+
+	require.NoError(t, err)
+	require.NotNil(t, got, "request should go through the transport")
+	require.Equal(t, "Bearer pat", got.Header.Get("Authorization"))
+}
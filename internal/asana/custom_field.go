@@ -2,15 +2,11 @@ package asana
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
 	"strings"
 
 	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
-	asanaapi "github.com/qw4n7y/go-asana/asana"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -26,32 +22,15 @@ func (a *Asana) CustomFieldByName(ctx context.Context, workspaceName, fieldName
 	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.CustomFieldByName")
 	defer span.End()
 
-	workspaces, err := a.ListWorkspaces(ctx)
+	wsID, err := a.workspaceID(ctx, workspaceName)
 	if err != nil {
-		return CustomField{}, err
-	}
-	var wsID int64
-	found := false
-	for _, ws := range workspaces {
-		if ws.Name == workspaceName {
-			wsID = ws.ID
-			found = true
-			break
-		}
-	}
-	if !found {
-		err := fmt.Errorf("workspace not found")
 		span.SetStatus(codes.Error, err.Error())
 		return CustomField{}, err
 	}
 
-	client := asanaapi.NewClient(a.Client)
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-
-	var fields []CustomField
-
-	if err := client.Request(ctx, fmt.Sprintf("workspaces/%d/custom_fields", wsID), nil, &fields); err != nil {
+	fields, err := listAll[CustomField](ctx, a, fmt.Sprintf("workspaces/%d/custom_fields", wsID), nil, "gid", "name")
+	if err != nil {
+		a.forgetWorkspaces(err)
 		span.RecordError(err, trace.WithStackTrace(true))
 		span.SetStatus(codes.Error, err.Error())
 		return CustomField{}, err
@@ -62,7 +41,7 @@ func (a *Asana) CustomFieldByName(ctx context.Context, workspaceName, fieldName
 			return f, nil
 		}
 	}
-	err = fmt.Errorf("custom field not found")
+	err = notFound("custom field", fieldName)
 	span.SetStatus(codes.Error, err.Error())
 	return CustomField{}, err
 }
@@ -73,16 +52,8 @@ func (a *Asana) ProjectHasCustomField(ctx context.Context, projectGID, fieldName
 	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.ProjectHasCustomField")
 	defer span.End()
 
-	client := asanaapi.NewClient(a.Client)
-
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-
-	var settings []struct {
-		CustomField CustomField `json:"custom_field"`
-	}
-
-	if err := client.Request(ctx, fmt.Sprintf("projects/%s/custom_field_settings", projectGID), nil, &settings); err != nil {
+	settings, err := a.customFieldSettings(ctx, projectGID)
+	if err != nil {
 		span.RecordError(err, trace.WithStackTrace(true))
 		span.SetStatus(codes.Error, err.Error())
 		return false, err
@@ -102,56 +73,34 @@ func (a *Asana) ProjectCustomFieldByName(ctx context.Context, projectGID, fieldN
 	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.ProjectCustomFieldByName")
 	defer span.End()
 
-	client := asanaapi.NewClient(a.Client)
-
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-
-	u := client.BaseURL.ResolveReference(&url.URL{Path: fmt.Sprintf("projects/%s/custom_field_settings", projectGID)})
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
-	if err != nil {
-		span.RecordError(err, trace.WithStackTrace(true))
-		span.SetStatus(codes.Error, err.Error())
-		return CustomField{}, err
+	settings, err := a.customFieldSettings(ctx, projectGID)
+	if errors.Is(err, ErrPremiumRequired) {
+		return CustomField{}, fmt.Errorf("custom fields unavailable: %w", err)
 	}
-
-	resp, err := a.Client.Do(req)
 	if err != nil {
 		span.RecordError(err, trace.WithStackTrace(true))
 		span.SetStatus(codes.Error, err.Error())
 		return CustomField{}, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusPaymentRequired {
-		return CustomField{}, fmt.Errorf("custom fields unavailable")
-	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		err := fmt.Errorf("asana request failed: %s", string(body))
-		span.RecordError(err, trace.WithStackTrace(true))
-		span.SetStatus(codes.Error, err.Error())
-		return CustomField{}, err
-	}
-
-	var payload struct {
-		Data []struct {
-			CustomField CustomField `json:"custom_field"`
-		} `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-		span.RecordError(err, trace.WithStackTrace(true))
-		span.SetStatus(codes.Error, err.Error())
-		return CustomField{}, err
-	}
 
 	lname := strings.ToLower(fieldName)
-	for _, s := range payload.Data {
+	for _, s := range settings {
 		if strings.ToLower(s.CustomField.Name) == lname {
 			return s.CustomField, nil
 		}
 	}
-	err = fmt.Errorf("custom field not found")
+	err = notFound("custom field", fieldName)
 	span.SetStatus(codes.Error, err.Error())
 	return CustomField{}, err
 }
+
+// customFieldSetting links a custom field to a project.
+type customFieldSetting struct {
+	CustomField CustomField `json:"custom_field"`
+}
+
+// customFieldSettings returns every custom field setting on the project.
+func (a *Asana) customFieldSettings(ctx context.Context, projectGID string) ([]customFieldSetting, error) {
+	path := fmt.Sprintf("projects/%s/custom_field_settings", projectGID)
+	return listAll[customFieldSetting](ctx, a, path, nil, "custom_field.gid", "custom_field.name")
+}
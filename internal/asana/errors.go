@@ -0,0 +1,101 @@
+package asana
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	asanaapi "github.com/qw4n7y/go-asana/asana"
+)
+
+// Errors returned by the client, usable with errors.Is. Failed API calls
+// return an *APIError that matches the sentinel for its status code.
+var (
+	// ErrNotFound reports a missing workspace, project, task, tag or custom
+	// field, or an object the token cannot see.
+	ErrNotFound = errors.New("not found")
+	// ErrUnauthorized reports a rejected token (401) or an object the token
+	// may not modify (403).
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrRateLimited reports requests Asana throttled (429).
+	ErrRateLimited = errors.New("rate limited")
+	// ErrPremiumRequired reports a feature, such as custom fields, that the
+	// workspace's plan does not include (402).
+	ErrPremiumRequired = errors.New("premium required")
+	// ErrValidation reports a request Asana rejected as invalid (400). The
+	// *APIError carries the messages Asana returned.
+	ErrValidation = errors.New("invalid request")
+	// ErrSyncExpired reports an events sync token that is missing or too
+	// old (412). Events since the token was issued are lost.
+	ErrSyncExpired = errors.New("sync token expired")
+)
+
+// APIError is returned when Asana responds with a non-2xx status.
+type APIError struct {
+	StatusCode int
+	// Errors is the error payload returned by Asana, when the body could be
+	// decoded.
+	Errors asanaapi.Errors
+	Body   string
+	// RetryAfter is how long Asana asked to wait before retrying a
+	// throttled request, zero when it did not say.
+	RetryAfter time.Duration
+}
+
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	var payload struct {
+		Errors asanaapi.Errors `json:"errors"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		e.Errors = payload.Errors
+	}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		e.RetryAfter = time.Duration(secs) * time.Second
+	}
+	return e
+}
+
+// RetryAfter returns how long Asana asked to wait before retrying the
+// request that failed with err, zero when it did not say.
+func RetryAfter(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+func (e *APIError) Error() string {
+	if len(e.Errors) > 0 {
+		return fmt.Sprintf("asana request failed (%d): %s", e.StatusCode, e.Errors.Error())
+	}
+	return fmt.Sprintf("asana request failed (%d): %s", e.StatusCode, e.Body)
+}
+
+// Is matches the sentinel error for the status code.
+func (e *APIError) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return target == ErrValidation
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return target == ErrUnauthorized
+	case http.StatusPaymentRequired:
+		return target == ErrPremiumRequired
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusPreconditionFailed:
+		return target == ErrSyncExpired
+	case http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	}
+	return false
+}
+
+// notFound returns an error matching ErrNotFound for the named object.
+func notFound(kind, name string) error {
+	return fmt.Errorf("%s %q %w", kind, name, ErrNotFound)
+}
@@ -0,0 +1,99 @@
+package asana
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIErrorMatchesSentinel(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusBadRequest, ErrValidation},
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrUnauthorized},
+		{http.StatusPaymentRequired, ErrPremiumRequired},
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusPreconditionFailed, ErrSyncExpired},
+		{http.StatusTooManyRequests, ErrRateLimited},
+	}
+	sentinels := []error{ErrValidation, ErrUnauthorized, ErrPremiumRequired, ErrNotFound, ErrSyncExpired, ErrRateLimited}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			a := &Asana{Client: testutil.NewTestClient(jsonResponse(tt.status, `{"errors":[{"message":"nope"}]}`), nil)}
+			err := a.UpdateTask(context.Background(), "1", "name", "notes")
+			for _, s := range sentinels {
+				require.Equal(t, s == tt.want, errors.Is(err, s), "sentinel %v", s)
+			}
+		})
+	}
+}
+
+func TestAPIErrorCarriesPayload(t *testing.T) {
+	body := `{"errors":[{"message":"name: Value cannot be longer than 1024 characters","phrase":"6 sad squid"}]}`
+	a := &Asana{Client: testutil.NewTestClient(jsonResponse(http.StatusBadRequest, body), nil)}
+
+	_, err := a.CreateTask(context.Background(), "42", "name", "notes")
+
+	require.ErrorIs(t, err, ErrValidation)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	require.Len(t, apiErr.Errors, 1)
+	require.Equal(t, "name: Value cannot be longer than 1024 characters", apiErr.Errors[0].Message)
+	require.Contains(t, err.Error(), "cannot be longer")
+}
+
+func TestAPIErrorUndecodableBody(t *testing.T) {
+	a := &Asana{Client: testutil.NewTestClient(jsonResponse(http.StatusTooManyRequests, "slow down"), nil)}
+
+	err := a.AddTagToTask(context.Background(), "1", "2")
+
+	require.ErrorIs(t, err, ErrRateLimited)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Empty(t, apiErr.Errors)
+	require.Contains(t, err.Error(), "slow down")
+}
+
+func TestLookupsReturnNotFound(t *testing.T) {
+	a := &Asana{Client: pagedClient(map[string]string{
+		"": `{"data":[{"gid":"1","name":"Workspace"}]}`,
+	}, new([]*http.Request))}
+
+	_, err := a.ListProjects(context.Background(), "Missing")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.EqualError(t, err, `workspace "Missing" not found`)
+
+	_, err = a.TagByName(context.Background(), "Missing", "synced")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestProjectCustomFieldByNamePremiumRequired(t *testing.T) {
+	a := &Asana{Client: testutil.NewTestClient(jsonResponse(http.StatusPaymentRequired, `{"errors":[{"message":"premium"}]}`), nil)}
+
+	_, err := a.ProjectCustomFieldByName(context.Background(), "1", "link")
+
+	require.ErrorIs(t, err, ErrPremiumRequired)
+	require.ErrorContains(t, err, "custom fields unavailable")
+}
+
+func TestAPIErrorRetryAfter(t *testing.T) {
+	resp := jsonResponse(http.StatusTooManyRequests, `{"errors":[{"message":"slow down"}]}`)
+	resp.Header.Set("Retry-After", "30")
+	a := &Asana{Client: testutil.NewTestClient(resp, nil)}
+
+	err := a.AddTagToTask(context.Background(), "1", "2")
+
+	require.ErrorIs(t, err, ErrRateLimited)
+	require.Equal(t, 30*time.Second, RetryAfter(err))
+	require.Zero(t, RetryAfter(errors.New("other")))
+}
@@ -0,0 +1,81 @@
+package asana
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
+	asanaapi "github.com/qw4n7y/go-asana/asana"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// EventPage is a batch of events read from the events endpoint.
+type EventPage struct {
+	Events []Event
+	// Sync is the token to read the events following this page with.
+	Sync string
+	// HasMore reports whether more events are waiting already.
+	HasMore bool
+}
+
+// Events returns the events of the resource since the sync token was
+// issued, at most 100 per page. Without a token, or with one Asana no longer
+// accepts, it returns an error matching ErrSyncExpired and a page carrying
+// only a fresh token: events before it are lost.
+func (a *Asana) Events(ctx context.Context, resourceGID, sync string) (EventPage, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.Events")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("resource_gid", resourceGID), attribute.Bool("has_sync", sync != ""))
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	page, err := a.events(ctx, resourceGID, sync)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return page, err
+	}
+	span.SetAttributes(attribute.Int("events", len(page.Events)))
+	return page, nil
+}
+
+func (a *Asana) events(ctx context.Context, resourceGID, sync string) (EventPage, error) {
+	query := url.Values{"resource": {resourceGID}}
+	if sync != "" {
+		query.Set("sync", sync)
+	}
+	req, err := a.newRequest(ctx, http.MethodGet, "events", query, nil)
+	if err != nil {
+		return EventPage{}, err
+	}
+
+	var payload struct {
+		Data    []Event         `json:"data"`
+		Sync    string          `json:"sync"`
+		HasMore bool            `json:"has_more"`
+		Errors  asanaapi.Errors `json:"errors"`
+	}
+	b, err := a.send(req)
+	if err != nil {
+		// Expired tokens are answered with a fresh one next to the errors.
+		var apiErr *APIError
+		if errors.Is(err, ErrSyncExpired) && errors.As(err, &apiErr) {
+			_ = json.Unmarshal([]byte(apiErr.Body), &payload)
+		}
+		return EventPage{Sync: payload.Sync}, err
+	}
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return EventPage{}, err
+	}
+	if len(payload.Errors) > 0 {
+		return EventPage{}, payload.Errors
+	}
+	return EventPage{Events: payload.Data, Sync: payload.Sync, HasMore: payload.HasMore}, nil
+}
@@ -0,0 +1,53 @@
+package asana
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsanaEvents(t *testing.T) {
+	var req *http.Request
+	body := `{"data":[{"action":"changed","resource":{"gid":"t1","resource_type":"task"}}],"sync":"next","has_more":true}`
+	a := &Asana{Client: testutil.NewTestClientWithRequest(jsonResponse(http.StatusOK, body), nil, &req)}
+
+	page, err := a.Events(context.Background(), "p1", "tok")
+
+	require.NoError(t, err)
+	require.Equal(t, "/api/1.0/events", req.URL.Path)
+	require.Equal(t, "p1", req.URL.Query().Get("resource"))
+	require.Equal(t, "tok", req.URL.Query().Get("sync"))
+	require.Equal(t, "next", page.Sync)
+	require.True(t, page.HasMore)
+	require.Len(t, page.Events, 1)
+	require.Equal(t, "t1", page.Events[0].TaskGID())
+}
+
+func TestAsanaEventsSyncExpired(t *testing.T) {
+	var req *http.Request
+	body := `{"errors":[{"message":"Sync token invalid or too old."}],"sync":"fresh"}`
+	a := &Asana{Client: testutil.NewTestClientWithRequest(jsonResponse(http.StatusPreconditionFailed, body), nil, &req)}
+
+	page, err := a.Events(context.Background(), "p1", "")
+
+	require.ErrorIs(t, err, ErrSyncExpired)
+	require.Equal(t, "fresh", page.Sync)
+	require.Empty(t, page.Events)
+	require.False(t, req.URL.Query().Has("sync"))
+}
+
+func TestAsanaEventsRateLimited(t *testing.T) {
+	resp := jsonResponse(http.StatusTooManyRequests, `{"errors":[{"message":"Rate limited."}]}`)
+	resp.Header.Set("Retry-After", "30")
+	a := &Asana{Client: testutil.NewTestClient(resp, nil)}
+
+	page, err := a.Events(context.Background(), "p1", "tok")
+
+	require.ErrorIs(t, err, ErrRateLimited)
+	require.Equal(t, 30*time.Second, RetryAfter(err))
+	require.Empty(t, page.Sync, "the token is kept")
+}
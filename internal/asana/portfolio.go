@@ -0,0 +1,175 @@
+package asana
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Project status types accepted by the Asana status updates API.
+const (
+	StatusOnTrack  = "on_track"
+	StatusAtRisk   = "at_risk"
+	StatusOffTrack = "off_track"
+	StatusOnHold   = "on_hold"
+	StatusComplete = "complete"
+)
+
+// NewProject describes a project to create in a workspace. When TemplateGID
+// is set the project is instantiated from that project template.
+type NewProject struct {
+	WorkspaceName string
+	TeamGID       string
+	TemplateGID   string
+	Name          string
+	Notes         string
+}
+
+// ProjectStatus is a status update posted on a project.
+type ProjectStatus struct {
+	Type  string
+	Title string
+	Text  string
+}
+
+// CreateProject creates a new project, optionally from a project template.
+func (a *Asana) CreateProject(ctx context.Context, p NewProject) (Project, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.CreateProject")
+	defer span.End()
+
+	var (
+		result Project
+		err    error
+	)
+	if p.TemplateGID != "" {
+		result, err = a.instantiateProjectTemplate(ctx, p)
+	} else {
+		result, err = a.createBlankProject(ctx, p)
+	}
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return Project{}, err
+	}
+
+	// Templates do not accept notes, so set them once the project exists.
+	// The project is returned even if this fails so the caller can record
+	// it; the notes are set again on the next update.
+	if p.TemplateGID != "" && p.Notes != "" {
+		if err := a.UpdateProject(ctx, result.GID, p.Name, p.Notes); err != nil {
+			span.RecordError(err)
+			logrus.WithError(err).WithField("project", result.GID).Warn("failed to set notes on project created from template")
+		}
+	}
+	return result, nil
+}
+
+func (a *Asana) instantiateProjectTemplate(ctx context.Context, p NewProject) (Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	data := map[string]interface{}{"name": p.Name}
+	if p.TeamGID != "" {
+		data["team"] = p.TeamGID
+	}
+	var job struct {
+		NewProject Project `json:"new_project"`
+	}
+	path := fmt.Sprintf("project_templates/%s/instantiateProject", p.TemplateGID)
+	if err := a.doJSON(ctx, http.MethodPost, path, data, &job); err != nil {
+		return Project{}, err
+	}
+	if job.NewProject.GID == "" {
+		return Project{}, fmt.Errorf("project template did not return a project")
+	}
+	return job.NewProject, nil
+}
+
+func (a *Asana) createBlankProject(ctx context.Context, p NewProject) (Project, error) {
+	wsID, err := a.workspaceID(ctx, p.WorkspaceName)
+	if err != nil {
+		return Project{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	data := map[string]interface{}{
+		"name":       p.Name,
+		"workspace":  fmt.Sprint(wsID),
+		"html_notes": ensureHTMLBody(p.Notes),
+	}
+	if p.TeamGID != "" {
+		data["team"] = p.TeamGID
+	}
+	var result Project
+	if err := a.doJSON(ctx, http.MethodPost, "projects", data, &result); err != nil {
+		a.forgetWorkspaces(err)
+		return Project{}, err
+	}
+	return result, nil
+}
+
+// UpdateProject updates the name and HTML description of a project.
+func (a *Asana) UpdateProject(ctx context.Context, projectGID, name, notes string) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.UpdateProject")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	data := map[string]string{
+		"name":       name,
+		"html_notes": ensureHTMLBody(notes),
+	}
+	if err := a.doJSON(ctx, http.MethodPut, fmt.Sprintf("projects/%s", projectGID), data, nil); err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
+
+// AddProjectToPortfolio adds the project to the given portfolio.
+func (a *Asana) AddProjectToPortfolio(ctx context.Context, portfolioGID, projectGID string) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.AddProjectToPortfolio")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	data := map[string]string{"item": projectGID}
+	if err := a.doJSON(ctx, http.MethodPost, fmt.Sprintf("portfolios/%s/addItem", portfolioGID), data, nil); err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
+
+// CreateProjectStatus posts a status update on the given project.
+func (a *Asana) CreateProjectStatus(ctx context.Context, projectGID string, status ProjectStatus) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.CreateProjectStatus")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	data := map[string]string{
+		"parent":      projectGID,
+		"status_type": status.Type,
+		"title":       status.Title,
+		"text":        status.Text,
+	}
+	if err := a.doJSON(ctx, http.MethodPost, "status_updates", data, nil); err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
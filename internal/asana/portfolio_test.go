@@ -0,0 +1,130 @@
+package asana
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/testutil"
+	asanaapi "github.com/qw4n7y/go-asana/asana"
+	"github.com/stretchr/testify/require"
+)
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}
+}
+
+func TestAsanaCreateProjectBlank(t *testing.T) {
+	var reqs []*http.Request
+	var bodies []string
+	client := &http.Client{Transport: testutil.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		reqs = append(reqs, req)
+		if req.Body != nil {
+			b, _ := io.ReadAll(req.Body)
+			bodies = append(bodies, string(b))
+		} else {
+			bodies = append(bodies, "")
+		}
+		if len(reqs) == 1 {
+			return createWorkspaceResponse([]asanaapi.Workspace{{ID: 7, Name: "Acme"}}, nil), nil
+		}
+		return jsonResponse(http.StatusCreated, `{"data":{"gid":"p1","name":"Epic 1: Launch"}}`), nil
+	})}
+	a := &Asana{Client: client}
+
+	got, err := a.CreateProject(context.Background(), NewProject{WorkspaceName: "Acme", TeamGID: "t1", Name: "Epic 1: Launch", Notes: "notes"})
+	require.NoError(t, err)
+	require.Equal(t, Project{GID: "p1", Name: "Epic 1: Launch"}, got)
+	require.Len(t, reqs, 2)
+	require.Equal(t, http.MethodPost, reqs[1].Method)
+	require.True(t, strings.HasSuffix(reqs[1].URL.Path, "/projects"))
+
+	var payload struct {
+		Data map[string]string `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(bodies[1]), &payload))
+	require.Equal(t, "7", payload.Data["workspace"])
+	require.Equal(t, "t1", payload.Data["team"])
+	require.Equal(t, "<body>notes</body>", payload.Data["html_notes"])
+}
+
+func TestAsanaCreateProjectFromTemplate(t *testing.T) {
+	var paths []string
+	client := &http.Client{Transport: testutil.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		paths = append(paths, req.Method+" "+req.URL.Path)
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusCreated, `{"data":{"gid":"job1","new_project":{"gid":"p2","name":"Feature 2"}}}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"data":{}}`), nil
+	})}
+	a := &Asana{Client: client}
+
+	got, err := a.CreateProject(context.Background(), NewProject{TemplateGID: "tpl", Name: "Feature 2", Notes: "notes"})
+	require.NoError(t, err)
+	require.Equal(t, "p2", got.GID)
+	require.Len(t, paths, 2)
+	require.True(t, strings.HasSuffix(paths[0], "/project_templates/tpl/instantiateProject"))
+	require.True(t, strings.HasSuffix(paths[1], "/projects/p2"))
+}
+
+func TestAsanaCreateProjectFromTemplateNotesFailure(t *testing.T) {
+	client := &http.Client{Transport: testutil.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPost {
+			return jsonResponse(http.StatusCreated, `{"data":{"gid":"job1","new_project":{"gid":"p2","name":"Feature 2"}}}`), nil
+		}
+		return jsonResponse(http.StatusInternalServerError, "boom"), nil
+	})}
+	a := &Asana{Client: client}
+
+	got, err := a.CreateProject(context.Background(), NewProject{TemplateGID: "tpl", Name: "Feature 2", Notes: "notes"})
+	require.NoError(t, err, "the project exists, so it must be returned")
+	require.Equal(t, "p2", got.GID)
+}
+
+func TestAsanaCreateProjectWorkspaceNotFound(t *testing.T) {
+	client := testutil.NewTestClient(createWorkspaceResponse([]asanaapi.Workspace{}, nil), nil)
+	a := &Asana{Client: client}
+	_, err := a.CreateProject(context.Background(), NewProject{WorkspaceName: "Missing", Name: "x"})
+	require.Error(t, err)
+}
+
+func TestAsanaAddProjectToPortfolio(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    *http.Response
+		wantErr bool
+	}{
+		{name: "success", resp: jsonResponse(http.StatusOK, `{"data":{}}`)},
+		{name: "http error", resp: jsonResponse(http.StatusForbidden, "nope"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *http.Request
+			a := &Asana{Client: testutil.NewTestClientWithRequest(tt.resp, nil, &req)}
+			err := a.AddProjectToPortfolio(context.Background(), "pf1", "p1")
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, strings.HasSuffix(req.URL.Path, "/portfolios/pf1/addItem"))
+		})
+	}
+}
+
+func TestAsanaCreateProjectStatus(t *testing.T) {
+	var req *http.Request
+	a := &Asana{Client: testutil.NewTestClientWithRequest(jsonResponse(http.StatusCreated, `{"data":{}}`), nil, &req)}
+	err := a.CreateProjectStatus(context.Background(), "p1", ProjectStatus{Type: StatusComplete, Title: "Closed", Text: "done"})
+	require.NoError(t, err)
+	body, _ := io.ReadAll(req.Body)
+	var payload struct {
+		Data map[string]string `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &payload))
+	require.Equal(t, "p1", payload.Data["parent"])
+	require.Equal(t, StatusComplete, payload.Data["status_type"])
+}
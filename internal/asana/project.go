@@ -3,6 +3,7 @@ package asana
 import (
 	"context"
 	"fmt"
+	"net/url"
 
 	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
 	asanaapi "github.com/qw4n7y/go-asana/asana"
@@ -19,40 +20,16 @@ func (a *Asana) ProjectGIDByName(ctx context.Context, workspaceName, projectName
 	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.ProjectGIDByName")
 	defer span.End()
 
-	workspaces, err := a.ListWorkspaces(ctx)
-	if err != nil {
-		return "", err
-	}
-	var wsID int64
-	found := false
-	for _, ws := range workspaces {
-		if ws.Name == workspaceName {
-			wsID = ws.ID
-			found = true
-			break
-		}
-	}
-	if !found {
-		return "", fmt.Errorf("workspace not found")
-	}
-
-	client := asanaapi.NewClient(a.Client)
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-
-	projs, err := client.ListProjects(ctx, &asanaapi.Filter{Workspace: wsID})
+	projs, err := a.ListProjects(ctx, workspaceName)
 	if err != nil {
 		return "", err
 	}
 	for _, p := range projs {
 		if p.Name == projectName {
-			if p.GID != "" {
-				return p.GID, nil
-			}
-			return fmt.Sprint(p.ID), nil
+			return p.GID, nil
 		}
 	}
-	return "", fmt.Errorf("project not found")
+	return "", notFound("project", projectName)
 }
 
 // ListProjects returns all projects within the given workspace.
@@ -60,29 +37,15 @@ func (a *Asana) ListProjects(ctx context.Context, workspaceName string) ([]Proje
 	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.ListProjects")
 	defer span.End()
 
-	workspaces, err := a.ListWorkspaces(ctx)
+	wsID, err := a.workspaceID(ctx, workspaceName)
 	if err != nil {
 		return nil, err
 	}
-	var wsID int64
-	found := false
-	for _, ws := range workspaces {
-		if ws.Name == workspaceName {
-			wsID = ws.ID
-			found = true
-			break
-		}
-	}
-	if !found {
-		return nil, fmt.Errorf("workspace not found")
-	}
-
-	client := asanaapi.NewClient(a.Client)
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
 
-	projs, err := client.ListProjects(ctx, &asanaapi.Filter{Workspace: wsID})
+	params := url.Values{"workspace": {fmt.Sprint(wsID)}}
+	projs, err := listAll[asanaapi.Project](ctx, a, "projects", params, "gid", "name")
 	if err != nil {
+		a.forgetWorkspaces(err)
 		return nil, err
 	}
 	var result []Project
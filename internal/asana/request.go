@@ -0,0 +1,146 @@
+package asana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	asanaapi "github.com/qw4n7y/go-asana/asana"
+)
+
+// pageLimit is the number of items requested per page from list endpoints,
+// the maximum Asana allows.
+var pageLimit = 100
+
+// doJSON sends a request to the Asana API wrapping data in the standard
+// {"data": ...} envelope. When out is not nil the "data" member of the
+// response is decoded into it.
+func (a *Asana) doJSON(ctx context.Context, method, path string, data interface{}, out interface{}) error {
+	var body io.Reader
+	if data != nil {
+		b, err := json.Marshal(map[string]interface{}{"data": data})
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := a.newRequest(ctx, method, path, nil, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return a.do(req, out)
+}
+
+// newRequest builds a request for the path relative to the Asana API, with
+// query as its query string when not nil.
+func (a *Asana) newRequest(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Request, error) {
+	client := asanaapi.NewClient(a.Client)
+	u := client.BaseURL.ResolveReference(&url.URL{Path: path, RawQuery: query.Encode()})
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+// do sends req and decodes the "data" member of the response into out when
+// it is not nil. Non-2xx responses are returned as an *APIError.
+func (a *Asana) do(req *http.Request, out interface{}) error {
+	_, err := a.doPage(req, out)
+	return err
+}
+
+// doPage sends req like do and returns the offset of the next page of a list
+// response, empty on the last page.
+func (a *Asana) doPage(req *http.Request, out interface{}) (string, error) {
+	b, err := a.send(req)
+	if err != nil {
+		return "", err
+	}
+	if out == nil {
+		return "", nil
+	}
+
+	payload := struct {
+		Data     interface{} `json:"data"`
+		NextPage *struct {
+			Offset string `json:"offset"`
+		} `json:"next_page"`
+		Errors asanaapi.Errors `json:"errors"`
+	}{Data: out}
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return "", err
+	}
+	if len(payload.Errors) > 0 {
+		return "", payload.Errors
+	}
+	if payload.NextPage == nil {
+		return "", nil
+	}
+	return payload.NextPage.Offset, nil
+}
+
+// send sends req and returns the body of the response. Non-2xx responses
+// are returned as an *APIError.
+func (a *Asana) send(req *http.Request) ([]byte, error) {
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, newAPIError(resp, b)
+	}
+	return b, nil
+}
+
+// listAll fetches every page of an Asana list endpoint, following the
+// next_page offset until it is exhausted. params are sent on every request
+// and optFields, when given, limits the fields returned for each item. Each
+// page gets its own timeout, so long lists are not cut short.
+func listAll[T any](ctx context.Context, a *Asana, path string, params url.Values, optFields ...string) ([]T, error) {
+	query := url.Values{}
+	for k, v := range params {
+		query[k] = v
+	}
+	query.Set("limit", strconv.Itoa(pageLimit))
+	if len(optFields) > 0 {
+		query.Set("opt_fields", strings.Join(optFields, ","))
+	}
+
+	var items []T
+	for {
+		var page []T
+		offset, err := a.listPage(ctx, path, query, &page)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+		if offset == "" {
+			return items, nil
+		}
+		query.Set("offset", offset)
+	}
+}
+
+// listPage fetches a single page of a list endpoint into out and returns the
+// offset of the next page.
+func (a *Asana) listPage(ctx context.Context, path string, query url.Values, out interface{}) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := a.newRequest(ctx, http.MethodGet, path, query, nil)
+	if err != nil {
+		return "", err
+	}
+	return a.doPage(req, out)
+}
@@ -0,0 +1,130 @@
+package asana
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// pagedClient serves pages keyed by the offset query parameter, with the
+// first page served when no offset is sent. The requests received are
+// returned through reqs.
+func pagedClient(pages map[string]string, reqs *[]*http.Request) *http.Client {
+	return &http.Client{Transport: testutil.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		*reqs = append(*reqs, req)
+		body, ok := pages[req.URL.Query().Get("offset")]
+		if !ok {
+			return jsonResponse(http.StatusBadRequest, `{"errors":[{"message":"bad offset"}]}`), nil
+		}
+		return jsonResponse(http.StatusOK, body), nil
+	})}
+}
+
+func TestListAllFollowsOffsets(t *testing.T) {
+	var reqs []*http.Request
+	a := &Asana{Client: pagedClient(map[string]string{
+		"":    `{"data":[{"gid":"1","name":"one"},{"gid":"2","name":"two"}],"next_page":{"offset":"abc"}}`,
+		"abc": `{"data":[{"gid":"3","name":"three"}],"next_page":{"offset":"def"}}`,
+		"def": `{"data":[{"gid":"4","name":"four"}],"next_page":null}`,
+	}, &reqs)}
+
+	got, err := listAll[Tag](context.Background(), a, "tags", map[string][]string{"workspace": {"9"}}, "gid", "name")
+
+	require.NoError(t, err)
+	require.Equal(t, []Tag{{"1", "one"}, {"2", "two"}, {"3", "three"}, {"4", "four"}}, got)
+	require.Len(t, reqs, 3)
+	for _, req := range reqs {
+		q := req.URL.Query()
+		require.Equal(t, "/api/1.0/tags", req.URL.Path)
+		require.Equal(t, "9", q.Get("workspace"))
+		require.Equal(t, fmt.Sprint(pageLimit), q.Get("limit"))
+		require.Equal(t, "gid,name", q.Get("opt_fields"))
+	}
+	require.Equal(t, "def", reqs[2].URL.Query().Get("offset"))
+}
+
+func TestListAllErrorOnLaterPage(t *testing.T) {
+	var reqs []*http.Request
+	a := &Asana{Client: pagedClient(map[string]string{
+		"": `{"data":[{"gid":"1","name":"one"}],"next_page":{"offset":"missing"}}`,
+	}, &reqs)}
+
+	got, err := listAll[Tag](context.Background(), a, "tags", nil)
+
+	require.ErrorIs(t, err, ErrValidation)
+	require.Nil(t, got)
+}
+
+func TestListAllTimeoutPerPage(t *testing.T) {
+	defer func(d time.Duration) { timeout = d }(timeout)
+	timeout = 50 * time.Millisecond
+
+	pages := map[string]string{
+		"":   `{"data":[{"gid":"1","name":"one"}],"next_page":{"offset":"p2"}}`,
+		"p2": `{"data":[{"gid":"2","name":"two"}],"next_page":{"offset":"p3"}}`,
+		"p3": `{"data":[{"gid":"3","name":"three"}]}`,
+	}
+	client := &http.Client{Transport: testutil.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		// Together the pages take longer than a single timeout.
+		time.Sleep(30 * time.Millisecond)
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		return jsonResponse(http.StatusOK, pages[req.URL.Query().Get("offset")]), nil
+	})}
+	a := &Asana{Client: client}
+
+	got, err := listAll[Tag](context.Background(), a, "tags", nil)
+
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+}
+
+func TestListAllErrorsPayload(t *testing.T) {
+	client := testutil.NewTestClient(jsonResponse(http.StatusOK, `{"errors":[{"message":"Invalid token"}]}`), nil)
+	a := &Asana{Client: client}
+
+	_, err := listAll[Tag](context.Background(), a, "tags", nil)
+
+	require.ErrorContains(t, err, "Invalid token")
+}
+
+func TestListProjectTasksPaged(t *testing.T) {
+	var reqs []*http.Request
+	a := &Asana{Client: pagedClient(map[string]string{
+		"":   `{"data":[{"gid":"1","name":"User Story 1: First"}],"next_page":{"offset":"p2"}}`,
+		"p2": `{"data":[{"gid":"2","name":"User Story 2: Second"}]}`,
+	}, &reqs)}
+
+	got, err := a.ListProjectTasks(context.Background(), "42")
+
+	require.NoError(t, err)
+	require.Equal(t, []Task{{GID: "1", Name: "User Story 1: First"}, {GID: "2", Name: "User Story 2: Second"}}, got)
+	require.Equal(t, "/api/1.0/projects/42/tasks", reqs[1].URL.Path)
+}
+
+func TestProjectGIDByNamePaged(t *testing.T) {
+	var reqs []*http.Request
+	pages := map[string]string{
+		"":   `{"data":[{"gid":"p1","name":"Other"}],"next_page":{"offset":"p2"}}`,
+		"p2": `{"data":[{"gid":"p2","name":"Target"}]}`,
+	}
+	client := &http.Client{Transport: testutil.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/api/1.0/workspaces" {
+			return jsonResponse(http.StatusOK, `{"data":[{"gid":"7","name":"Acme"}]}`), nil
+		}
+		return pagedClient(pages, &reqs).Transport.RoundTrip(req)
+	})}
+	a := &Asana{Client: client}
+
+	got, err := a.ProjectGIDByName(context.Background(), "Acme", "Target")
+
+	require.NoError(t, err)
+	require.Equal(t, "p2", got)
+	require.Equal(t, "7", reqs[0].URL.Query().Get("workspace"), "workspace ID parsed from its gid")
+}
@@ -0,0 +1,29 @@
+package asana
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AddStoryToTask posts a comment story on the task. The text parameter
+// should contain HTML and is wrapped in a <body> element when needed.
+func (a *Asana) AddStoryToTask(ctx context.Context, taskGID, text string) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.AddStoryToTask")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	data := map[string]string{"html_text": ensureHTMLBody(text)}
+	if err := a.doJSON(ctx, http.MethodPost, fmt.Sprintf("tasks/%s/stories", taskGID), data, nil); err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
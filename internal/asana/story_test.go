@@ -0,0 +1,49 @@
+package asana
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsanaAddStoryToTask(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    *http.Response
+		respErr error
+		wantErr bool
+	}{
+		{name: "success", resp: jsonResponse(http.StatusCreated, `{"data":{"gid":"s1"}}`)},
+		{name: "http error", resp: jsonResponse(http.StatusBadRequest, "oops"), wantErr: true},
+		{name: "client error", respErr: context.DeadlineExceeded, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *http.Request
+			a := &Asana{Client: testutil.NewTestClientWithRequest(tt.resp, tt.respErr, &req)}
+			err := a.AddStoryToTask(context.Background(), "t1", "<strong>State</strong>")
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, http.MethodPost, req.Method)
+			require.True(t, strings.HasSuffix(req.URL.Path, "/tasks/t1/stories"))
+			body, _ := io.ReadAll(req.Body)
+			var payload struct {
+				Data struct {
+					HTMLText string `json:"html_text"`
+				} `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(body, &payload))
+			require.Equal(t, "<body><strong>State</strong></body>", payload.Data.HTMLText)
+		})
+	}
+}
@@ -1,11 +1,8 @@
 package asana
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"math"
 	"net/http"
 	"net/url"
@@ -23,15 +20,6 @@ type Tag struct {
 	Name string `json:"name"`
 }
 
-func workspaceIDByName(workspaces []Workspace, name string) (int64, bool) {
-	for _, ws := range workspaces {
-		if ws.Name == name {
-			return ws.ID, true
-		}
-	}
-	return 0, false
-}
-
 func pickTagByName(tags []asanaapi.Tag, name string) (Tag, bool) {
 	var (
 		chosen Tag
@@ -65,24 +53,16 @@ func (a *Asana) TagByName(ctx context.Context, workspaceName, tagName string) (T
 	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.TagByName")
 	defer span.End()
 
-	workspaces, err := a.ListWorkspaces(ctx)
+	wsID, err := a.workspaceID(ctx, workspaceName)
 	if err != nil {
-		return Tag{}, err
-	}
-
-	wsID, ok := workspaceIDByName(workspaces, workspaceName)
-	if !ok {
-		err := fmt.Errorf("workspace not found")
 		span.SetStatus(codes.Error, err.Error())
 		return Tag{}, err
 	}
 
-	client := asanaapi.NewClient(a.Client)
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-
-	tags, err := client.ListTags(ctx, &asanaapi.Filter{Workspace: wsID})
+	params := url.Values{"workspace": {fmt.Sprint(wsID)}}
+	tags, err := listAll[asanaapi.Tag](ctx, a, "tags", params, "gid", "name")
 	if err != nil {
+		a.forgetWorkspaces(err)
 		span.RecordError(err, trace.WithStackTrace(true))
 		span.SetStatus(codes.Error, err.Error())
 		return Tag{}, err
@@ -90,7 +70,7 @@ func (a *Asana) TagByName(ctx context.Context, workspaceName, tagName string) (T
 
 	tag, ok := pickTagByName(tags, tagName)
 	if !ok {
-		err := fmt.Errorf("tag not found")
+		err := notFound("tag", tagName)
 		span.SetStatus(codes.Error, err.Error())
 		return Tag{}, err
 	}
@@ -102,40 +82,11 @@ func (a *Asana) AddTagToTask(ctx context.Context, taskGID, tagGID string) error
 	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.AddTagToTask")
 	defer span.End()
 
-	client := asanaapi.NewClient(a.Client)
-
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	payload := map[string]map[string]string{
-		"data": {"tag": tagGID},
-	}
-	b, err := json.Marshal(payload)
-	if err != nil {
-		span.RecordError(err, trace.WithStackTrace(true))
-		span.SetStatus(codes.Error, err.Error())
-		return err
-	}
-
-	u := client.BaseURL.ResolveReference(&url.URL{Path: fmt.Sprintf("tasks/%s/addTag", taskGID)})
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(b))
-	if err != nil {
-		span.RecordError(err, trace.WithStackTrace(true))
-		span.SetStatus(codes.Error, err.Error())
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := a.Client.Do(req)
-	if err != nil {
-		span.RecordError(err, trace.WithStackTrace(true))
-		span.SetStatus(codes.Error, err.Error())
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		err := fmt.Errorf("asana add tag failed: %s", string(body))
+	data := map[string]string{"tag": tagGID}
+	if err := a.doJSON(ctx, http.MethodPost, fmt.Sprintf("tasks/%s/addTag", taskGID), data, nil); err != nil {
 		span.RecordError(err, trace.WithStackTrace(true))
 		span.SetStatus(codes.Error, err.Error())
 		return err
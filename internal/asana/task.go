@@ -1,15 +1,13 @@
 package asana
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
 	asanaapi "github.com/qw4n7y/go-asana/asana"
@@ -19,29 +17,55 @@ import (
 type Task struct {
 	GID  string
 	Name string
+	// Notes and CustomFields are only set by ListProjectTasks.
+	Notes        string
+	CustomFields map[string]string // custom field GID → display value
+	// HTMLNotes is only set by ListProjectTasks and TaskByGID, ModifiedAt
+	// only by TaskByGID.
+	HTMLNotes  string
+	ModifiedAt time.Time
 }
 
+// taskRecord is a task as returned by ListProjectTasks.
+type taskRecord struct {
+	GID          string `json:"gid"`
+	Name         string `json:"name"`
+	Notes        string `json:"notes"`
+	HTMLNotes    string `json:"html_notes"`
+	CustomFields []struct {
+		GID          string `json:"gid"`
+		DisplayValue string `json:"display_value"`
+	} `json:"custom_fields"`
+}
+
+// ListProjectTasks returns the tasks in the project with their notes, in
+// plain text and HTML, and custom field values. Each page of tasks gets its
+// own timeout, so large projects are listed in full.
 func (a *Asana) ListProjectTasks(ctx context.Context, projectGID string) ([]Task, error) {
 	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.ListProjectTasks")
 	defer span.End()
 
-	client := asanaapi.NewClient(a.Client)
-	pid, err := strconv.ParseInt(projectGID, 10, 64)
-	if err != nil {
+	// Asana GIDs are numeric, reject anything else before building the path.
+	if _, err := strconv.ParseInt(projectGID, 10, 64); err != nil {
 		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-
-	tasks, err := client.ListProjectTasks(ctx, pid, nil)
+	tasks, err := listAll[taskRecord](ctx, a, fmt.Sprintf("projects/%s/tasks", projectGID), nil,
+		"gid", "name", "notes", "html_notes", "custom_fields.gid", "custom_fields.display_value")
 	if err != nil {
 		return nil, err
 	}
 
 	var result []Task
 	for _, t := range tasks {
-		result = append(result, Task{GID: t.GID, Name: t.Name})
+		task := Task{GID: t.GID, Name: t.Name, Notes: t.Notes, HTMLNotes: t.HTMLNotes}
+		for _, cf := range t.CustomFields {
+			if task.CustomFields == nil {
+				task.CustomFields = map[string]string{}
+			}
+			task.CustomFields[cf.GID] = cf.DisplayValue
+		}
+		result = append(result, task)
 	}
 	return result, nil
 }
@@ -51,132 +75,116 @@ func (a *Asana) CreateTask(ctx context.Context, projectGID, name, notes string)
 	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.CreateTask")
 	defer span.End()
 
-	client := asanaapi.NewClient(a.Client)
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	notes = ensureHTMLBody(notes)
 
-	fields := map[string]string{
-		"projects":   projectGID,
-		"name":       name,
-		"html_notes": notes,
+	form := url.Values{
+		"projects":   {projectGID},
+		"name":       {name},
+		"html_notes": {notes},
 	}
-	t, err := client.CreateTask(ctx, fields, nil)
+	req, err := a.newRequest(ctx, http.MethodPost, "tasks", nil, strings.NewReader(form.Encode()))
 	if err != nil {
 		return Task{}, err
 	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var t asanaapi.Task
+	if err := a.do(req, &t); err != nil {
+		return Task{}, err
+	}
 	return Task{GID: t.GID, Name: t.Name}, nil
 }
 
 // UpdateTask updates an existing task using HTML notes for the description.
+// An empty name or notes is left unchanged.
 func (a *Asana) UpdateTask(ctx context.Context, taskGID, name, notes string) error {
 	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.UpdateTask")
 	defer span.End()
 
-	client := asanaapi.NewClient(a.Client)
-
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	notes = ensureHTMLBody(notes)
-
-	payload := map[string]map[string]string{
-		"data": {
-			"name":       name,
-			"html_notes": notes,
-		},
-	}
-	b, err := json.Marshal(payload)
-	if err != nil {
-		return err
-	}
-
-	u := client.BaseURL.ResolveReference(&url.URL{Path: fmt.Sprintf("tasks/%s", taskGID)})
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(b))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := a.Client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("asana update failed: %s", string(body))
-	}
-	return nil
+	return a.doJSON(ctx, http.MethodPut, fmt.Sprintf("tasks/%s", taskGID), taskChanges(name, notes), nil)
 }
 
-// CreateTaskWithCustomFields creates a task and sets the provided custom fields.
+// CreateTaskWithCustomFields creates a task and sets the provided custom
+// fields.
 func (a *Asana) CreateTaskWithCustomFields(ctx context.Context, projectGID, name, notes string, customFields map[string]string) (Task, error) {
 	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.CreateTaskWithCustomFields")
 	defer span.End()
 
-	client := asanaapi.NewClient(a.Client)
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	notes = ensureHTMLBody(notes)
-
 	body := asanaapi.NewTask{
 		Name:         name,
-		HTMLNotes:    notes,
+		HTMLNotes:    ensureHTMLBody(notes),
 		Projects:     []string{projectGID},
 		CustomFields: customFields,
 	}
 
-	t, err := client.CreateTask2(ctx, body, nil)
-	if err != nil {
+	var t asanaapi.Task
+	if err := a.doJSON(ctx, http.MethodPost, "tasks", body, &t); err != nil {
 		return Task{}, err
 	}
 	return Task{GID: t.GID, Name: t.Name}, nil
 }
 
-// UpdateTaskWithCustomFields updates a task and sets custom field values.
-func (a *Asana) UpdateTaskWithCustomFields(ctx context.Context, taskGID, name, notes string, customFields map[string]string) error {
-	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.UpdateTaskWithCustomFields")
+// TaskByGID returns the name, HTML notes and last modification time of a
+// task.
+func (a *Asana) TaskByGID(ctx context.Context, taskGID string) (Task, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.TaskByGID")
 	defer span.End()
 
-	client := asanaapi.NewClient(a.Client)
-
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	notes = ensureHTMLBody(notes)
-
-	payload := map[string]map[string]interface{}{
-		"data": {
-			"name":          name,
-			"html_notes":    notes,
-			"custom_fields": customFields,
-		},
-	}
-	b, err := json.Marshal(payload)
+	req, err := a.newRequest(ctx, http.MethodGet, fmt.Sprintf("tasks/%s", taskGID), nil, nil)
 	if err != nil {
-		return err
+		return Task{}, err
 	}
+	req.URL.RawQuery = url.Values{"opt_fields": {"gid,name,html_notes,modified_at"}}.Encode()
 
-	u := client.BaseURL.ResolveReference(&url.URL{Path: fmt.Sprintf("tasks/%s", taskGID)})
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(b))
-	if err != nil {
-		return err
+	var t struct {
+		GID        string    `json:"gid"`
+		Name       string    `json:"name"`
+		HTMLNotes  string    `json:"html_notes"`
+		ModifiedAt time.Time `json:"modified_at"`
+	}
+	if err := a.do(req, &t); err != nil {
+		return Task{}, err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	return Task{GID: t.GID, Name: t.Name, HTMLNotes: t.HTMLNotes, ModifiedAt: t.ModifiedAt}, nil
+}
 
-	resp, err := a.Client.Do(req)
-	if err != nil {
-		return err
+// UpdateTaskWithCustomFields updates a task and sets custom field values.
+// An empty name or notes is left unchanged.
+func (a *Asana) UpdateTaskWithCustomFields(ctx context.Context, taskGID, name, notes string, customFields map[string]string) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.UpdateTaskWithCustomFields")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	data := taskChanges(name, notes)
+	data["custom_fields"] = customFields
+	return a.doJSON(ctx, http.MethodPut, fmt.Sprintf("tasks/%s", taskGID), data, nil)
+}
+
+// taskChanges returns the body of a task update, leaving out an empty name
+// or notes.
+func taskChanges(name, notes string) map[string]interface{} {
+	data := map[string]interface{}{}
+	if name != "" {
+		data["name"] = name
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("asana update failed: %s", string(body))
+	if notes != "" {
+		data["html_notes"] = ensureHTMLBody(notes)
 	}
-	return nil
+	return data
 }
 
 // ensureHTMLBody wraps the provided notes in a <body> element if one is not already present.
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ADO-Asana-Sync/sync-engine/internal/testutil"
 	asanaapi "github.com/qw4n7y/go-asana/asana"
@@ -115,6 +116,28 @@ func TestAsanaListProjectTasks(t *testing.T) {
 	}
 }
 
+func TestAsanaListProjectTasksMatchFields(t *testing.T) {
+	body := `{"data":[{"gid":"1","name":"Task 1","notes":"see https://dev.azure.com/org/p/_workitems/edit/7",` +
+		`"html_notes":"<body><a href=\"http://ado/7\">Story 7:</a> Task 1</body>",` +
+		`"custom_fields":[{"gid":"cf-1","display_value":"http://ado/7"}]}]}`
+	resp := &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}
+	var req *http.Request
+	a := &Asana{Client: testutil.NewTestClientWithRequest(resp, nil, &req)}
+
+	got, err := a.ListProjectTasks(context.Background(), "42")
+
+	require.NoError(t, err)
+	require.Equal(t, []Task{{
+		GID:          "1",
+		Name:         "Task 1",
+		Notes:        "see https://dev.azure.com/org/p/_workitems/edit/7",
+		HTMLNotes:    `<body><a href="http://ado/7">Story 7:</a> Task 1</body>`,
+		CustomFields: map[string]string{"cf-1": "http://ado/7"},
+	}}, got)
+	require.Contains(t, req.URL.Query().Get("opt_fields"), "custom_fields.display_value")
+	require.Contains(t, req.URL.Query().Get("opt_fields"), "html_notes")
+}
+
 // TestAsanaCreateTask verifies CreateTask correctly handles success and error scenarios.
 func TestAsanaCreateTask(t *testing.T) {
 	tests := []struct {
@@ -237,6 +260,39 @@ func TestAsanaCreateTaskWithCustomFields(t *testing.T) {
 	require.Equal(t, "<body>notes</body>", payload.Data.HTMLNotes)
 }
 
+func TestAsanaTaskByGID(t *testing.T) {
+	body := `{"data":{"gid":"1","name":"Task","html_notes":"<body>notes</body>","modified_at":"2026-01-02T03:04:05.000Z"}}`
+	resp := &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}
+	var req *http.Request
+	a := &Asana{Client: testutil.NewTestClientWithRequest(resp, nil, &req)}
+
+	got, err := a.TaskByGID(context.Background(), "1")
+
+	require.NoError(t, err)
+	require.Equal(t, Task{GID: "1", Name: "Task", HTMLNotes: "<body>notes</body>", ModifiedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}, got)
+	require.Equal(t, "/api/1.0/tasks/1", req.URL.Path)
+	require.Equal(t, "gid,name,html_notes,modified_at", req.URL.Query().Get("opt_fields"))
+}
+
+func TestAsanaUpdateTaskLeavesEmptyFieldsUnchanged(t *testing.T) {
+	successResp := &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}")), Header: make(http.Header)}
+	var req *http.Request
+	client := testutil.NewTestClientWithRequest(successResp, nil, &req)
+	a := &Asana{Client: client}
+
+	err := a.UpdateTaskWithCustomFields(context.Background(), "1", "", "", map[string]string{"123": "http://example.com"})
+
+	require.NoError(t, err)
+	body, _ := io.ReadAll(req.Body)
+	var payload struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &payload))
+	require.NotContains(t, payload.Data, "name")
+	require.NotContains(t, payload.Data, "html_notes")
+	require.Contains(t, payload.Data, "custom_fields")
+}
+
 func TestAsanaUpdateTaskWithCustomFields(t *testing.T) {
 	successResp := &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}")), Header: make(http.Header)}
 	var req *http.Request
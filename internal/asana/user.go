@@ -0,0 +1,33 @@
+package asana
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// User is an Asana user.
+type User struct {
+	GID  string `json:"gid"`
+	Name string `json:"name"`
+}
+
+// CurrentUser returns the user the client is authenticated as.
+func (a *Asana) CurrentUser(ctx context.Context) (User, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.CurrentUser")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var u User
+	if err := a.doJSON(ctx, http.MethodGet, "users/me", nil, &u); err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return User{}, err
+	}
+	return u, nil
+}
@@ -0,0 +1,41 @@
+package asana
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsanaCurrentUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    *http.Response
+		respErr error
+		want    User
+		wantErr bool
+	}{
+		{name: "success", resp: jsonResponse(http.StatusOK, `{"data":{"gid":"u1","name":"Sync Bot"}}`), want: User{GID: "u1", Name: "Sync Bot"}},
+		{name: "http error", resp: jsonResponse(http.StatusUnauthorized, "oops"), wantErr: true},
+		{name: "client error", respErr: context.DeadlineExceeded, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *http.Request
+			a := &Asana{Client: testutil.NewTestClientWithRequest(tt.resp, tt.respErr, &req)}
+			u, err := a.CurrentUser(context.Background())
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, http.MethodGet, req.Method)
+			require.True(t, strings.HasSuffix(req.URL.Path, "/users/me"))
+			require.Equal(t, tt.want, u)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package asana
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Webhook delivers the events of an Asana resource to a target URL.
+type Webhook struct {
+	GID    string `json:"gid"`
+	Target string `json:"target"`
+	Active bool   `json:"active"`
+}
+
+// EventResource identifies the resource an event is about.
+type EventResource struct {
+	GID             string `json:"gid"`
+	ResourceType    string `json:"resource_type"`
+	ResourceSubtype string `json:"resource_subtype"`
+}
+
+// Event is a change to an Asana resource, delivered by webhooks.
+type Event struct {
+	// Action is changed, added, removed, deleted or undeleted.
+	Action   string         `json:"action"`
+	Resource EventResource  `json:"resource"`
+	Parent   *EventResource `json:"parent"`
+	User     *struct {
+		GID string `json:"gid"`
+	} `json:"user"`
+	// Change names the field changed, for changed events.
+	Change *struct {
+		Field  string `json:"field"`
+		Action string `json:"action"`
+	} `json:"change"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TaskGID returns the GID of the task the event is about: the task itself,
+// or the task a story such as a comment was added to. It is empty for
+// events about other resources.
+func (e Event) TaskGID() string {
+	if e.Resource.ResourceType == "task" {
+		return e.Resource.GID
+	}
+	if e.Resource.ResourceType == "story" && e.Parent != nil && e.Parent.ResourceType == "task" {
+		return e.Parent.GID
+	}
+	return ""
+}
+
+// webhookFilters restricts the events of a project webhook to its tasks and
+// the comments added to them. Filters cannot match the user who made the
+// change, so receivers drop their own events using Event.User.
+var webhookFilters = []map[string]string{
+	{"resource_type": "task"},
+	{"resource_type": "story", "action": "added"},
+}
+
+// CreateWebhook subscribes target to the events of the resource. Asana
+// calls target with an X-Hook-Secret header before answering, and the
+// webhook is only created once target echoes the header back.
+func (a *Asana) CreateWebhook(ctx context.Context, resourceGID, target string) (Webhook, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.CreateWebhook")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	data := map[string]interface{}{
+		"resource": resourceGID,
+		"target":   target,
+		"filters":  webhookFilters,
+	}
+	var w Webhook
+	if err := a.doJSON(ctx, http.MethodPost, "webhooks", data, &w); err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return Webhook{}, err
+	}
+	return w, nil
+}
+
+// DeleteWebhook removes a webhook.
+func (a *Asana) DeleteWebhook(ctx context.Context, webhookGID string) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.DeleteWebhook")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := a.doJSON(ctx, http.MethodDelete, fmt.Sprintf("webhooks/%s", webhookGID), nil, nil); err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package asana
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsanaCreateWebhook(t *testing.T) {
+	var req *http.Request
+	resp := jsonResponse(http.StatusCreated, `{"data":{"gid":"w1","target":"https://sync/hooks/asana/p1","active":true}}`)
+	a := &Asana{Client: testutil.NewTestClientWithRequest(resp, nil, &req)}
+
+	got, err := a.CreateWebhook(context.Background(), "p1", "https://sync/hooks/asana/p1")
+
+	require.NoError(t, err)
+	require.Equal(t, Webhook{GID: "w1", Target: "https://sync/hooks/asana/p1", Active: true}, got)
+	require.Equal(t, http.MethodPost, req.Method)
+	require.Equal(t, "/api/1.0/webhooks", req.URL.Path)
+	body, _ := io.ReadAll(req.Body)
+	var payload struct {
+		Data struct {
+			Resource string              `json:"resource"`
+			Target   string              `json:"target"`
+			Filters  []map[string]string `json:"filters"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &payload))
+	require.Equal(t, "p1", payload.Data.Resource)
+	require.Equal(t, "https://sync/hooks/asana/p1", payload.Data.Target)
+	require.Equal(t, webhookFilters, payload.Data.Filters)
+}
+
+func TestAsanaDeleteWebhook(t *testing.T) {
+	var req *http.Request
+	a := &Asana{Client: testutil.NewTestClientWithRequest(jsonResponse(http.StatusOK, `{"data":{}}`), nil, &req)}
+
+	require.NoError(t, a.DeleteWebhook(context.Background(), "w1"))
+	require.Equal(t, http.MethodDelete, req.Method)
+	require.Equal(t, "/api/1.0/webhooks/w1", req.URL.Path)
+
+	a = &Asana{Client: testutil.NewTestClient(jsonResponse(http.StatusNotFound, `{}`), nil)}
+	require.ErrorIs(t, a.DeleteWebhook(context.Background(), "w1"), ErrNotFound)
+}
+
+func TestEventTaskGID(t *testing.T) {
+	var events []Event
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"action":"changed","resource":{"gid":"t1","resource_type":"task"},"change":{"field":"completed","action":"changed"}},
+		{"action":"added","resource":{"gid":"s1","resource_type":"story","resource_subtype":"comment_added"},"parent":{"gid":"t2","resource_type":"task"}},
+		{"action":"added","resource":{"gid":"t3","resource_type":"task"},"parent":{"gid":"p1","resource_type":"project"}},
+		{"action":"changed","resource":{"gid":"p1","resource_type":"project"}}
+	]`), &events))
+
+	require.Equal(t, "t1", events[0].TaskGID())
+	require.Equal(t, "completed", events[0].Change.Field)
+	require.Equal(t, "t2", events[1].TaskGID())
+	require.Equal(t, "t3", events[2].TaskGID())
+	require.Empty(t, events[3].TaskGID())
+}
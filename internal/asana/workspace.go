@@ -2,10 +2,12 @@ package asana
 
 import (
 	"context"
+	"errors"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
-	"github.com/qw4n7y/go-asana/asana"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -24,12 +26,11 @@ func (a *Asana) ListWorkspaces(ctx context.Context) ([]Workspace, error) {
 	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "asana.ListWorkspaces")
 	defer span.End()
 
-	client := asana.NewClient(a.Client)
-
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-
-	workspaces, err := client.ListWorkspaces(ctx)
+	workspaces, err := listAll[struct {
+		ID   int64  `json:"id"`
+		GID  string `json:"gid"`
+		Name string `json:"name"`
+	}](ctx, a, "workspaces", nil, "gid", "name")
 	if err != nil {
 		span.RecordError(err, trace.WithStackTrace(true))
 		span.SetStatus(codes.Error, err.Error())
@@ -38,7 +39,61 @@ func (a *Asana) ListWorkspaces(ctx context.Context) ([]Workspace, error) {
 
 	var result []Workspace
 	for _, ws := range workspaces {
-		result = append(result, Workspace{ID: ws.ID, Name: ws.Name})
+		id := ws.ID
+		if id == 0 {
+			id, _ = strconv.ParseInt(ws.GID, 10, 64)
+		}
+		result = append(result, Workspace{ID: id, Name: ws.Name})
 	}
 	return result, nil
 }
+
+// workspaceCache remembers the workspace IDs by name so name lookups do not
+// list the workspaces on every call.
+type workspaceCache struct {
+	mu      sync.Mutex
+	ids     map[string]int64
+	fetched time.Time
+}
+
+// workspaceID returns the ID of the named workspace. The workspaces are
+// listed at most once per CacheTTL, or again when the name is not among the
+// cached ones in case the workspace was created since.
+func (a *Asana) workspaceID(ctx context.Context, name string) (int64, error) {
+	c := &a.workspaces
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if a.CacheTTL > 0 && c.ids != nil && time.Since(c.fetched) < a.CacheTTL {
+		if id, ok := c.ids[name]; ok {
+			return id, nil
+		}
+	}
+
+	workspaces, err := a.ListWorkspaces(ctx)
+	if err != nil {
+		return 0, err
+	}
+	c.ids = make(map[string]int64, len(workspaces))
+	for _, ws := range workspaces {
+		c.ids[ws.Name] = ws.ID
+	}
+	c.fetched = time.Now()
+
+	id, ok := c.ids[name]
+	if !ok {
+		return 0, notFound("workspace", name)
+	}
+	return id, nil
+}
+
+// forgetWorkspaces drops the cached workspace IDs when err shows one of them
+// no longer exists.
+func (a *Asana) forgetWorkspaces(err error) {
+	if !errors.Is(err, ErrNotFound) {
+		return
+	}
+	a.workspaces.mu.Lock()
+	a.workspaces.ids = nil
+	a.workspaces.mu.Unlock()
+}
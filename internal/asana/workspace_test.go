@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ADO-Asana-Sync/sync-engine/internal/testutil"
 	"github.com/qw4n7y/go-asana/asana"
@@ -101,3 +102,39 @@ func TestAsanaListWorkspaces(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkspaceIDCached(t *testing.T) {
+	var calls int
+	a := &Asana{CacheTTL: time.Hour, Client: &http.Client{Transport: testutil.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return jsonResponse(http.StatusOK, `{"data":[{"gid":"1","name":"Acme"}]}`), nil
+	})}}
+
+	for i := 0; i < 3; i++ {
+		id, err := a.workspaceID(context.Background(), "Acme")
+		require.NoError(t, err)
+		require.EqualValues(t, 1, id)
+	}
+	require.Equal(t, 1, calls)
+
+	_, err := a.workspaceID(context.Background(), "New")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.Equal(t, 2, calls, "unknown names are looked up again")
+
+	a.forgetWorkspaces(&APIError{StatusCode: http.StatusNotFound})
+	_, err = a.workspaceID(context.Background(), "Acme")
+	require.NoError(t, err)
+	require.Equal(t, 3, calls, "listed again after a 404")
+}
+
+func TestWorkspaceIDNoCache(t *testing.T) {
+	var calls int
+	a := &Asana{Client: &http.Client{Transport: testutil.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return jsonResponse(http.StatusOK, `{"data":[{"gid":"1","name":"Acme"}]}`), nil
+	})}}
+
+	_, _ = a.workspaceID(context.Background(), "Acme")
+	_, _ = a.workspaceID(context.Background(), "Acme")
+	require.Equal(t, 2, calls)
+}
@@ -3,6 +3,7 @@ package azure
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strconv"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -17,8 +19,16 @@ import (
 // AzureInterface defines the methods that the Azure client must implement.
 type AzureInterface interface {
 	Connect(ctx context.Context, orgUrl, pat string)
-	GetChangedWorkItems(ctx context.Context, lastSync time.Time) ([]workitemtracking.WorkItemReference, error)
+	// GetChangedWorkItems returns the work items changed since lastSync,
+	// scoped to the given team project unless it is empty, and the ADO time
+	// the query ran at.
+	GetChangedWorkItems(ctx context.Context, project string, lastSync time.Time) ([]workitemtracking.WorkItemReference, time.Time, error)
 	GetWorkItem(ctx context.Context, id int) (WorkItem, error)
+	// GetWorkItemsBatch returns the work items with the given IDs, fetching
+	// up to MaxBatchSize per request. IDs that cannot be read are omitted.
+	GetWorkItemsBatch(ctx context.Context, ids []int, expandRelations bool) ([]WorkItem, error)
+	// GetWorkItemRevision returns the work item as it was at the given revision.
+	GetWorkItemRevision(ctx context.Context, id, rev int) (WorkItem, error)
 	GetProjects(ctx context.Context) ([]core.TeamProjectReference, error)
 }
 
@@ -26,6 +36,8 @@ type AzureInterface interface {
 type WIClient interface {
 	QueryByWiql(ctx context.Context, args workitemtracking.QueryByWiqlArgs) (*workitemtracking.WorkItemQueryResult, error)
 	GetWorkItem(ctx context.Context, args workitemtracking.GetWorkItemArgs) (*workitemtracking.WorkItem, error)
+	GetRevision(ctx context.Context, args workitemtracking.GetRevisionArgs) (*workitemtracking.WorkItem, error)
+	GetWorkItemsBatch(ctx context.Context, args workitemtracking.GetWorkItemsBatchArgs) (*[]workitemtracking.WorkItem, error)
 }
 
 // CoreClient defines the methods that the Azure Core client must implement.
@@ -33,21 +45,66 @@ type CoreClient interface {
 	GetProjects(ctx context.Context, args core.GetProjectsArgs) (*core.GetProjectsResponseValue, error)
 }
 
+// defaultWIQLPageSize is the number of work items requested per WIQL page.
+const defaultWIQLPageSize = 10000
+
+// MaxBatchSize is the maximum number of work items ADO returns per batch
+// request.
+const MaxBatchSize = 200
+
+// batchFields are the fields requested when fetching work items in batches.
+var batchFields = []string{
+	"System.Id",
+	"System.Rev",
+	"System.AssignedTo",
+	"System.ChangedDate",
+	"System.CreatedDate",
+	"System.State",
+	"System.Title",
+	"System.TeamProject",
+	"System.WorkItemType",
+	"System.IterationPath",
+	"System.Parent",
+	"Microsoft.VSTS.Common.Priority",
+}
+
 type Azure struct {
-	Client            *azuredevops.Connection
+	Client *azuredevops.Connection
+	// Transport, when set, carries the requests of the ADO clients instead
+	// of http.DefaultTransport.
+	Transport         http.RoundTripper
+	wiqlPageSize      int
 	newCoreClient     func(context.Context, *azuredevops.Connection) (CoreClient, error)
 	newWorkItemClient func(context.Context, *azuredevops.Connection) (WIClient, error)
 }
 
 func NewAzure() *Azure {
-	return &Azure{
-		newWorkItemClient: func(ctx context.Context, c *azuredevops.Connection) (WIClient, error) {
+	a := &Azure{}
+	a.newWorkItemClient = func(ctx context.Context, c *azuredevops.Connection) (WIClient, error) {
+		if a.Transport == nil {
 			return workitemtracking.NewClient(ctx, c)
-		},
-		newCoreClient: func(ctx context.Context, c *azuredevops.Connection) (CoreClient, error) {
+		}
+		return &workitemtracking.ClientImpl{Client: *a.apiClient(c)}, nil
+	}
+	a.newCoreClient = func(ctx context.Context, c *azuredevops.Connection) (CoreClient, error) {
+		if a.Transport == nil {
 			return core.NewClient(ctx, c)
-		},
+		}
+		return &core.ClientImpl{Client: *a.apiClient(c)}, nil
 	}
+	return a
+}
+
+// apiClient builds an ADO API client sending its requests through
+// a.Transport. The connection does not expose its HTTP client, so the
+// client is built against the organization URL, which serves the work item
+// tracking and core areas on Azure DevOps Services and Server alike.
+func (a *Azure) apiClient(c *azuredevops.Connection) *azuredevops.Client {
+	httpClient := &http.Client{Transport: a.Transport}
+	if c.Timeout != nil {
+		httpClient.Timeout = *c.Timeout
+	}
+	return azuredevops.NewClientWithOptions(c, c.BaseUrl, azuredevops.WithHTTPClient(httpClient))
 }
 
 // Connect establishes a connection to Azure DevOps using the provided organization URL and personal access token (PAT).
@@ -60,49 +117,87 @@ func (a *Azure) Connect(ctx context.Context, orgUrl, pat string) {
 	a.Client = clt
 }
 
-// GetChangedWorkItems retrieves the changed work items from Azure.
-// It configures the tracing and starts a span for the operation.
+// GetChangedWorkItems retrieves the work items changed since lastSync. When
+// project is not empty the query is scoped to that team project, otherwise it
+// covers the whole organization. It also returns the time, by the ADO clock,
+// the first page was queried at: every change made until then is included.
+// The time is zero when ADO did not report it.
 //
 // https://github.com/microsoft/azure-devops-go-api/blob/dev/azuredevops/workitemtracking/client.go#L2676
 // https://learn.microsoft.com/en-us/rest/api/azure/devops/wit/wiql/query-by-wiql?view=azure-devops-rest-7.2&tabs=HTTP
-func (a *Azure) GetChangedWorkItems(ctx context.Context, lastSync time.Time) ([]workitemtracking.WorkItemReference, error) {
+func (a *Azure) GetChangedWorkItems(ctx context.Context, project string, lastSync time.Time) ([]workitemtracking.WorkItemReference, time.Time, error) {
 	_, span := helpers.StartSpanOnTracerFromContext(ctx, "azure.GetChangedWorkItems")
 	defer span.End()
 
+	span.SetAttributes(attribute.String("project", project))
+
 	var tasks []workitemtracking.WorkItemReference
+	var asOf time.Time
 
 	workClient, err := a.newWorkItemClient(ctx, a.Client)
 	if err != nil {
 		span.RecordError(err, trace.WithStackTrace(true))
 		span.SetStatus(codes.Error, err.Error())
-		return tasks, err
+		return tasks, time.Time{}, err
 	}
 
-	qs := fmt.Sprintf(
-		"SELECT [System.Id], [System.Title], [System.State] FROM workitems WHERE [System.ChangedDate] > '%s' ORDER BY [System.ChangedDate] DESC",
-		lastSync.Format(time.RFC3339),
-	)
+	scope := ""
+	if project != "" {
+		scope = "[System.TeamProject] = @project AND "
+	}
+	pageSize := a.wiqlPageSize
+	if pageSize <= 0 {
+		pageSize = defaultWIQLPageSize
+	}
 
-	// Get the first page of work items.
+	// WIQL refuses queries matching more than 20,000 items unless a top is
+	// set, so page through the results in ID order until a short page.
 	// Enable time precision so the query includes the timestamp and not just the date
 	timePrecision := true
-	responseValue, err := workClient.QueryByWiql(ctx, workitemtracking.QueryByWiqlArgs{
-		Wiql: &workitemtracking.Wiql{
-			Query: &qs,
-		},
-		TimePrecision: &timePrecision,
-	})
-	if err != nil {
-		span.RecordError(err, trace.WithStackTrace(true))
-		span.SetStatus(codes.Error, err.Error())
-		return tasks, err
-	}
+	lastID := 0
+	pages := 0
+	for {
+		qs := fmt.Sprintf(
+			"SELECT [System.Id], [System.Title], [System.State] FROM workitems WHERE %s[System.ChangedDate] > '%s' AND [System.Id] > %d ORDER BY [System.Id] ASC",
+			scope,
+			lastSync.Format(time.RFC3339),
+			lastID,
+		)
+		args := workitemtracking.QueryByWiqlArgs{
+			Wiql: &workitemtracking.Wiql{
+				Query: &qs,
+			},
+			TimePrecision: &timePrecision,
+			Top:           &pageSize,
+		}
+		if project != "" {
+			args.Project = &project
+		}
+		responseValue, err := workClient.QueryByWiql(ctx, args)
+		err = wrapError(err)
+		if err != nil {
+			span.RecordError(err, trace.WithStackTrace(true))
+			span.SetStatus(codes.Error, err.Error())
+			return tasks, time.Time{}, err
+		}
+		pages++
+		if pages == 1 && responseValue.AsOf != nil {
+			asOf = responseValue.AsOf.Time
+		}
 
-	if responseValue.WorkItems != nil {
-		tasks = append(tasks, *responseValue.WorkItems...)
+		if responseValue.WorkItems == nil || len(*responseValue.WorkItems) == 0 {
+			break
+		}
+		page := *responseValue.WorkItems
+		tasks = append(tasks, page...)
+		if len(page) < pageSize || page[len(page)-1].Id == nil {
+			break
+		}
+		lastID = *page[len(page)-1].Id
 	}
 
-	return tasks, nil
+	span.SetAttributes(attribute.Int("pages", pages), attribute.Int("work_items", len(tasks)))
+	return tasks, asOf, nil
 }
 
 // GetProjects retrieves a list of team projects from Azure DevOps.
@@ -124,6 +219,7 @@ func (a *Azure) GetProjects(ctx context.Context) ([]core.TeamProjectReference, e
 
 	// Get first page of the list of team projects for your organization
 	responseValue, err := coreClient.GetProjects(ctx, core.GetProjectsArgs{})
+	err = wrapError(err)
 	if err != nil {
 		span.RecordError(err, trace.WithStackTrace(true))
 		span.SetStatus(codes.Error, err.Error())
@@ -149,6 +245,7 @@ func (a *Azure) GetProjects(ctx context.Context) ([]core.TeamProjectReference, e
 				ContinuationToken: &continuationToken,
 			}
 			responseValue, err = coreClient.GetProjects(ctx, projectArgs)
+			err = wrapError(err)
 			if err != nil {
 				span.RecordError(err, trace.WithStackTrace(true))
 				span.SetStatus(codes.Error, err.Error())
@@ -177,14 +274,129 @@ func (a *Azure) GetWorkItem(ctx context.Context, id int) (WorkItem, error) {
 	}
 
 	wi, err := workClient.GetWorkItem(ctx, workitemtracking.GetWorkItemArgs{Id: &id})
+	err = wrapError(err)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return result, err
+	}
+
+	result, err = toWorkItem(wi)
+	if err != nil {
+		return result, err
+	}
+	result.ID = id
+	return result, nil
+}
+
+// GetWorkItemsBatch retrieves work items in batches of up to MaxBatchSize.
+// Only the fields used by the sync are requested unless expandRelations is
+// set, in which case ADO returns all fields along with the relations, as it
+// does not allow both. Work items that are missing or not readable are
+// omitted rather than failing the batch.
+//
+// https://learn.microsoft.com/en-us/rest/api/azure/devops/wit/work-items/get-work-items-batch?view=azure-devops-rest-7.2
+func (a *Azure) GetWorkItemsBatch(ctx context.Context, ids []int, expandRelations bool) ([]WorkItem, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "azure.GetWorkItemsBatch")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("ids", len(ids)), attribute.Bool("expand_relations", expandRelations))
+
+	var result []WorkItem
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	workClient, err := a.newWorkItemClient(ctx, a.Client)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return result, err
+	}
+
+	errorPolicy := workitemtracking.WorkItemErrorPolicyValues.Omit
+	for start := 0; start < len(ids); start += MaxBatchSize {
+		end := start + MaxBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunk := ids[start:end]
+
+		req := workitemtracking.WorkItemBatchGetRequest{
+			Ids:         &chunk,
+			ErrorPolicy: &errorPolicy,
+		}
+		if expandRelations {
+			expand := workitemtracking.WorkItemExpandValues.Relations
+			req.Expand = &expand
+		} else {
+			fields := batchFields
+			req.Fields = &fields
+		}
+
+		items, err := workClient.GetWorkItemsBatch(ctx, workitemtracking.GetWorkItemsBatchArgs{WorkItemGetRequest: &req})
+		err = wrapError(err)
+		if err != nil {
+			span.RecordError(err, trace.WithStackTrace(true))
+			span.SetStatus(codes.Error, err.Error())
+			return result, err
+		}
+		if items == nil {
+			continue
+		}
+		for i := range *items {
+			// Omitted items come back as nulls.
+			if (*items)[i].Id == nil {
+				continue
+			}
+			wi, err := toWorkItem(&(*items)[i])
+			if err != nil {
+				span.RecordError(err)
+				continue
+			}
+			result = append(result, wi)
+		}
+	}
+
+	return result, nil
+}
+
+// GetWorkItemRevision retrieves a specific revision of a work item.
+func (a *Azure) GetWorkItemRevision(ctx context.Context, id, rev int) (WorkItem, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "azure.GetWorkItemRevision")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("id", id), attribute.Int("rev", rev))
+
+	var result WorkItem
+
+	workClient, err := a.newWorkItemClient(ctx, a.Client)
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return result, err
+	}
+
+	wi, err := workClient.GetRevision(ctx, workitemtracking.GetRevisionArgs{Id: &id, RevisionNumber: &rev})
+	err = wrapError(err)
 	if err != nil {
 		span.RecordError(err, trace.WithStackTrace(true))
 		span.SetStatus(codes.Error, err.Error())
 		return result, err
 	}
 
-	if wi.Fields == nil {
-		return result, fmt.Errorf("work item missing fields")
+	result, err = toWorkItem(wi)
+	if err != nil {
+		return result, err
+	}
+	result.ID = id
+	return result, nil
+}
+
+// toWorkItem converts an API work item into the simplified WorkItem struct.
+func toWorkItem(wi *workitemtracking.WorkItem) (WorkItem, error) {
+	if wi == nil || wi.Fields == nil {
+		return WorkItem{}, fmt.Errorf("work item missing fields")
 	}
 
 	fields := *wi.Fields
@@ -198,6 +410,18 @@ func (a *Azure) GetWorkItem(ctx context.Context, id int) (WorkItem, error) {
 		return ""
 	}
 
+	// Identity fields are returned as objects, older payloads as plain strings.
+	getIdentity := func(key string) string {
+		if v, ok := fields[key].(map[string]interface{}); ok {
+			if s, ok := v["displayName"].(string); ok && s != "" {
+				return s
+			}
+			s, _ := v["uniqueName"].(string)
+			return s
+		}
+		return getStr(key)
+	}
+
 	getTime := func(key string) time.Time {
 		if v, ok := fields[key]; ok {
 			switch t := v.(type) {
@@ -211,19 +435,54 @@ func (a *Azure) GetWorkItem(ctx context.Context, id int) (WorkItem, error) {
 		return time.Time{}
 	}
 
-	result = WorkItem{
-		ID:           id,
-		AssignedTo:   getStr("System.AssignedTo"),
-		ChangedDate:  getTime("System.ChangedDate"),
-		CreatedDate:  getTime("System.CreatedDate"),
-		State:        getStr("System.State"),
-		Title:        getStr("System.Title"),
-		URL:          safeDerefString(wi.Url),
-		TeamProject:  getStr("System.TeamProject"),
-		WorkItemType: getStr("System.WorkItemType"),
+	getInt := func(key string) int {
+		if v, ok := fields[key]; ok {
+			switch n := v.(type) {
+			case int:
+				return n
+			case float64:
+				return int(n)
+			}
+		}
+		return 0
 	}
 
-	return result, nil
+	rev := getInt("System.Rev")
+	if wi.Rev != nil {
+		rev = *wi.Rev
+	}
+
+	return WorkItem{
+		ID:            safeDerefInt(wi.Id),
+		Rev:           rev,
+		AssignedTo:    getIdentity("System.AssignedTo"),
+		ChangedDate:   getTime("System.ChangedDate"),
+		CreatedDate:   getTime("System.CreatedDate"),
+		State:         getStr("System.State"),
+		Title:         getStr("System.Title"),
+		URL:           safeDerefString(wi.Url),
+		TeamProject:   getStr("System.TeamProject"),
+		WorkItemType:  getStr("System.WorkItemType"),
+		IterationPath: getStr("System.IterationPath"),
+		Priority:      getInt("Microsoft.VSTS.Common.Priority"),
+		ParentID:      getInt("System.Parent"),
+		Relations:     toRelations(wi.Relations),
+	}, nil
+}
+
+func toRelations(rels *[]workitemtracking.WorkItemRelation) []Relation {
+	if rels == nil {
+		return nil
+	}
+	result := make([]Relation, 0, len(*rels))
+	for _, r := range *rels {
+		rel := Relation{Rel: safeDerefString(r.Rel), URL: safeDerefString(r.Url)}
+		if r.Attributes != nil {
+			rel.Name, _ = (*r.Attributes)["name"].(string)
+		}
+		result = append(result, rel)
+	}
+	return result
 }
 
 func safeDerefString(s *string) string {
@@ -232,3 +491,10 @@ func safeDerefString(s *string) string {
 	}
 	return *s
 }
+
+func safeDerefInt(i *int) int {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
@@ -3,6 +3,7 @@ package azure
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,14 +14,17 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ADO-Asana-Sync/sync-engine/internal/db"
 	"github.com/ADO-Asana-Sync/sync-engine/internal/testutil"
 )
 
 func TestGetChangedWorkItems(t *testing.T) {
 	t.Parallel() // Enable parallel execution of sub-tests
-	const queryFmt = "SELECT [System.Id], [System.Title], [System.State] FROM workitems WHERE [System.ChangedDate] > '%s' ORDER BY [System.ChangedDate] DESC"
+	const queryFmt = "SELECT [System.Id], [System.Title], [System.State] FROM workitems WHERE [System.ChangedDate] > '%s' AND [System.Id] > 0 ORDER BY [System.Id] ASC"
+	const projectQueryFmt = "SELECT [System.Id], [System.Title], [System.State] FROM workitems WHERE [System.TeamProject] = @project AND [System.ChangedDate] > '%s' AND [System.Id] > 0 ORDER BY [System.Id] ASC"
 	type args struct {
 		ctx      context.Context
+		project  string
 		lastSync time.Time
 	}
 
@@ -49,6 +53,18 @@ func TestGetChangedWorkItems(t *testing.T) {
 			want:    []workitemtracking.WorkItemReference{{Id: testutil.Ptr(123)}},
 			wantErr: false,
 		},
+		{
+			name:  "scopes query to project",
+			a:     &Azure{},
+			args:  args{ctx: context.Background(), project: "Proj", lastSync: testTime},
+			query: fmt.Sprintf(projectQueryFmt, testTime.Format(time.RFC3339)),
+			result: &workitemtracking.WorkItemQueryResult{
+				WorkItems: &[]workitemtracking.WorkItemReference{{Id: testutil.Ptr(7)}},
+			},
+			mockErr: nil,
+			want:    []workitemtracking.WorkItemReference{{Id: testutil.Ptr(7)}},
+			wantErr: false,
+		},
 		{
 			name:  "returns empty when no work items changed",
 			a:     &Azure{},
@@ -115,7 +131,10 @@ func TestGetChangedWorkItems(t *testing.T) {
 					On("QueryByWiql", mock.Anything, mock.MatchedBy(func(args workitemtracking.QueryByWiqlArgs) bool {
 						return args.Wiql != nil && args.Wiql.Query != nil &&
 							*args.Wiql.Query == tt.query &&
-							args.TimePrecision != nil && *args.TimePrecision
+							args.TimePrecision != nil && *args.TimePrecision &&
+							args.Top != nil && *args.Top == defaultWIQLPageSize &&
+							(args.Project == nil) == (tt.args.project == "") &&
+							(args.Project == nil || *args.Project == tt.args.project)
 					})).
 					Return(tt.result, tt.mockErr)
 				tt.a.newWorkItemClient = func(ctx context.Context, conn *azuredevops.Connection) (WIClient, error) {
@@ -123,7 +142,7 @@ func TestGetChangedWorkItems(t *testing.T) {
 				}
 			}
 
-			got, err := tt.a.GetChangedWorkItems(tt.args.ctx, tt.args.lastSync)
+			got, _, err := tt.a.GetChangedWorkItems(tt.args.ctx, tt.args.project, tt.args.lastSync)
 			if tt.wantErr {
 				require.ErrorContains(t, err, tt.errMsg)
 				return
@@ -136,6 +155,95 @@ func TestGetChangedWorkItems(t *testing.T) {
 	}
 }
 
+func TestGetChangedWorkItemsPages(t *testing.T) {
+	t.Parallel()
+	const queryFmt = "SELECT [System.Id], [System.Title], [System.State] FROM workitems WHERE [System.TeamProject] = @project AND [System.ChangedDate] > '%s' AND [System.Id] > %d ORDER BY [System.Id] ASC"
+	lastSync := time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	pages := map[int][]workitemtracking.WorkItemReference{
+		0: {{Id: testutil.Ptr(1)}, {Id: testutil.Ptr(2)}},
+		2: {{Id: testutil.Ptr(5)}, {Id: testutil.Ptr(8)}},
+		8: {{Id: testutil.Ptr(9)}},
+	}
+
+	queried := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	mockWI := new(MockWIClient)
+	for after, refs := range pages {
+		query := fmt.Sprintf(queryFmt, lastSync.Format(time.RFC3339), after)
+		// Later pages run later, but only the first covers every change.
+		asOf := &azuredevops.Time{Time: queried.Add(time.Duration(after) * time.Second)}
+		mockWI.
+			On("QueryByWiql", mock.Anything, mock.MatchedBy(func(args workitemtracking.QueryByWiqlArgs) bool {
+				return *args.Wiql.Query == query && *args.Top == 2 && *args.Project == "Proj"
+			})).
+			Return(&workitemtracking.WorkItemQueryResult{AsOf: asOf, WorkItems: &refs}, nil).
+			Once()
+	}
+	a := &Azure{
+		wiqlPageSize: 2,
+		newWorkItemClient: func(ctx context.Context, conn *azuredevops.Connection) (WIClient, error) {
+			return mockWI, nil
+		},
+	}
+
+	got, asOf, err := a.GetChangedWorkItems(context.Background(), "Proj", lastSync)
+
+	require.NoError(t, err)
+	require.Equal(t, queried, asOf)
+	require.Len(t, got, 5)
+	require.Equal(t, 9, *got[4].Id)
+	mockWI.AssertExpectations(t)
+}
+
+func TestGetChangedWorkItemsFromFirstSync(t *testing.T) {
+	t.Parallel()
+	// Full scans query from the earliest ChangedDate ADO accepts.
+	const query = "SELECT [System.Id], [System.Title], [System.State] FROM workitems WHERE [System.TeamProject] = @project AND [System.ChangedDate] > '1980-01-01T00:00:00Z' AND [System.Id] > 0 ORDER BY [System.Id] ASC"
+	mockWI := new(MockWIClient)
+	mockWI.
+		On("QueryByWiql", mock.Anything, mock.MatchedBy(func(args workitemtracking.QueryByWiqlArgs) bool {
+			return *args.Wiql.Query == query
+		})).
+		Return(&workitemtracking.WorkItemQueryResult{WorkItems: &[]workitemtracking.WorkItemReference{}}, nil).
+		Once()
+	a := &Azure{
+		newWorkItemClient: func(ctx context.Context, conn *azuredevops.Connection) (WIClient, error) {
+			return mockWI, nil
+		},
+	}
+
+	_, _, err := a.GetChangedWorkItems(context.Background(), "Proj", db.FirstSync)
+
+	require.NoError(t, err)
+	mockWI.AssertExpectations(t)
+}
+
+func TestGetChangedWorkItemsStopsOnEmptyPage(t *testing.T) {
+	t.Parallel()
+	mockWI := new(MockWIClient)
+	full := []workitemtracking.WorkItemReference{{Id: testutil.Ptr(1)}, {Id: testutil.Ptr(2)}}
+	empty := []workitemtracking.WorkItemReference{}
+	mockWI.On("QueryByWiql", mock.Anything, mock.MatchedBy(func(args workitemtracking.QueryByWiqlArgs) bool {
+		return strings.Contains(*args.Wiql.Query, "[System.Id] > 0 ")
+	})).Return(&workitemtracking.WorkItemQueryResult{WorkItems: &full}, nil).Once()
+	mockWI.On("QueryByWiql", mock.Anything, mock.MatchedBy(func(args workitemtracking.QueryByWiqlArgs) bool {
+		return strings.Contains(*args.Wiql.Query, "[System.Id] > 2 ")
+	})).Return(&workitemtracking.WorkItemQueryResult{WorkItems: &empty}, nil).Once()
+	a := &Azure{
+		wiqlPageSize: 2,
+		newWorkItemClient: func(ctx context.Context, conn *azuredevops.Connection) (WIClient, error) {
+			return mockWI, nil
+		},
+	}
+
+	got, asOf, err := a.GetChangedWorkItems(context.Background(), "", time.Now())
+
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.True(t, asOf.IsZero(), "no query time without AsOf")
+	mockWI.AssertExpectations(t)
+}
+
 func TestGetProjects(t *testing.T) {
 	t.Parallel() // Enable parallel execution of sub-tests
 	type args struct {
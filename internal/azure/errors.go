@@ -0,0 +1,66 @@
+package azure
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+)
+
+// Errors returned by the client, usable with errors.Is. Failed API calls
+// return an *APIError that matches the sentinel for its status code.
+var (
+	// ErrNotFound reports a work item or project that does not exist or
+	// that the token cannot read.
+	ErrNotFound = errors.New("not found")
+	// ErrUnauthorized reports a rejected or under-privileged token.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrRateLimited reports requests ADO throttled (429).
+	ErrRateLimited = errors.New("rate limited")
+	// ErrValidation reports a request ADO rejected as invalid, such as a
+	// malformed WIQL query.
+	ErrValidation = errors.New("invalid request")
+)
+
+// APIError wraps an error returned by the ADO API with its status code.
+type APIError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *APIError) Error() string { return e.Err.Error() }
+
+func (e *APIError) Unwrap() error { return e.Err }
+
+// Is matches the sentinel error for the status code.
+func (e *APIError) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return target == ErrValidation
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return target == ErrUnauthorized
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	}
+	return false
+}
+
+// wrapError returns err as an *APIError when the ADO client reported the
+// status code of the failed request, and err unchanged otherwise.
+func wrapError(err error) error {
+	var status *int
+	var wrapped azuredevops.WrappedError
+	var wrappedPtr *azuredevops.WrappedError
+	switch {
+	case errors.As(err, &wrappedPtr):
+		status = wrappedPtr.StatusCode
+	case errors.As(err, &wrapped):
+		status = wrapped.StatusCode
+	}
+	if status == nil {
+		return err
+	}
+	return &APIError{StatusCode: *status, Err: err}
+}
@@ -0,0 +1,65 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/testutil"
+)
+
+func TestWrapError(t *testing.T) {
+	msg := "TF401232: Work item 123 does not exist, or you do not have permissions to read it."
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"value", azuredevops.WrappedError{Message: &msg, StatusCode: testutil.Ptr(http.StatusNotFound)}, ErrNotFound},
+		{"pointer", &azuredevops.WrappedError{Message: &msg, StatusCode: testutil.Ptr(http.StatusUnauthorized)}, ErrUnauthorized},
+		{"throttled", azuredevops.WrappedError{Message: &msg, StatusCode: testutil.Ptr(http.StatusTooManyRequests)}, ErrRateLimited},
+		{"bad request", azuredevops.WrappedError{Message: &msg, StatusCode: testutil.Ptr(http.StatusBadRequest)}, ErrValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := wrapError(tt.err)
+			require.ErrorIs(t, err, tt.want)
+			require.Equal(t, msg, err.Error())
+
+			var apiErr *APIError
+			require.ErrorAs(t, err, &apiErr)
+			require.ErrorIs(t, apiErr.Unwrap(), tt.err)
+		})
+	}
+
+	require.Nil(t, wrapError(nil))
+	plain := fmt.Errorf("dial tcp: connection refused")
+	require.Same(t, plain, wrapError(plain))
+	require.False(t, errors.Is(wrapError(azuredevops.WrappedError{StatusCode: testutil.Ptr(http.StatusInternalServerError)}), ErrNotFound))
+}
+
+func TestAzureGetWorkItemNotFound(t *testing.T) {
+	t.Parallel()
+
+	mockWI := new(MockWIClient)
+	mockWI.On("GetWorkItem", mock.Anything, mock.Anything).
+		Return(nil, azuredevops.WrappedError{StatusCode: testutil.Ptr(http.StatusNotFound)})
+	a := &Azure{
+		newWorkItemClient: func(ctx context.Context, c *azuredevops.Connection) (WIClient, error) {
+			return mockWI, nil
+		},
+	}
+
+	_, err := a.GetWorkItem(context.Background(), 123)
+
+	require.ErrorIs(t, err, ErrNotFound)
+	var wrapped azuredevops.WrappedError
+	require.ErrorAs(t, err, &wrapped, "the ADO error stays reachable")
+}
@@ -2,6 +2,7 @@ package azure
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -23,6 +24,7 @@ func TestAzureGetWorkItem(t *testing.T) {
 		"System.AssignedTo":   "bob@example.com",
 		"System.ChangedDate":  time.Now(),
 		"System.CreatedDate":  time.Now(),
+		"System.Parent":       float64(77),
 	}
 	wi := &workitemtracking.WorkItem{
 		Id:     testutil.Ptr(123),
@@ -47,6 +49,7 @@ func TestAzureGetWorkItem(t *testing.T) {
 	require.Equal(t, "Test Item", got.Title)
 	require.Equal(t, "Bug", got.WorkItemType)
 	require.Equal(t, "https://dev.azure.com/org/proj/_workitems/edit/123", got.URL)
+	require.Equal(t, 77, got.ParentID)
 
 	mockWI.AssertExpectations(t)
 }
@@ -61,3 +64,160 @@ func TestAzureGetWorkItemError(t *testing.T) {
 	_, err := a.GetWorkItem(context.Background(), 123)
 	require.Error(t, err)
 }
+
+func TestAzureGetWorkItemIdentityAssignee(t *testing.T) {
+	t.Parallel()
+
+	fields := map[string]interface{}{
+		"System.Title":                   "Test Item",
+		"System.AssignedTo":              map[string]interface{}{"displayName": "Bob Smith", "uniqueName": "bob@example.com"},
+		"System.IterationPath":           "Proj\\Sprint 3",
+		"Microsoft.VSTS.Common.Priority": float64(2),
+	}
+	wi := &workitemtracking.WorkItem{Id: testutil.Ptr(5), Rev: testutil.Ptr(9), Fields: &fields}
+
+	mockWI := new(MockWIClient)
+	mockWI.On("GetWorkItem", mock.Anything, mock.Anything).Return(wi, nil)
+
+	a := &Azure{
+		newWorkItemClient: func(ctx context.Context, c *azuredevops.Connection) (WIClient, error) {
+			return mockWI, nil
+		},
+	}
+
+	got, err := a.GetWorkItem(context.Background(), 5)
+	require.NoError(t, err)
+	require.Equal(t, "Bob Smith", got.AssignedTo)
+	require.Equal(t, 9, got.Rev)
+	require.Equal(t, "Proj\\Sprint 3", got.IterationPath)
+	require.Equal(t, 2, got.Priority)
+}
+
+func TestAzureGetWorkItemRevision(t *testing.T) {
+	t.Parallel()
+
+	fields := map[string]interface{}{
+		"System.Title": "Old Title",
+		"System.State": "New",
+	}
+	wi := &workitemtracking.WorkItem{Id: testutil.Ptr(123), Rev: testutil.Ptr(3), Fields: &fields}
+
+	mockWI := new(MockWIClient)
+	mockWI.On("GetRevision", mock.Anything, mock.MatchedBy(func(args workitemtracking.GetRevisionArgs) bool {
+		return args.Id != nil && *args.Id == 123 && args.RevisionNumber != nil && *args.RevisionNumber == 3
+	})).Return(wi, nil)
+
+	a := &Azure{
+		newWorkItemClient: func(ctx context.Context, c *azuredevops.Connection) (WIClient, error) {
+			return mockWI, nil
+		},
+	}
+
+	got, err := a.GetWorkItemRevision(context.Background(), 123, 3)
+	require.NoError(t, err)
+	require.Equal(t, 3, got.Rev)
+	require.Equal(t, "New", got.State)
+	mockWI.AssertExpectations(t)
+}
+
+func TestAzureGetWorkItemRevisionError(t *testing.T) {
+	t.Parallel()
+
+	mockWI := new(MockWIClient)
+	mockWI.On("GetRevision", mock.Anything, mock.Anything).Return(nil, context.DeadlineExceeded)
+
+	a := &Azure{
+		newWorkItemClient: func(ctx context.Context, c *azuredevops.Connection) (WIClient, error) {
+			return mockWI, nil
+		},
+	}
+
+	_, err := a.GetWorkItemRevision(context.Background(), 123, 3)
+	require.Error(t, err)
+}
+
+func TestAzureGetWorkItemsBatch(t *testing.T) {
+	t.Parallel()
+
+	ids := make([]int, MaxBatchSize+1)
+	for i := range ids {
+		ids[i] = i + 1
+	}
+	itemsFor := func(ids []int) *[]workitemtracking.WorkItem {
+		items := make([]workitemtracking.WorkItem, 0, len(ids))
+		for _, id := range ids {
+			fields := map[string]interface{}{"System.Title": fmt.Sprintf("Item %d", id)}
+			items = append(items, workitemtracking.WorkItem{Id: testutil.Ptr(id), Fields: &fields})
+		}
+		// Items omitted by the error policy are returned as nulls.
+		items = append(items, workitemtracking.WorkItem{})
+		return &items
+	}
+
+	mockWI := new(MockWIClient)
+	mockWI.On("GetWorkItemsBatch", mock.Anything, mock.MatchedBy(func(args workitemtracking.GetWorkItemsBatchArgs) bool {
+		req := args.WorkItemGetRequest
+		return len(*req.Ids) == MaxBatchSize && req.Fields != nil && req.Expand == nil &&
+			*req.ErrorPolicy == workitemtracking.WorkItemErrorPolicyValues.Omit
+	})).Return(itemsFor(ids[:MaxBatchSize]), nil).Once()
+	mockWI.On("GetWorkItemsBatch", mock.Anything, mock.MatchedBy(func(args workitemtracking.GetWorkItemsBatchArgs) bool {
+		return len(*args.WorkItemGetRequest.Ids) == 1
+	})).Return(itemsFor(ids[MaxBatchSize:]), nil).Once()
+
+	a := &Azure{
+		newWorkItemClient: func(ctx context.Context, c *azuredevops.Connection) (WIClient, error) {
+			return mockWI, nil
+		},
+	}
+
+	got, err := a.GetWorkItemsBatch(context.Background(), ids, false)
+	require.NoError(t, err)
+	require.Len(t, got, MaxBatchSize+1)
+	require.Equal(t, 1, got[0].ID)
+	require.Equal(t, fmt.Sprintf("Item %d", MaxBatchSize+1), got[MaxBatchSize].Title)
+	mockWI.AssertExpectations(t)
+}
+
+func TestAzureGetWorkItemsBatchExpandRelations(t *testing.T) {
+	t.Parallel()
+
+	fields := map[string]interface{}{"System.Title": "Parent"}
+	rels := []workitemtracking.WorkItemRelation{{
+		Rel:        testutil.Ptr("Hyperlink"),
+		Url:        testutil.Ptr("https://app.asana.com/0/1/2"),
+		Attributes: &map[string]interface{}{"name": "Asana"},
+	}}
+	items := []workitemtracking.WorkItem{{Id: testutil.Ptr(3), Fields: &fields, Relations: &rels}}
+
+	mockWI := new(MockWIClient)
+	mockWI.On("GetWorkItemsBatch", mock.Anything, mock.MatchedBy(func(args workitemtracking.GetWorkItemsBatchArgs) bool {
+		req := args.WorkItemGetRequest
+		return req.Fields == nil && req.Expand != nil && *req.Expand == workitemtracking.WorkItemExpandValues.Relations
+	})).Return(&items, nil)
+
+	a := &Azure{
+		newWorkItemClient: func(ctx context.Context, c *azuredevops.Connection) (WIClient, error) {
+			return mockWI, nil
+		},
+	}
+
+	got, err := a.GetWorkItemsBatch(context.Background(), []int{3}, true)
+	require.NoError(t, err)
+	require.Equal(t, []Relation{{Rel: "Hyperlink", URL: "https://app.asana.com/0/1/2", Name: "Asana"}}, got[0].Relations)
+}
+
+func TestAzureGetWorkItemsBatchError(t *testing.T) {
+	t.Parallel()
+
+	mockWI := new(MockWIClient)
+	mockWI.On("GetWorkItemsBatch", mock.Anything, mock.Anything).Return(nil, context.DeadlineExceeded)
+
+	a := &Azure{
+		newWorkItemClient: func(ctx context.Context, c *azuredevops.Connection) (WIClient, error) {
+			return mockWI, nil
+		},
+	}
+
+	_, err := a.GetWorkItemsBatch(context.Background(), []int{1}, false)
+	require.Error(t, err)
+}
@@ -35,3 +35,27 @@ func (m *MockWIClient) GetWorkItem(
 	}
 	return wi, ret.Error(1)
 }
+
+func (m *MockWIClient) GetRevision(
+	ctx context.Context,
+	args workitemtracking.GetRevisionArgs,
+) (*workitemtracking.WorkItem, error) {
+	ret := m.Called(ctx, args)
+	var wi *workitemtracking.WorkItem
+	if ret.Get(0) != nil {
+		wi = ret.Get(0).(*workitemtracking.WorkItem)
+	}
+	return wi, ret.Error(1)
+}
+
+func (m *MockWIClient) GetWorkItemsBatch(
+	ctx context.Context,
+	args workitemtracking.GetWorkItemsBatchArgs,
+) (*[]workitemtracking.WorkItem, error) {
+	ret := m.Called(ctx, args)
+	var items *[]workitemtracking.WorkItem
+	if ret.Get(0) != nil {
+		items = ret.Get(0).(*[]workitemtracking.WorkItem)
+	}
+	return items, ret.Error(1)
+}
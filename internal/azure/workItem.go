@@ -3,20 +3,66 @@ package azure
 import (
 	"fmt"
 	"html"
+	"strconv"
 	"time"
 )
 
 // WorkItem represents the fields we care about on an Azure DevOps work item.
 type WorkItem struct {
-	ID           int
-	AssignedTo   string
-	ChangedDate  time.Time
-	CreatedDate  time.Time
-	State        string
-	Title        string
-	URL          string
-	TeamProject  string
-	WorkItemType string
+	ID            int
+	Rev           int
+	AssignedTo    string
+	ChangedDate   time.Time
+	CreatedDate   time.Time
+	State         string
+	Title         string
+	URL           string
+	TeamProject   string
+	WorkItemType  string
+	IterationPath string
+	Priority      int
+	ParentID      int
+	// Relations is only populated when relations were expanded.
+	Relations []Relation
+}
+
+// Relation is a link from a work item to another resource.
+type Relation struct {
+	Rel  string
+	URL  string
+	Name string
+}
+
+// FieldChange describes a tracked field whose value differs between two
+// revisions of a work item.
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// ChangedFields returns the tracked fields (state, assignee, iteration and
+// priority) that differ between the before and after revisions, in that order.
+func ChangedFields(before, after WorkItem) []FieldChange {
+	priority := func(p int) string {
+		if p == 0 {
+			return ""
+		}
+		return strconv.Itoa(p)
+	}
+	tracked := []FieldChange{
+		{Field: "State", Old: before.State, New: after.State},
+		{Field: "Assigned To", Old: before.AssignedTo, New: after.AssignedTo},
+		{Field: "Iteration", Old: before.IterationPath, New: after.IterationPath},
+		{Field: "Priority", Old: priority(before.Priority), New: priority(after.Priority)},
+	}
+	var changes []FieldChange
+	for _, c := range tracked {
+		if c.Old != c.New {
+			changes = append(changes, c)
+		}
+	}
+	return changes
 }
 
 // checkRequiredProperties checks if the given property names are present (non-zero/non-empty) on the WorkItem.
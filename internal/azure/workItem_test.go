@@ -140,3 +140,53 @@ func TestWorkItemFormatTitleWithLink(t *testing.T) {
 		})
 	}
 }
+
+func TestChangedFields(t *testing.T) {
+	t.Parallel()
+	before := WorkItem{State: "New", AssignedTo: "Alice", IterationPath: "Proj\\Sprint 1", Priority: 2}
+	tests := []struct {
+		name  string
+		after WorkItem
+		want  []FieldChange
+	}{
+		{
+			name:  "no changes",
+			after: before,
+			want:  nil,
+		},
+		{
+			name:  "state change",
+			after: WorkItem{State: "Active", AssignedTo: "Alice", IterationPath: "Proj\\Sprint 1", Priority: 2},
+			want:  []FieldChange{{Field: "State", Old: "New", New: "Active"}},
+		},
+		{
+			name:  "all tracked fields",
+			after: WorkItem{State: "Closed", AssignedTo: "", IterationPath: "Proj\\Sprint 2", Priority: 1},
+			want: []FieldChange{
+				{Field: "State", Old: "New", New: "Closed"},
+				{Field: "Assigned To", Old: "Alice", New: ""},
+				{Field: "Iteration", Old: "Proj\\Sprint 1", New: "Proj\\Sprint 2"},
+				{Field: "Priority", Old: "2", New: "1"},
+			},
+		},
+		{
+			name:  "untracked field ignored",
+			after: WorkItem{State: "New", AssignedTo: "Alice", IterationPath: "Proj\\Sprint 1", Priority: 2, Title: "Renamed"},
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := ChangedFields(before, tt.after)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("change %d: expected %v, got %v", i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// AdoptionReviewsCollection is the name of the collection storing the ADO
+// work items matching several existing Asana tasks, waiting for someone to
+// pick one.
+var AdoptionReviewsCollection = "adoption_reviews"
+
+const (
+	// AdoptionDecisionAdopt maps the work item to the chosen Asana task.
+	AdoptionDecisionAdopt = "adopt"
+	// AdoptionDecisionCreate creates a new Asana task for the work item.
+	AdoptionDecisionCreate = "create"
+)
+
+// AdoptionCandidate is an existing Asana task that may belong to a work item,
+// and how it matched.
+type AdoptionCandidate struct {
+	AsanaTaskID string `bson:"asana_task_id" json:"asana_task_id"`
+	Name        string `bson:"name" json:"name"`
+	Match       string `bson:"match" json:"match"`
+}
+
+// AdoptionReview holds a work item whose existing Asana task is ambiguous.
+// The work item is not synced until a decision is made.
+type AdoptionReview struct {
+	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	ADOTaskID      int                 `bson:"ado_task_id" json:"ado_task_id"`
+	ADOProjectName string              `bson:"ado_project_name" json:"ado_project_name"`
+	ADOTitle       string              `bson:"ado_title" json:"ado_title"`
+	ADOURL         string              `bson:"ado_url" json:"ado_url"`
+	AsanaProjectID string              `bson:"asana_project_id" json:"asana_project_id"`
+	Candidates     []AdoptionCandidate `bson:"candidates" json:"candidates"`
+	Decision       string              `bson:"decision,omitempty" json:"decision,omitempty"`
+	AsanaTaskID    string              `bson:"asana_task_id,omitempty" json:"asana_task_id,omitempty"`
+	CreatedAt      time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// AdoptionReviews returns the reviews waiting for a decision, oldest first.
+func (db *DB) AdoptionReviews(ctx context.Context) ([]AdoptionReview, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.AdoptionReviews")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var reviews []AdoptionReview
+	coll := db.Client.Database(DatabaseName).Collection(AdoptionReviewsCollection)
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "created_at", Value: 1}})
+	cursor, err := coll.Find(ctx, bson.M{"decision": bson.M{"$exists": false}}, opts)
+	if err != nil {
+		err = fmt.Errorf("error finding adoption reviews: %v", err)
+		span.RecordError(err)
+		return reviews, err
+	}
+	if err := cursor.All(ctx, &reviews); err != nil {
+		err = fmt.Errorf("error decoding adoption reviews: %v", err)
+		span.RecordError(err)
+		return reviews, err
+	}
+	return reviews, nil
+}
+
+// AdoptionReviewByADOTaskID returns the review for an ADO work item. The
+// boolean reports whether there is one.
+func (db *DB) AdoptionReviewByADOTaskID(ctx context.Context, adoTaskID int) (AdoptionReview, bool, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.AdoptionReviewByADOTaskID")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("ado_task_id", adoTaskID))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var review AdoptionReview
+	coll := db.Client.Database(DatabaseName).Collection(AdoptionReviewsCollection)
+	err := coll.FindOne(ctx, bson.M{"ado_task_id": adoTaskID}).Decode(&review)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return review, false, nil
+	}
+	if err != nil {
+		err = fmt.Errorf("error finding adoption review: %v", err)
+		span.RecordError(err)
+		return review, false, err
+	}
+	return review, true, nil
+}
+
+// AddAdoptionReview records the candidates for a work item, replacing those
+// of an earlier review still waiting for a decision.
+func (db *DB) AddAdoptionReview(ctx context.Context, review AdoptionReview) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.AddAdoptionReview")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("ado_task_id", review.ADOTaskID),
+		attribute.Int("candidates", len(review.Candidates)),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	now := time.Now()
+	coll := db.Client.Database(DatabaseName).Collection(AdoptionReviewsCollection)
+	update := bson.M{
+		"$set": bson.M{
+			"ado_project_name": review.ADOProjectName,
+			"ado_title":        review.ADOTitle,
+			"ado_url":          review.ADOURL,
+			"asana_project_id": review.AsanaProjectID,
+			"candidates":       review.Candidates,
+			"updated_at":       now,
+		},
+		"$unset":       bson.M{"decision": "", "asana_task_id": ""},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	_, err := coll.UpdateOne(ctx, bson.M{"ado_task_id": review.ADOTaskID}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		err = fmt.Errorf("error recording adoption review: %v", err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// DecideAdoptionReview records the decision for a work item's review and
+// queues the work item so a worker applies it. asanaTaskID is the chosen
+// task for AdoptionDecisionAdopt and ignored for AdoptionDecisionCreate.
+func (db *DB) DecideAdoptionReview(ctx context.Context, adoTaskID int, decision, asanaTaskID string) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.DecideAdoptionReview")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("ado_task_id", adoTaskID), attribute.String("decision", decision))
+
+	set := bson.M{"decision": decision, "updated_at": time.Now()}
+	switch decision {
+	case AdoptionDecisionAdopt:
+		if asanaTaskID == "" {
+			err := fmt.Errorf("an Asana task is required to adopt")
+			span.RecordError(err)
+			return err
+		}
+		set["asana_task_id"] = asanaTaskID
+	case AdoptionDecisionCreate:
+		set["asana_task_id"] = ""
+	default:
+		err := fmt.Errorf("unknown adoption decision %q", decision)
+		span.RecordError(err)
+		return err
+	}
+
+	dbCtx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var review AdoptionReview
+	coll := db.Client.Database(DatabaseName).Collection(AdoptionReviewsCollection)
+	filter := bson.M{"ado_task_id": adoTaskID}
+	if decision == AdoptionDecisionAdopt {
+		// Only a listed candidate can be adopted.
+		filter["candidates.asana_task_id"] = asanaTaskID
+	}
+	err := coll.FindOneAndUpdate(dbCtx, filter, bson.M{"$set": set}).Decode(&review)
+	if err != nil {
+		err = fmt.Errorf("error deciding adoption review: %v", err)
+		span.RecordError(err)
+		return err
+	}
+
+	return db.EnqueueJob(ctx, adoTaskID, review.ADOProjectName, time.Time{})
+}
+
+// RemoveAdoptionReview removes the review for an ADO work item.
+func (db *DB) RemoveAdoptionReview(ctx context.Context, adoTaskID int) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.RemoveAdoptionReview")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("ado_task_id", adoTaskID))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	coll := db.Client.Database(DatabaseName).Collection(AdoptionReviewsCollection)
+	if _, err := coll.DeleteOne(ctx, bson.M{"ado_task_id": adoTaskID}); err != nil {
+		err = fmt.Errorf("error removing adoption review: %v", err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// AsanaEventSyncsCollection is the name of the collection storing the sync
+// tokens the events of the mapped Asana projects are polled with.
+var AsanaEventSyncsCollection = "asana_event_syncs"
+
+// AsanaEventSync is where polling the events of a mapped Asana project left
+// off.
+type AsanaEventSync struct {
+	AsanaProjectGID string `bson:"asana_project_gid" json:"asana_project_gid"`
+	// SyncToken returns the events following the last ones read.
+	SyncToken string    `bson:"sync_token" json:"-"`
+	PolledAt  time.Time `bson:"polled_at" json:"polled_at"`
+}
+
+// AsanaEventSyncs returns the sync tokens of the polled Asana projects.
+func (db *DB) AsanaEventSyncs(ctx context.Context) ([]AsanaEventSync, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.AsanaEventSyncs")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var syncs []AsanaEventSync
+	coll := db.Client.Database(DatabaseName).Collection(AsanaEventSyncsCollection)
+	cursor, err := coll.Find(ctx, bson.M{})
+	if err != nil {
+		err = fmt.Errorf("error finding Asana event syncs: %v", err)
+		span.RecordError(err)
+		return syncs, err
+	}
+	if err := cursor.All(ctx, &syncs); err != nil {
+		err = fmt.Errorf("error decoding Asana event syncs: %v", err)
+		span.RecordError(err)
+		return syncs, err
+	}
+	return syncs, nil
+}
+
+// WriteAsanaSyncToken records the token to poll the next events of the
+// Asana project with.
+func (db *DB) WriteAsanaSyncToken(ctx context.Context, projectGID, token string) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.WriteAsanaSyncToken")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("asana_project_gid", projectGID))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"sync_token": token, "polled_at": time.Now()}}
+	coll := db.Client.Database(DatabaseName).Collection(AsanaEventSyncsCollection)
+	_, err := coll.UpdateOne(ctx, bson.M{"asana_project_gid": projectGID}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		err = fmt.Errorf("error writing Asana sync token: %v", err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// RemoveAsanaEventSync removes the sync token of an Asana project no longer
+// polled.
+func (db *DB) RemoveAsanaEventSync(ctx context.Context, projectGID string) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.RemoveAsanaEventSync")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("asana_project_gid", projectGID))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	coll := db.Client.Database(DatabaseName).Collection(AsanaEventSyncsCollection)
+	if _, err := coll.DeleteOne(ctx, bson.M{"asana_project_gid": projectGID}); err != nil {
+		err = fmt.Errorf("error removing Asana event sync: %v", err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
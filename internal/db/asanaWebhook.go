@@ -0,0 +1,196 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// AsanaWebhooksCollection is the name of the collection storing the Asana
+// webhooks of the mapped Asana projects.
+var AsanaWebhooksCollection = "asana_webhooks"
+
+// AsanaWebhook is the webhook delivering the events of a mapped Asana
+// project. A webhook is requested first, then receives its secret in the
+// handshake Asana makes while creating it, and only then gets its GID.
+type AsanaWebhook struct {
+	AsanaProjectGID string `bson:"asana_project_gid" json:"asana_project_gid"`
+	// WebhookGID is empty until Asana created the webhook.
+	WebhookGID string `bson:"webhook_gid,omitempty" json:"webhook_gid,omitempty"`
+	Target     string `bson:"target" json:"target"`
+	// Secret signs the events delivered; it is never shown.
+	Secret      string    `bson:"secret,omitempty" json:"-"`
+	RequestedAt time.Time `bson:"requested_at" json:"requested_at"`
+	CreatedAt   time.Time `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	// LastEventAt is when events or a heartbeat were last delivered.
+	LastEventAt time.Time `bson:"last_event_at,omitempty" json:"last_event_at,omitempty"`
+}
+
+// AsanaWebhooks returns the Asana webhooks, requested or created.
+func (db *DB) AsanaWebhooks(ctx context.Context) ([]AsanaWebhook, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.AsanaWebhooks")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var hooks []AsanaWebhook
+	coll := db.Client.Database(DatabaseName).Collection(AsanaWebhooksCollection)
+	cursor, err := coll.Find(ctx, bson.M{})
+	if err != nil {
+		err = fmt.Errorf("error finding Asana webhooks: %v", err)
+		span.RecordError(err)
+		return hooks, err
+	}
+	if err := cursor.All(ctx, &hooks); err != nil {
+		err = fmt.Errorf("error decoding Asana webhooks: %v", err)
+		span.RecordError(err)
+		return hooks, err
+	}
+	return hooks, nil
+}
+
+// AsanaWebhook returns the webhook of the Asana project. The boolean
+// reports whether there is one.
+func (db *DB) AsanaWebhook(ctx context.Context, projectGID string) (AsanaWebhook, bool, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.AsanaWebhook")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("asana_project_gid", projectGID))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var hook AsanaWebhook
+	coll := db.Client.Database(DatabaseName).Collection(AsanaWebhooksCollection)
+	err := coll.FindOne(ctx, bson.M{"asana_project_gid": projectGID}).Decode(&hook)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return hook, false, nil
+	}
+	if err != nil {
+		err = fmt.Errorf("error finding Asana webhook: %v", err)
+		span.RecordError(err)
+		return hook, false, err
+	}
+	return hook, true, nil
+}
+
+// RequestAsanaWebhook records that a webhook is about to be created for the
+// Asana project, replacing the previous one, so its handshake is accepted.
+func (db *DB) RequestAsanaWebhook(ctx context.Context, projectGID, target string) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.RequestAsanaWebhook")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("asana_project_gid", projectGID))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	update := bson.M{
+		"$set":   bson.M{"target": target, "requested_at": time.Now()},
+		"$unset": bson.M{"webhook_gid": "", "secret": "", "created_at": "", "last_event_at": ""},
+	}
+	coll := db.Client.Database(DatabaseName).Collection(AsanaWebhooksCollection)
+	_, err := coll.UpdateOne(ctx, bson.M{"asana_project_gid": projectGID}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		err = fmt.Errorf("error requesting Asana webhook: %v", err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// SetAsanaWebhookSecret stores the secret sent in the handshake of the
+// webhook of the Asana project. It only succeeds for a webhook requested
+// since the given time that has no secret yet, and reports whether it did.
+func (db *DB) SetAsanaWebhookSecret(ctx context.Context, projectGID, secret string, since time.Time) (bool, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.SetAsanaWebhookSecret")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("asana_project_gid", projectGID))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	filter := bson.M{
+		"asana_project_gid": projectGID,
+		"secret":            bson.M{"$exists": false},
+		"requested_at":      bson.M{"$gte": since},
+	}
+	coll := db.Client.Database(DatabaseName).Collection(AsanaWebhooksCollection)
+	res, err := coll.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"secret": secret}})
+	if err != nil {
+		err = fmt.Errorf("error storing Asana webhook secret: %v", err)
+		span.RecordError(err)
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// WriteAsanaWebhookGID records the GID of the webhook Asana created for the
+// project.
+func (db *DB) WriteAsanaWebhookGID(ctx context.Context, projectGID, webhookGID string) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.WriteAsanaWebhookGID")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("asana_project_gid", projectGID), attribute.String("webhook_gid", webhookGID))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"webhook_gid": webhookGID, "created_at": time.Now()}}
+	coll := db.Client.Database(DatabaseName).Collection(AsanaWebhooksCollection)
+	if _, err := coll.UpdateOne(ctx, bson.M{"asana_project_gid": projectGID}, update); err != nil {
+		err = fmt.Errorf("error writing Asana webhook GID: %v", err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// TouchAsanaWebhook records that the webhook of the project delivered
+// events or a heartbeat.
+func (db *DB) TouchAsanaWebhook(ctx context.Context, projectGID string) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.TouchAsanaWebhook")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("asana_project_gid", projectGID))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"last_event_at": time.Now()}}
+	coll := db.Client.Database(DatabaseName).Collection(AsanaWebhooksCollection)
+	if _, err := coll.UpdateOne(ctx, bson.M{"asana_project_gid": projectGID}, update); err != nil {
+		err = fmt.Errorf("error touching Asana webhook: %v", err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// RemoveAsanaWebhook removes the webhook of the Asana project.
+func (db *DB) RemoveAsanaWebhook(ctx context.Context, projectGID string) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.RemoveAsanaWebhook")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("asana_project_gid", projectGID))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	coll := db.Client.Database(DatabaseName).Collection(AsanaWebhooksCollection)
+	if _, err := coll.DeleteOne(ctx, bson.M{"asana_project_gid": projectGID}); err != nil {
+		err = fmt.Errorf("error removing Asana webhook: %v", err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
@@ -62,3 +62,20 @@ func (db *DB) UpsertCacheItem(ctx context.Context, item CacheItem) error {
 	}
 	return nil
 }
+
+// DeleteCacheItem removes the cached item with the given key, if any.
+func (db *DB) DeleteCacheItem(ctx context.Context, key string) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.DeleteCacheItem")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	coll := db.Client.Database(DatabaseName).Collection(CacheCollection)
+	if _, err := coll.DeleteOne(ctx, bson.M{"key": key}); err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SyncCheckpointsCollection is the name of the collection storing the sync
+// watermark of each mapped ADO project.
+var SyncCheckpointsCollection = "sync_checkpoints"
+
+// SyncCheckpoint is the newest ADO ChangedDate synced for a project, and
+// when the project was last and will next be synced.
+type SyncCheckpoint struct {
+	ADOProjectName string    `bson:"ado_project_name" json:"ado_project_name"`
+	Time           time.Time `bson:"time" json:"time"`
+	LastRunAt      time.Time `bson:"last_run_at,omitempty" json:"last_run_at,omitempty"`
+	NextRunAt      time.Time `bson:"next_run_at,omitempty" json:"next_run_at,omitempty"`
+	UpdatedAt      time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// SyncCheckpoint retrieves the checkpoint for the given ADO project. Projects
+// without a checkpoint start from the global last sync time, so upgrading from
+// a single watermark does not trigger a full re-import. So do projects whose
+// runs were recorded before any change was synced. Other read errors are
+// returned, as starting over from the last sync time would query the whole
+// history of the project again.
+func (db *DB) SyncCheckpoint(ctx context.Context, project string) (SyncCheckpoint, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.SyncCheckpoint")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("ado_project_name", project))
+
+	findCtx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var cp SyncCheckpoint
+	coll := db.Client.Database(DatabaseName).Collection(SyncCheckpointsCollection)
+	err := coll.FindOne(findCtx, bson.M{"ado_project_name": project}).Decode(&cp)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		cp = SyncCheckpoint{ADOProjectName: project}
+	case err != nil:
+		err = fmt.Errorf("error finding sync checkpoint: %w", err)
+		span.RecordError(err)
+		return SyncCheckpoint{}, err
+	}
+	if cp.Time.IsZero() {
+		cp.Time = db.LastSync(ctx).Time
+	}
+	return cp, nil
+}
+
+// WriteSyncCheckpoint stores the checkpoint for the given ADO project.
+func (db *DB) WriteSyncCheckpoint(ctx context.Context, project string, timestamp time.Time) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.WriteSyncCheckpoint")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("ado_project_name", project))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	coll := db.Client.Database(DatabaseName).Collection(SyncCheckpointsCollection)
+	update := bson.M{"$set": bson.M{"time": timestamp, "updated_at": time.Now()}}
+	_, err := coll.UpdateOne(ctx, bson.M{"ado_project_name": project}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		err = fmt.Errorf("error updating sync checkpoint: %v", err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// WriteSyncRun records when the given ADO project was last synced and when
+// it is next due.
+func (db *DB) WriteSyncRun(ctx context.Context, project string, lastRun, nextRun time.Time) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.WriteSyncRun")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("ado_project_name", project))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	coll := db.Client.Database(DatabaseName).Collection(SyncCheckpointsCollection)
+	update := bson.M{"$set": bson.M{"last_run_at": lastRun, "next_run_at": nextRun, "updated_at": time.Now()}}
+	_, err := coll.UpdateOne(ctx, bson.M{"ado_project_name": project}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		err = fmt.Errorf("error updating sync run: %v", err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// SyncCheckpoints returns the checkpoints of every ADO project synced so far.
+func (db *DB) SyncCheckpoints(ctx context.Context) ([]SyncCheckpoint, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.SyncCheckpoints")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var cps []SyncCheckpoint
+	coll := db.Client.Database(DatabaseName).Collection(SyncCheckpointsCollection)
+	cursor, err := coll.Find(ctx, bson.M{})
+	if err != nil {
+		err = fmt.Errorf("error finding sync checkpoints: %v", err)
+		span.RecordError(err)
+		return cps, err
+	}
+	if err := cursor.All(ctx, &cps); err != nil {
+		err = fmt.Errorf("error decoding sync checkpoints: %v", err)
+		span.RecordError(err)
+		return cps, err
+	}
+	return cps, nil
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ConflictsCollection is the name of the collection storing the fields
+// edited both in ADO and in Asana, waiting for someone to pick a side.
+var ConflictsCollection = "conflicts"
+
+// Conflict is a task field edited in Asana since it was last synced while
+// ADO has a new value for it. The field is not overwritten until the
+// conflict is resolved.
+type Conflict struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ADOTaskID      int                `bson:"ado_task_id" json:"ado_task_id"`
+	ADOProjectName string             `bson:"ado_project_name" json:"ado_project_name"`
+	AsanaTaskID    string             `bson:"asana_task_id" json:"asana_task_id"`
+	Field          string             `bson:"field" json:"field"`
+	ADOValue       string             `bson:"ado_value" json:"ado_value"`
+	AsanaValue     string             `bson:"asana_value" json:"asana_value"`
+	DetectedAt     time.Time          `bson:"detected_at" json:"detected_at"`
+}
+
+// Conflicts returns the unresolved conflicts, most recent first.
+func (db *DB) Conflicts(ctx context.Context) ([]Conflict, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.Conflicts")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var conflicts []Conflict
+	coll := db.Client.Database(DatabaseName).Collection(ConflictsCollection)
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "detected_at", Value: -1}})
+	cursor, err := coll.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		err = fmt.Errorf("error finding conflicts: %v", err)
+		span.RecordError(err)
+		return conflicts, err
+	}
+	if err := cursor.All(ctx, &conflicts); err != nil {
+		err = fmt.Errorf("error decoding conflicts: %v", err)
+		span.RecordError(err)
+		return conflicts, err
+	}
+	return conflicts, nil
+}
+
+// AddConflict records a conflict, replacing an unresolved one for the same
+// field of the work item.
+func (db *DB) AddConflict(ctx context.Context, c Conflict) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.AddConflict")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("ado_task_id", c.ADOTaskID), attribute.String("field", c.Field))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	coll := db.Client.Database(DatabaseName).Collection(ConflictsCollection)
+	update := bson.M{
+		"$set": bson.M{
+			"ado_project_name": c.ADOProjectName,
+			"asana_task_id":    c.AsanaTaskID,
+			"ado_value":        c.ADOValue,
+			"asana_value":      c.AsanaValue,
+			"detected_at":      time.Now(),
+		},
+	}
+	filter := bson.M{"ado_task_id": c.ADOTaskID, "field": c.Field}
+	if _, err := coll.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+		err = fmt.Errorf("error recording conflict: %v", err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// ResolveConflict removes the conflict on a field of the work item. When
+// useADO is set the field is synced from ADO again and the work item is
+// queued to be resynced in full; otherwise the Asana value is kept.
+func (db *DB) ResolveConflict(ctx context.Context, adoTaskID int, field string, useADO bool) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.ResolveConflict")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("ado_task_id", adoTaskID), attribute.String("field", field), attribute.Bool("use_ado", useADO))
+
+	dbCtx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	if useADO {
+		tasks := db.Client.Database(DatabaseName).Collection(TasksCollection)
+		update := bson.M{"$pull": bson.M{"asana_owned_fields": field}}
+		if _, err := tasks.UpdateOne(dbCtx, bson.M{"ado_task_id": adoTaskID}, update); err != nil {
+			err = fmt.Errorf("error releasing field to ADO: %v", err)
+			span.RecordError(err)
+			return err
+		}
+	}
+
+	coll := db.Client.Database(DatabaseName).Collection(ConflictsCollection)
+	if _, err := coll.DeleteOne(dbCtx, bson.M{"ado_task_id": adoTaskID, "field": field}); err != nil {
+		err = fmt.Errorf("error removing conflict: %v", err)
+		span.RecordError(err)
+		return err
+	}
+
+	if useADO {
+		return db.ForceResync(ctx, adoTaskID)
+	}
+	return nil
+}
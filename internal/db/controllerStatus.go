@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ControllerStatusCollection is the name of the collection storing the
+// health of the controller, as reported by the replica running it.
+var ControllerStatusCollection = "controller_status"
+
+const (
+	// ControllerHealthy means the last controller cycles succeeded.
+	ControllerHealthy = "healthy"
+	// ControllerDegraded means several controller cycles in a row failed
+	// and the next ones are backing off.
+	ControllerDegraded = "degraded"
+)
+
+// ControllerStatus is the health of the controller after its last cycle.
+type ControllerStatus struct {
+	State               string    `bson:"state" json:"state"`
+	Holder              string    `bson:"holder" json:"holder"`
+	ConsecutiveFailures int       `bson:"consecutive_failures" json:"consecutive_failures"`
+	LastError           string    `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	LastSuccessAt       time.Time `bson:"last_success_at" json:"last_success_at"`
+	LastFailureAt       time.Time `bson:"last_failure_at" json:"last_failure_at"`
+	NextRunAt           time.Time `bson:"next_run_at" json:"next_run_at"`
+	UpdatedAt           time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// ControllerStatus returns the controller health. The boolean reports
+// whether a controller has reported it yet.
+func (db *DB) ControllerStatus(ctx context.Context) (ControllerStatus, bool, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.ControllerStatus")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var status ControllerStatus
+	coll := db.Client.Database(DatabaseName).Collection(ControllerStatusCollection)
+	err := coll.FindOne(ctx, bson.D{}).Decode(&status)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return status, false, nil
+	}
+	if err != nil {
+		err = fmt.Errorf("error finding controller status: %v", err)
+		span.RecordError(err)
+		return status, false, err
+	}
+	return status, true, nil
+}
+
+// WriteControllerStatus replaces the controller health.
+func (db *DB) WriteControllerStatus(ctx context.Context, status ControllerStatus) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.WriteControllerStatus")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("state", status.State),
+		attribute.Int("consecutive_failures", status.ConsecutiveFailures),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	status.UpdatedAt = time.Now()
+	coll := db.Client.Database(DatabaseName).Collection(ControllerStatusCollection)
+	_, err := coll.ReplaceOne(ctx, bson.D{}, status, options.Replace().SetUpsert(true))
+	if err != nil {
+		err = fmt.Errorf("error writing controller status: %v", err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
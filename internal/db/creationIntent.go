@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CreationIntentsCollection is the name of the collection storing the Asana
+// tasks being created, so a creation interrupted before its task mapping was
+// saved can be recovered instead of repeated.
+var CreationIntentsCollection = "creation_intents"
+
+// CreationIntent records that an Asana task is being created for an ADO work
+// item. It is written before the task is created and removed once the task
+// mapping is saved. The task is found again by the work item link it
+// carries.
+type CreationIntent struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ADOTaskID      int                `bson:"ado_task_id" json:"ado_task_id"`
+	ADOProjectID   string             `bson:"ado_project_id" json:"ado_project_id"`
+	AsanaProjectID string             `bson:"asana_project_id" json:"asana_project_id"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// CreationIntent returns the pending creation for an ADO work item. The
+// boolean reports whether there is one.
+func (db *DB) CreationIntent(ctx context.Context, adoTaskID int) (CreationIntent, bool, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.CreationIntent")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("ado_task_id", adoTaskID))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var intent CreationIntent
+	coll := db.Client.Database(DatabaseName).Collection(CreationIntentsCollection)
+	err := coll.FindOne(ctx, bson.M{"ado_task_id": adoTaskID}).Decode(&intent)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return intent, false, nil
+	}
+	if err != nil {
+		err = fmt.Errorf("error finding creation intent: %v", err)
+		span.RecordError(err)
+		return intent, false, err
+	}
+	return intent, true, nil
+}
+
+// AddCreationIntent records a pending creation, replacing any earlier one
+// for the same work item.
+func (db *DB) AddCreationIntent(ctx context.Context, intent CreationIntent) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.AddCreationIntent")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("ado_task_id", intent.ADOTaskID),
+		attribute.String("asana_project_id", intent.AsanaProjectID),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	coll := db.Client.Database(DatabaseName).Collection(CreationIntentsCollection)
+	update := bson.M{
+		"$set": bson.M{
+			"ado_project_id":   intent.ADOProjectID,
+			"asana_project_id": intent.AsanaProjectID,
+			"created_at":       time.Now(),
+		},
+	}
+	_, err := coll.UpdateOne(ctx, bson.M{"ado_task_id": intent.ADOTaskID}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		err = fmt.Errorf("error recording creation intent: %v", err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// RemoveCreationIntent removes the pending creation for an ADO work item.
+func (db *DB) RemoveCreationIntent(ctx context.Context, adoTaskID int) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.RemoveCreationIntent")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("ado_task_id", adoTaskID))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	coll := db.Client.Database(DatabaseName).Collection(CreationIntentsCollection)
+	if _, err := coll.DeleteOne(ctx, bson.M{"ado_task_id": adoTaskID}); err != nil {
+		err = fmt.Errorf("error removing creation intent: %v", err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
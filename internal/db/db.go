@@ -41,13 +41,63 @@ type DBInterface interface {
 	UpdateProject(ctx context.Context, project Project) error
 	LastSync(ctx context.Context) LastSync
 	WriteLastSync(ctx context.Context, timestamp time.Time) error
+	SyncCheckpoint(ctx context.Context, project string) (SyncCheckpoint, error)
+	WriteSyncCheckpoint(ctx context.Context, project string, timestamp time.Time) error
+	WriteSyncRun(ctx context.Context, project string, lastRun, nextRun time.Time) error
+	SyncCheckpoints(ctx context.Context) ([]SyncCheckpoint, error)
+	EnqueueJob(ctx context.Context, adoTaskID int, project string, changed time.Time) error
+	LeaseJobs(ctx context.Context, owner string, shard Shard, limit int, lease time.Duration) ([]Job, error)
+	CompleteJob(ctx context.Context, job Job) error
+	FailJob(ctx context.Context, job Job, class, cause string, retryAt time.Time) error
+	ReturnJob(ctx context.Context, job Job) error
+	DeadLetterJob(ctx context.Context, job Job, class, cause string) error
+	DeadLetters(ctx context.Context) ([]DeadLetter, error)
+	RetryDeadLetter(ctx context.Context, adoTaskID int) error
+	IgnoreDeadLetter(ctx context.Context, adoTaskID int) error
+	ForceResync(ctx context.Context, adoTaskID int) error
+	CheckAsanaTask(ctx context.Context, adoTaskID int, project string) error
+	AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, name, holder string) error
+	Tasks(ctx context.Context, projectIDs ...string) ([]TaskMapping, error)
 	TaskByADOTaskID(ctx context.Context, id int) (TaskMapping, error)
+	TaskByAsanaTaskID(ctx context.Context, gid string) (TaskMapping, bool, error)
 	AddTask(ctx context.Context, task TaskMapping) error
 	UpdateTask(ctx context.Context, task TaskMapping) error
+	RemoveTask(ctx context.Context, id primitive.ObjectID) error
+	RenameTasksProject(ctx context.Context, from, to string) error
+	CreationIntent(ctx context.Context, adoTaskID int) (CreationIntent, bool, error)
+	AddCreationIntent(ctx context.Context, intent CreationIntent) error
+	RemoveCreationIntent(ctx context.Context, adoTaskID int) error
+	ControllerStatus(ctx context.Context) (ControllerStatus, bool, error)
+	WriteControllerStatus(ctx context.Context, status ControllerStatus) error
+	Conflicts(ctx context.Context) ([]Conflict, error)
+	AddConflict(ctx context.Context, c Conflict) error
+	ResolveConflict(ctx context.Context, adoTaskID int, field string, useADO bool) error
+	AdoptionReviews(ctx context.Context) ([]AdoptionReview, error)
+	AdoptionReviewByADOTaskID(ctx context.Context, adoTaskID int) (AdoptionReview, bool, error)
+	AddAdoptionReview(ctx context.Context, review AdoptionReview) error
+	DecideAdoptionReview(ctx context.Context, adoTaskID int, decision, asanaTaskID string) error
+	RemoveAdoptionReview(ctx context.Context, adoTaskID int) error
 	GetCacheItem(ctx context.Context, key string) (CacheItem, error)
 	UpsertCacheItem(ctx context.Context, item CacheItem) error
+	DeleteCacheItem(ctx context.Context, key string) error
 	WorkspaceTag(ctx context.Context, workspaceName string) (WorkspaceTag, error)
 	UpsertWorkspaceTag(ctx context.Context, tag WorkspaceTag) error
+	WorkItemProjectByADOID(ctx context.Context, id int) (WorkItemProject, error)
+	UpsertWorkItemProject(ctx context.Context, p WorkItemProject) error
+	AsanaWebhooks(ctx context.Context) ([]AsanaWebhook, error)
+	AsanaWebhook(ctx context.Context, projectGID string) (AsanaWebhook, bool, error)
+	RequestAsanaWebhook(ctx context.Context, projectGID, target string) error
+	SetAsanaWebhookSecret(ctx context.Context, projectGID, secret string, since time.Time) (bool, error)
+	WriteAsanaWebhookGID(ctx context.Context, projectGID, webhookGID string) error
+	TouchAsanaWebhook(ctx context.Context, projectGID string) error
+	RemoveAsanaWebhook(ctx context.Context, projectGID string) error
+	AsanaEventSyncs(ctx context.Context) ([]AsanaEventSync, error)
+	WriteAsanaSyncToken(ctx context.Context, projectGID, token string) error
+	RemoveAsanaEventSync(ctx context.Context, projectGID string) error
+	Reconciliations(ctx context.Context) ([]Reconciliation, error)
+	RequestReconciliation(ctx context.Context, adoProjectName string, repair bool) error
+	WriteReconciliation(ctx context.Context, rec Reconciliation) error
 }
 
 type DB struct {
@@ -122,5 +172,130 @@ func (db *DB) EnsureIndexes(ctx context.Context) error {
 		return fmt.Errorf("error creating project index: %v", err)
 	}
 
+	coll = db.Client.Database(DatabaseName).Collection(WorkItemProjectsCollection)
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{bson.E{Key: "ado_work_item_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("error creating work item project index: %v", err)
+	}
+
+	coll = db.Client.Database(DatabaseName).Collection(SyncCheckpointsCollection)
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{bson.E{Key: "ado_project_name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("error creating sync checkpoint index: %v", err)
+	}
+
+	coll = db.Client.Database(DatabaseName).Collection(JobsCollection)
+	_, err = coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{bson.E{Key: "ado_task_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{bson.E{Key: "state", Value: 1}, bson.E{Key: "visible_at", Value: 1}},
+		},
+	})
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("error creating job indexes: %v", err)
+	}
+
+	coll = db.Client.Database(DatabaseName).Collection(DeadLettersCollection)
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{bson.E{Key: "ado_task_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("error creating dead letter index: %v", err)
+	}
+
+	coll = db.Client.Database(DatabaseName).Collection(CreationIntentsCollection)
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{bson.E{Key: "ado_task_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("error creating creation intent index: %v", err)
+	}
+
+	coll = db.Client.Database(DatabaseName).Collection(AdoptionReviewsCollection)
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{bson.E{Key: "ado_task_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("error creating adoption review index: %v", err)
+	}
+
+	coll = db.Client.Database(DatabaseName).Collection(ConflictsCollection)
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{bson.E{Key: "ado_task_id", Value: 1}, bson.E{Key: "field", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("error creating conflict index: %v", err)
+	}
+
+	coll = db.Client.Database(DatabaseName).Collection(TasksCollection)
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{bson.E{Key: "asana_task_id", Value: 1}},
+	})
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("error creating task index: %v", err)
+	}
+
+	coll = db.Client.Database(DatabaseName).Collection(AsanaWebhooksCollection)
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{bson.E{Key: "asana_project_gid", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("error creating Asana webhook index: %v", err)
+	}
+
+	coll = db.Client.Database(DatabaseName).Collection(AsanaEventSyncsCollection)
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{bson.E{Key: "asana_project_gid", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("error creating Asana event sync index: %v", err)
+	}
+
+	coll = db.Client.Database(DatabaseName).Collection(ReconciliationsCollection)
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{bson.E{Key: "ado_project_name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("error creating reconciliation index: %v", err)
+	}
+
 	return nil
 }
@@ -30,3 +30,24 @@ func TestGetMaxPoolSizeInvalid(t *testing.T) {
 		t.Errorf("expected %d, got %d", expected, got)
 	}
 }
+
+func TestShardFilter(t *testing.T) {
+	if f := (Shard{}).filter(); len(f) != 0 {
+		t.Errorf("expected no filter without sharding, got %v", f)
+	}
+	if _, ok := (Shard{Count: 3, Index: 0}).filter()["$or"]; !ok {
+		t.Errorf("expected the first shard to include jobs without a shard key")
+	}
+	if _, ok := (Shard{Count: 3, Index: 1}).filter()["shard_key"]; !ok {
+		t.Errorf("expected a shard key filter")
+	}
+}
+
+func TestShardKeyStable(t *testing.T) {
+	if ShardKey("Project") != ShardKey("Project") {
+		t.Errorf("expected the same key for the same project")
+	}
+	if ShardKey("Project") < 0 {
+		t.Errorf("expected a non-negative key")
+	}
+}
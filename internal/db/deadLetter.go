@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DeadLettersCollection is the name of the collection storing the work items
+// that stopped being retried.
+var DeadLettersCollection = "dead_letters"
+
+// DeadLetter is a work item whose sync failed permanently or ran out of
+// attempts. It stays listed in the web UI until it is retried, ignored, or
+// synced after a later change.
+type DeadLetter struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ADOTaskID      int                `bson:"ado_task_id" json:"ado_task_id"`
+	ADOProjectName string             `bson:"ado_project_name" json:"ado_project_name"`
+	ErrorClass     string             `bson:"error_class" json:"error_class"`
+	Error          string             `bson:"error" json:"error"`
+	Attempts       int                `bson:"attempts" json:"attempts"`
+	DeadAt         time.Time          `bson:"dead_at" json:"dead_at"`
+}
+
+// DeadLetters returns the dead letters, most recent first.
+func (db *DB) DeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.DeadLetters")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var letters []DeadLetter
+	coll := db.Client.Database(DatabaseName).Collection(DeadLettersCollection)
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "dead_at", Value: -1}})
+	cursor, err := coll.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		err = fmt.Errorf("error finding dead letters: %v", err)
+		span.RecordError(err)
+		return letters, err
+	}
+	if err := cursor.All(ctx, &letters); err != nil {
+		err = fmt.Errorf("error decoding dead letters: %v", err)
+		span.RecordError(err)
+		return letters, err
+	}
+	return letters, nil
+}
+
+// DeadLetterJob records a leased job in the dead-letter queue and marks it
+// failed. The dead letter is recorded first so a failed job is never left
+// without one. A job whose work item changed while it was leased is queued
+// again instead, and its dead letter removed; if that fails, the dead letter
+// is removed once the job completes.
+func (db *DB) DeadLetterJob(ctx context.Context, job Job, class, cause string) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.DeadLetterJob")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("ado_task_id", job.ADOTaskID), attribute.String("error_class", class))
+
+	letterCtx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	letter := bson.M{
+		"ado_project_name": job.ADOProjectName,
+		"error_class":      class,
+		"error":            cause,
+		"attempts":         job.Attempts,
+		"dead_at":          time.Now(),
+	}
+	coll := db.Client.Database(DatabaseName).Collection(DeadLettersCollection)
+	_, err := coll.UpdateOne(letterCtx, bson.M{"ado_task_id": job.ADOTaskID}, bson.M{"$set": letter}, options.Update().SetUpsert(true))
+	if err != nil {
+		err = fmt.Errorf("error recording dead letter: %v", err)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	update := mongo.Pipeline{
+		bson.D{bson.E{Key: "$set", Value: bson.M{
+			"state":       bson.M{"$cond": bson.A{"$requeue", JobQueued, JobFailed}},
+			"attempts":    bson.M{"$cond": bson.A{"$requeue", 0, "$attempts"}},
+			"visible_at":  "$$NOW",
+			"error":       bson.M{"$literal": cause},
+			"error_class": bson.M{"$literal": class},
+			"updated_at":  "$$NOW",
+		}}},
+		bson.D{bson.E{Key: "$unset", Value: bson.A{"leased_by", "lease_expires_at", "requeue"}}},
+	}
+	filter := bson.M{"_id": job.ID, "state": JobLeased, "leased_by": job.LeasedBy}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updated Job
+	err = db.Client.Database(DatabaseName).Collection(JobsCollection).FindOneAndUpdate(jobCtx, filter, update, opts).Decode(&updated)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		span.AddEvent("lease lost")
+		return db.deleteDeadLetter(ctx, span, job.ADOTaskID)
+	}
+	if err != nil {
+		err = fmt.Errorf("error failing job: %v", err)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if updated.State != JobFailed {
+		span.AddEvent("requeued")
+		return db.deleteDeadLetter(ctx, span, job.ADOTaskID)
+	}
+	return nil
+}
+
+// RetryDeadLetter queues the failed job of the work item straight away with
+// a fresh set of attempts and removes its dead letter.
+func (db *DB) RetryDeadLetter(ctx context.Context, adoTaskID int) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.RetryDeadLetter")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("ado_task_id", adoTaskID))
+
+	jobCtx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"state": JobQueued, "attempts": 0, "visible_at": now, "updated_at": now}}
+	coll := db.Client.Database(DatabaseName).Collection(JobsCollection)
+	if _, err := coll.UpdateOne(jobCtx, bson.M{"ado_task_id": adoTaskID, "state": JobFailed}, update); err != nil {
+		err = fmt.Errorf("error retrying job: %v", err)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return db.deleteDeadLetter(ctx, span, adoTaskID)
+}
+
+// IgnoreDeadLetter removes the dead letter of the work item. Its job stays
+// failed until the work item changes again.
+func (db *DB) IgnoreDeadLetter(ctx context.Context, adoTaskID int) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.IgnoreDeadLetter")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("ado_task_id", adoTaskID))
+
+	return db.deleteDeadLetter(ctx, span, adoTaskID)
+}
+
+func (db *DB) deleteDeadLetter(ctx context.Context, span trace.Span, adoTaskID int) error {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	coll := db.Client.Database(DatabaseName).Collection(DeadLettersCollection)
+	if _, err := coll.DeleteOne(ctx, bson.M{"ado_task_id": adoTaskID}); err != nil {
+		err = fmt.Errorf("error deleting dead letter: %v", err)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,372 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// JobsCollection is the name of the collection holding the queue of work
+// items waiting to be synced.
+var JobsCollection = "sync_jobs"
+
+// Job states.
+const (
+	// JobQueued jobs wait to be leased once their VisibleAt has passed.
+	JobQueued = "queued"
+	// JobLeased jobs are being synced by a worker. A lease that expires,
+	// because the worker died, makes the job available again.
+	JobLeased = "leased"
+	// JobDone jobs were synced successfully.
+	JobDone = "done"
+	// JobFailed jobs were moved to the dead-letter queue. They wait for the
+	// work item to change or to be retried from the web UI.
+	JobFailed = "failed"
+)
+
+// Job is a work item queued for syncing. There is a single job per work
+// item, re-queued whenever the work item changes.
+type Job struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ADOTaskID      int                `bson:"ado_task_id" json:"ado_task_id"`
+	ADOProjectName string             `bson:"ado_project_name" json:"ado_project_name"`
+	State          string             `bson:"state" json:"state"`
+	// ShardKey is a hash of the ADO project, used to split the jobs between
+	// replicas.
+	ShardKey int64 `bson:"shard_key" json:"shard_key"`
+	// Attempts counts the leases since the job last succeeded.
+	Attempts int `bson:"attempts" json:"attempts"`
+	// Error and ErrorClass describe the last failure.
+	Error      string `bson:"error,omitempty" json:"error,omitempty"`
+	ErrorClass string `bson:"error_class,omitempty" json:"error_class,omitempty"`
+	// ChangedDate is the ADO ChangedDate of the change that queued the job,
+	// zero when unknown.
+	ChangedDate time.Time `bson:"changed_date" json:"changed_date"`
+	VisibleAt   time.Time `bson:"visible_at" json:"visible_at"`
+	// LeasedBy and LeaseExpiresAt identify the worker holding the job.
+	LeasedBy       string    `bson:"leased_by,omitempty" json:"leased_by,omitempty"`
+	LeaseExpiresAt time.Time `bson:"lease_expires_at,omitempty" json:"lease_expires_at,omitempty"`
+	// Requeue is set when the work item changed while the job was leased, so
+	// it is queued again rather than marked done.
+	Requeue bool `bson:"requeue,omitempty" json:"requeue,omitempty"`
+	// Force is set to sync the work item in full even if nothing changed.
+	// It is cleared once the job completes.
+	Force bool `bson:"force,omitempty" json:"force,omitempty"`
+	// CheckAsana is set when the Asana task changed, to look for edits made
+	// in Asana even if the work item did not change. It is cleared once the
+	// job completes.
+	CheckAsana bool      `bson:"check_asana,omitempty" json:"check_asana,omitempty"`
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// EnqueueJob queues the work item for syncing straight away, unless its job
+// already covers the change made at changed. A job leased by a worker is
+// flagged to run again once the worker is done. A zero changed always queues
+// the work item and keeps the changed date already recorded.
+func (db *DB) EnqueueJob(ctx context.Context, adoTaskID int, project string, changed time.Time) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.EnqueueJob")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("ado_task_id", adoTaskID))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	coll := db.Client.Database(DatabaseName).Collection(JobsCollection)
+	// The unique index on ado_task_id makes the upsert fail when the job is
+	// leased; the job is then flagged instead. Retry in case the lease ends
+	// in between.
+	for range 3 {
+		var job Job
+		err := coll.FindOne(ctx, bson.M{"ado_task_id": adoTaskID}).Decode(&job)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			err = fmt.Errorf("error finding job: %v", err)
+			span.RecordError(err, trace.WithStackTrace(true))
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+		if err == nil && !changed.IsZero() && !changed.After(job.ChangedDate) {
+			span.AddEvent("already queued")
+			return nil
+		}
+
+		now := time.Now()
+		if job.State == JobLeased {
+			filter := bson.M{"ado_task_id": adoTaskID, "state": JobLeased}
+			set := bson.M{"requeue": true, "ado_project_name": project, "shard_key": ShardKey(project), "updated_at": now}
+			if !changed.IsZero() {
+				set["changed_date"] = changed
+			}
+			update := bson.M{"$set": set}
+			res, err := coll.UpdateOne(ctx, filter, update)
+			if err != nil {
+				err = fmt.Errorf("error flagging leased job: %v", err)
+				span.RecordError(err, trace.WithStackTrace(true))
+				span.SetStatus(codes.Error, err.Error())
+				return err
+			}
+			if res.MatchedCount > 0 {
+				return nil
+			}
+			continue
+		}
+
+		set := bson.M{
+			"ado_project_name": project,
+			"shard_key":        ShardKey(project),
+			"state":            JobQueued,
+			"attempts":         0,
+			"visible_at":       now,
+			"updated_at":       now,
+		}
+		// A zero changed keeps the change the job last covered, so later
+		// events for that change are still skipped.
+		if !changed.IsZero() {
+			set["changed_date"] = changed
+		}
+		update := bson.M{
+			"$set":         set,
+			"$unset":       bson.M{"error": "", "error_class": "", "leased_by": "", "lease_expires_at": "", "requeue": ""},
+			"$setOnInsert": bson.M{"created_at": now},
+		}
+		filter := bson.M{"ado_task_id": adoTaskID, "state": bson.M{"$ne": JobLeased}}
+		_, err = coll.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			err = fmt.Errorf("error enqueuing job: %v", err)
+			span.RecordError(err, trace.WithStackTrace(true))
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+	}
+	err := fmt.Errorf("error enqueuing job %d: lease kept changing", adoTaskID)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return err
+}
+
+// Shard selects the jobs of one of Count replicas sharing the queue. The
+// zero Shard selects all jobs.
+type Shard struct {
+	Count int
+	Index int
+}
+
+// ShardKey returns the shard key of the jobs of an ADO project.
+func ShardKey(project string) int64 {
+	h := fnv.New32a()
+	h.Write([]byte(project))
+	return int64(h.Sum32())
+}
+
+// filter restricts a job query to the shard. Jobs queued before sharding
+// was enabled have no shard key and go to the first shard.
+func (s Shard) filter() bson.M {
+	if s.Count <= 1 {
+		return bson.M{}
+	}
+	mod := bson.M{"shard_key": bson.M{"$mod": bson.A{s.Count, s.Index}}}
+	if s.Index == 0 {
+		return bson.M{"$or": bson.A{mod, bson.M{"shard_key": bson.M{"$exists": false}}}}
+	}
+	return mod
+}
+
+// LeaseJobs leases up to limit jobs of the shard to owner for the lease
+// duration. Queued jobs become available once visible and leased jobs once
+// their lease expires. Fewer jobs, possibly none, are returned when the
+// queue runs dry.
+func (db *DB) LeaseJobs(ctx context.Context, owner string, shard Shard, limit int, lease time.Duration) ([]Job, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.LeaseJobs")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	coll := db.Client.Database(DatabaseName).Collection(JobsCollection)
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{bson.E{Key: "visible_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var jobs []Job
+	for len(jobs) < limit {
+		now := time.Now()
+		filter := bson.M{"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"state": JobQueued, "visible_at": bson.M{"$lte": now}},
+				bson.M{"state": JobLeased, "lease_expires_at": bson.M{"$lte": now}},
+			}},
+			shard.filter(),
+		}}
+		update := bson.M{
+			"$set": bson.M{
+				"state":            JobLeased,
+				"leased_by":        owner,
+				"lease_expires_at": now.Add(lease),
+				"updated_at":       now,
+			},
+			"$inc": bson.M{"attempts": 1},
+		}
+		var job Job
+		err := coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			err = fmt.Errorf("error leasing job: %v", err)
+			span.RecordError(err, trace.WithStackTrace(true))
+			span.SetStatus(codes.Error, err.Error())
+			return jobs, err
+		}
+		jobs = append(jobs, job)
+	}
+	span.SetAttributes(attribute.Int("jobs", len(jobs)))
+	return jobs, nil
+}
+
+// CompleteJob marks a leased job as done, or queues it again straight away
+// if the work item changed while it was leased. Jobs whose lease was taken
+// over by another worker are left alone. Any dead letter left by an earlier
+// failure of the work item is removed.
+func (db *DB) CompleteJob(ctx context.Context, job Job) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.CompleteJob")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("ado_task_id", job.ADOTaskID))
+
+	err := db.releaseJob(ctx, span, job, bson.M{
+		"state":      bson.M{"$cond": bson.A{"$requeue", JobQueued, JobDone}},
+		"attempts":   0,
+		"visible_at": "$$NOW",
+		// A forced sync or check asked for while the job was leased still
+		// applies.
+		"force":       bson.M{"$and": bson.A{"$requeue", "$force"}},
+		"check_asana": bson.M{"$and": bson.A{"$requeue", "$check_asana"}},
+	}, "error", "error_class")
+	if err != nil {
+		return err
+	}
+	return db.deleteDeadLetter(ctx, span, job.ADOTaskID)
+}
+
+// FailJob records the error of a leased job and queues it again to be
+// retried at retryAt. A job whose work item changed while it was leased is
+// retried straight away.
+func (db *DB) FailJob(ctx context.Context, job Job, class, cause string, retryAt time.Time) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.FailJob")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("ado_task_id", job.ADOTaskID), attribute.String("error_class", class))
+
+	return db.releaseJob(ctx, span, job, bson.M{
+		"state":       JobQueued,
+		"attempts":    bson.M{"$cond": bson.A{"$requeue", 0, "$attempts"}},
+		"visible_at":  bson.M{"$cond": bson.A{"$requeue", "$$NOW", retryAt}},
+		"error":       bson.M{"$literal": cause},
+		"error_class": bson.M{"$literal": class},
+	})
+}
+
+// ReturnJob puts a leased job that was never started back in the queue, as
+// when its worker shuts down, without counting the attempt.
+func (db *DB) ReturnJob(ctx context.Context, job Job) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.ReturnJob")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("ado_task_id", job.ADOTaskID))
+
+	return db.releaseJob(ctx, span, job, bson.M{
+		"state":      JobQueued,
+		"attempts":   bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{"$attempts", 1}}}},
+		"visible_at": "$$NOW",
+	})
+}
+
+// releaseJob applies set to a job still leased by its owner, removes the
+// unset fields and clears the lease.
+func (db *DB) releaseJob(ctx context.Context, span trace.Span, job Job, set bson.M, unset ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	set["updated_at"] = "$$NOW"
+	update := mongo.Pipeline{
+		bson.D{bson.E{Key: "$set", Value: set}},
+		bson.D{bson.E{Key: "$unset", Value: append([]string{"leased_by", "lease_expires_at", "requeue"}, unset...)}},
+	}
+	filter := bson.M{"_id": job.ID, "state": JobLeased, "leased_by": job.LeasedBy}
+	coll := db.Client.Database(DatabaseName).Collection(JobsCollection)
+	res, err := coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		err = fmt.Errorf("error releasing job: %v", err)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if res.MatchedCount == 0 {
+		span.AddEvent("lease lost")
+	}
+	return nil
+}
+
+// ForceResync queues a synced work item to be synced in full straight
+// away, even if its revision was synced already and nothing Asana-relevant
+// changed.
+func (db *DB) ForceResync(ctx context.Context, adoTaskID int) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.ForceResync")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("ado_task_id", adoTaskID))
+
+	task, err := db.TaskByADOTaskID(ctx, adoTaskID)
+	if err != nil {
+		return err
+	}
+	return db.flagAndEnqueueJob(ctx, span, adoTaskID, task.ADOProjectID, "force")
+}
+
+// CheckAsanaTask queues a synced work item to be compared with its Asana
+// task straight away, after the task was seen changing in Asana.
+func (db *DB) CheckAsanaTask(ctx context.Context, adoTaskID int, project string) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.CheckAsanaTask")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("ado_task_id", adoTaskID))
+
+	return db.flagAndEnqueueJob(ctx, span, adoTaskID, project, "check_asana")
+}
+
+// flagAndEnqueueJob sets the flag on the job of the work item, creating the
+// job when needed, then queues it.
+func (db *DB) flagAndEnqueueJob(ctx context.Context, span trace.Span, adoTaskID int, project, flag string) error {
+	jobCtx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	// Flag the job before queuing it so a worker cannot lease it unflagged.
+	now := time.Now()
+	update := bson.M{
+		"$set":         bson.M{flag: true, "ado_project_name": project, "shard_key": ShardKey(project), "updated_at": now},
+		"$setOnInsert": bson.M{"state": JobDone, "attempts": 0, "created_at": now},
+	}
+	coll := db.Client.Database(DatabaseName).Collection(JobsCollection)
+	if _, err := coll.UpdateOne(jobCtx, bson.M{"ado_task_id": adoTaskID}, update, options.Update().SetUpsert(true)); err != nil {
+		err = fmt.Errorf("error flagging job: %v", err)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return db.EnqueueJob(ctx, adoTaskID, project, time.Time{})
+}
@@ -20,6 +20,10 @@ type LastSync struct {
 	Time time.Time `json:"time" bson:"time"`
 }
 
+// FirstSync is the sync time of what was never synced. ADO rejects earlier
+// ChangedDate values in WIQL queries.
+var FirstSync = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
 // LastSync retrieves the last sync time.
 func (db *DB) LastSync(ctx context.Context) LastSync {
 	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.LastSync")
@@ -32,7 +36,7 @@ func (db *DB) LastSync(ctx context.Context) LastSync {
 	collection := db.Client.Database(DatabaseName).Collection(LastSyncCollection)
 	err := collection.FindOne(ctx, bson.D{}).Decode(&lastSync)
 	if err != nil {
-		lastSync.Time = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+		lastSync.Time = FirstSync
 	}
 	return lastSync
 }
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LeasesCollection is the name of the collection storing the leases used to
+// elect a single replica for work that must not run concurrently.
+var LeasesCollection = "leases"
+
+// Lease is held by one replica until it expires or is released.
+type Lease struct {
+	Name      string    `bson:"_id" json:"name"`
+	Holder    string    `bson:"holder" json:"holder"`
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`
+}
+
+// AcquireLease takes or renews the named lease for holder for ttl. It
+// reports false when another holder has a lease that has not expired.
+func (db *DB) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.AcquireLease")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("lease", name), attribute.String("holder", holder))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{"_id": name, "$or": bson.A{
+		bson.M{"holder": holder},
+		bson.M{"expires_at": bson.M{"$lte": now}},
+	}}
+	update := bson.M{"$set": bson.M{"holder": holder, "expires_at": now.Add(ttl)}}
+	coll := db.Client.Database(DatabaseName).Collection(LeasesCollection)
+	_, err := coll.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// The lease exists and is held by someone else.
+		return false, nil
+	}
+	if err != nil {
+		err = fmt.Errorf("error acquiring lease: %v", err)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseLease gives up the named lease if holder holds it, so another
+// replica can take it over without waiting for it to expire.
+func (db *DB) ReleaseLease(ctx context.Context, name, holder string) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.ReleaseLease")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("lease", name), attribute.String("holder", holder))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	coll := db.Client.Database(DatabaseName).Collection(LeasesCollection)
+	if _, err := coll.DeleteOne(ctx, bson.M{"_id": name, "holder": holder}); err != nil {
+		err = fmt.Errorf("error releasing lease: %v", err)
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
@@ -20,14 +20,45 @@ var (
 
 const errADOProjectAlreadyMapped = "ADO project already mapped to a different Asana project"
 
-// Project represents a project with its corresponding names in ADO and Asana.
+// Project maps an ADO project to an Asana project. The IDs identify the
+// projects and workspace; the names are kept for display and refreshed by the
+// sync engine when they change upstream. Mappings saved without IDs have them
+// resolved from the names on the next sync run.
 type Project struct {
 	ID                 primitive.ObjectID `json:"id" bson:"_id"`
 	ADOProjectName     string             `json:"ado_project_name" bson:"ado_project_name"`
 	AsanaProjectName   string             `json:"asana_project_name" bson:"asana_project_name"`
 	AsanaWorkspaceName string             `json:"asana_workspace_name" bson:"asana_workspace_name"`
+	ADOProjectID       string             `json:"ado_project_id,omitempty" bson:"ado_project_id,omitempty"`
+	AsanaWorkspaceGID  string             `json:"asana_workspace_gid,omitempty" bson:"asana_workspace_gid,omitempty"`
+	AsanaProjectGID    string             `json:"asana_project_gid,omitempty" bson:"asana_project_gid,omitempty"`
+	// SyncSchedule is a cron expression for when the project is synced;
+	// empty syncs it on every run. QuietHours lists daily HH:MM-HH:MM
+	// windows in which it is never synced.
+	SyncSchedule string `json:"sync_schedule,omitempty" bson:"sync_schedule,omitempty"`
+	QuietHours   string `json:"quiet_hours,omitempty" bson:"quiet_hours,omitempty"`
+	// Renames lists the upstream renames picked up since the mapping was
+	// last saved, so they can be shown to the user.
+	Renames []ProjectRename `json:"renames,omitempty" bson:"renames,omitempty"`
 }
 
+// ProjectRename records a project or workspace renamed in ADO or Asana.
+type ProjectRename struct {
+	// Kind is one of RenameADOProject, RenameAsanaWorkspace or
+	// RenameAsanaProject.
+	Kind string    `json:"kind" bson:"kind"`
+	From string    `json:"from" bson:"from"`
+	To   string    `json:"to" bson:"to"`
+	At   time.Time `json:"at" bson:"at"`
+}
+
+// Kinds of ProjectRename.
+const (
+	RenameADOProject     = "ADO project"
+	RenameAsanaWorkspace = "Asana workspace"
+	RenameAsanaProject   = "Asana project"
+)
+
 // Projects retrieves all projects from the database.
 // It returns a slice of Project structs and an error, if any.
 func (db *DB) Projects(ctx context.Context) ([]Project, error) {
@@ -126,7 +157,9 @@ func (db *DB) RemoveProject(ctx context.Context, id primitive.ObjectID) error {
 }
 
 // UpdateProject updates an existing project in the database.
-// It takes a Project struct as input and returns an error, if any.
+// It takes a Project struct as input and returns an error, if any. The IDs
+// and renames are replaced too, so saving a mapping without IDs has them
+// resolved again.
 func (db *DB) UpdateProject(ctx context.Context, project Project) error {
 	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.UpdateProject")
 	defer span.End()
@@ -161,6 +194,12 @@ func (db *DB) UpdateProject(ctx context.Context, project Project) error {
 			"ado_project_name":     project.ADOProjectName,
 			"asana_project_name":   project.AsanaProjectName,
 			"asana_workspace_name": project.AsanaWorkspaceName,
+			"ado_project_id":       project.ADOProjectID,
+			"asana_workspace_gid":  project.AsanaWorkspaceGID,
+			"asana_project_gid":    project.AsanaProjectGID,
+			"sync_schedule":        project.SyncSchedule,
+			"quiet_hours":          project.QuietHours,
+			"renames":              project.Renames,
 		},
 	}
 	_, err = collection.UpdateOne(dbCtx, filter, update)
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ReconciliationsCollection is the name of the collection storing the
+// latest reconciliation of each mapped ADO project, and the ones requested
+// from the web UI.
+var ReconciliationsCollection = "reconciliations"
+
+// Reconciliation compares every work item of an ADO project with its task
+// mapping and Asana task, reporting the drift found and, in repair mode,
+// fixing what can be fixed without deleting Asana tasks.
+type Reconciliation struct {
+	ADOProjectName string `bson:"ado_project_name" json:"ado_project_name"`
+	// RequestedAt is set while a reconciliation requested from the web UI
+	// waits to run, with Repair telling whether it should repair the drift.
+	RequestedAt time.Time `bson:"requested_at,omitempty" json:"requested_at,omitempty"`
+	Repair      bool      `bson:"repair" json:"repair"`
+	StartedAt   time.Time `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	FinishedAt  time.Time `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+	// Repaired reports whether the last run repaired the issues it found.
+	Repaired bool `bson:"repaired" json:"repaired"`
+	// Checked is the number of work items and mappings compared.
+	Checked int              `bson:"checked" json:"checked"`
+	Issues  []ReconcileIssue `bson:"issues" json:"issues"`
+	// Error is set when the last run could not finish.
+	Error string `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// ReconcileIssue is a drift between a work item, its task mapping and its
+// Asana task.
+type ReconcileIssue struct {
+	// Kind is one of IssueMissingTask, IssueStaleTitle, IssueOrphan or
+	// IssueDuplicate.
+	Kind        string `bson:"kind" json:"kind"`
+	ADOTaskID   int    `bson:"ado_task_id" json:"ado_task_id"`
+	AsanaTaskID string `bson:"asana_task_id,omitempty" json:"asana_task_id,omitempty"`
+	Detail      string `bson:"detail" json:"detail"`
+	// Repaired is set once the issue was fixed or queued to be.
+	Repaired bool `bson:"repaired" json:"repaired"`
+}
+
+// Kinds of ReconcileIssue.
+const (
+	IssueMissingTask = "missing task"
+	IssueStaleTitle  = "stale title"
+	IssueOrphan      = "orphan"
+	IssueDuplicate   = "duplicate"
+)
+
+// Reconciliations returns the reconciliation of each ADO project run or
+// requested so far, by project name.
+func (db *DB) Reconciliations(ctx context.Context) ([]Reconciliation, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.Reconciliations")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var recs []Reconciliation
+	coll := db.Client.Database(DatabaseName).Collection(ReconciliationsCollection)
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "ado_project_name", Value: 1}})
+	cursor, err := coll.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		err = fmt.Errorf("error finding reconciliations: %v", err)
+		span.RecordError(err)
+		return recs, err
+	}
+	if err := cursor.All(ctx, &recs); err != nil {
+		err = fmt.Errorf("error decoding reconciliations: %v", err)
+		span.RecordError(err)
+		return recs, err
+	}
+	return recs, nil
+}
+
+// RequestReconciliation asks the sync engine to reconcile the ADO project on
+// its next run, repairing the drift found when repair is set.
+func (db *DB) RequestReconciliation(ctx context.Context, adoProjectName string, repair bool) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.RequestReconciliation")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("project", adoProjectName), attribute.Bool("repair", repair))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"requested_at": time.Now(), "repair": repair}}
+	coll := db.Client.Database(DatabaseName).Collection(ReconciliationsCollection)
+	_, err := coll.UpdateOne(ctx, bson.M{"ado_project_name": adoProjectName}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		err = fmt.Errorf("error requesting reconciliation: %v", err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// WriteReconciliation records the outcome of a reconciliation. A request
+// made before it started is cleared; one made while it ran is kept for the
+// next run.
+func (db *DB) WriteReconciliation(ctx context.Context, rec Reconciliation) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.WriteReconciliation")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("project", rec.ADOProjectName), attribute.Int("issues", len(rec.Issues)))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	coll := db.Client.Database(DatabaseName).Collection(ReconciliationsCollection)
+	update := bson.M{"$set": bson.M{
+		"started_at":  rec.StartedAt,
+		"finished_at": rec.FinishedAt,
+		"repaired":    rec.Repaired,
+		"checked":     rec.Checked,
+		"issues":      rec.Issues,
+		"error":       rec.Error,
+	}}
+	filter := bson.M{"ado_project_name": rec.ADOProjectName}
+	if _, err := coll.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+		err = fmt.Errorf("error writing reconciliation: %v", err)
+		span.RecordError(err)
+		return err
+	}
+
+	filter["requested_at"] = bson.M{"$lte": rec.StartedAt}
+	if _, err := coll.UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"requested_at": ""}}); err != nil {
+		err = fmt.Errorf("error clearing reconciliation request: %v", err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
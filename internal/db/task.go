@@ -1,18 +1,20 @@
 package db
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/net/context"
 )
 
 // ErrorFmtFindingTask is the error format for when a task cannot be found
-const ErrorFmtFindingTask = "error finding task: %v"
+const ErrorFmtFindingTask = "error finding task: %w"
 
 // TasksCollection is the name of the collection in the database for synced tasks.
 var TasksCollection = "tasks"
@@ -23,11 +25,22 @@ type TaskMapping struct {
 	ADOProjectID     string             `bson:"ado_project_id" json:"ado_project_id"`
 	ADOTaskID        int                `bson:"ado_task_id" json:"ado_task_id"`
 	ADOLastUpdated   time.Time          `bson:"ado_last_updated" json:"ado_last_updated"`
+	ADORevision      int                `bson:"ado_revision" json:"ado_revision"`
 	AsanaProjectID   string             `bson:"asana_project_id" json:"asana_project_id"`
 	AsanaTaskID      string             `bson:"asana_task_id" json:"asana_task_id"`
 	AsanaLastUpdated time.Time          `bson:"asana_last_updated" json:"asana_last_updated"`
-	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+	// AsanaFingerprint holds a hash of each field last sent to the Asana
+	// task, so unchanged fields are not sent again.
+	AsanaFingerprint map[string]string `bson:"asana_fingerprint,omitempty" json:"asana_fingerprint,omitempty"`
+	// AsanaStored holds a hash of the name and notes as Asana stored them
+	// when they were last sent, so edits made in Asana are told apart from
+	// the rewriting Asana does itself.
+	AsanaStored map[string]string `bson:"asana_stored,omitempty" json:"asana_stored,omitempty"`
+	// AsanaOwnedFields lists the fields edited in Asana that are no longer
+	// overwritten from ADO.
+	AsanaOwnedFields []string  `bson:"asana_owned_fields,omitempty" json:"asana_owned_fields,omitempty"`
+	CreatedAt        time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time `bson:"updated_at" json:"updated_at"`
 }
 
 // Tasks retrieves all tasks from the database.
@@ -148,6 +161,31 @@ func (db *DB) TaskByADOTaskID(ctx context.Context, id int) (TaskMapping, error)
 	return task, nil
 }
 
+// TaskByAsanaTaskID returns the task mapping of an Asana task. The boolean
+// reports whether the task is mapped.
+func (db *DB) TaskByAsanaTaskID(ctx context.Context, gid string) (TaskMapping, bool, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.TaskByAsanaTaskID")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("asana_task_id", gid))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var task TaskMapping
+	collection := db.Client.Database(DatabaseName).Collection(TasksCollection)
+	err := collection.FindOne(ctx, bson.M{"asana_task_id": gid}).Decode(&task)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return task, false, nil
+	}
+	if err != nil {
+		err = fmt.Errorf(ErrorFmtFindingTask, err)
+		span.RecordError(err)
+		return task, false, err
+	}
+	return task, true, nil
+}
+
 // AddTask adds a new task mapping to the database.
 // It takes a TaskMapping struct as input and returns an error, if any.
 func (db *DB) AddTask(ctx context.Context, task TaskMapping) error {
@@ -262,9 +300,13 @@ func (db *DB) UpdateTask(ctx context.Context, task TaskMapping) error {
 			"ado_project_id":     task.ADOProjectID,
 			"ado_task_id":        task.ADOTaskID,
 			"ado_last_updated":   task.ADOLastUpdated,
+			"ado_revision":       task.ADORevision,
 			"asana_project_id":   task.AsanaProjectID,
 			"asana_task_id":      task.AsanaTaskID,
 			"asana_last_updated": task.AsanaLastUpdated,
+			"asana_fingerprint":  task.AsanaFingerprint,
+			"asana_stored":       task.AsanaStored,
+			"asana_owned_fields": task.AsanaOwnedFields,
 			"updated_at":         task.UpdatedAt,
 		},
 	}
@@ -278,3 +320,26 @@ func (db *DB) UpdateTask(ctx context.Context, task TaskMapping) error {
 
 	return nil
 }
+
+// RenameTasksProject points the task and work item project mappings of an
+// ADO project at its new name after the project was renamed in ADO.
+func (db *DB) RenameTasksProject(ctx context.Context, from, to string) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.RenameTasksProject")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("from", from), attribute.String("to", to))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"ado_project_id": to, "updated_at": time.Now()}}
+	for _, name := range []string{TasksCollection, WorkItemProjectsCollection} {
+		coll := db.Client.Database(DatabaseName).Collection(name)
+		if _, err := coll.UpdateMany(ctx, bson.M{"ado_project_id": from}, update); err != nil {
+			err = fmt.Errorf("error renaming project of %s: %v", name, err)
+			span.RecordError(err)
+			return err
+		}
+	}
+	return nil
+}
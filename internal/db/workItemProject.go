@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/helpers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// WorkItemProjectsCollection is the name of the collection storing ADO work
+// items that are mirrored as Asana projects.
+var WorkItemProjectsCollection = "work_item_projects"
+
+// WorkItemProject maps an ADO work item (such as an Epic or Feature) to the
+// Asana project that represents it.
+type WorkItemProject struct {
+	ID                 primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ADOProjectID       string             `bson:"ado_project_id" json:"ado_project_id"`
+	ADOWorkItemID      int                `bson:"ado_work_item_id" json:"ado_work_item_id"`
+	ADOLastUpdated     time.Time          `bson:"ado_last_updated" json:"ado_last_updated"`
+	ADOState           string             `bson:"ado_state" json:"ado_state"`
+	AsanaProjectID     string             `bson:"asana_project_id" json:"asana_project_id"`
+	AsanaWorkspaceName string             `bson:"asana_workspace_name" json:"asana_workspace_name"`
+	PortfolioID        string             `bson:"portfolio_id,omitempty" json:"portfolio_id,omitempty"`
+	CreatedAt          time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt          time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// WorkItemProjectByADOID retrieves the project mapping for an ADO work item.
+// The error wraps mongo.ErrNoDocuments when the work item has no project.
+func (db *DB) WorkItemProjectByADOID(ctx context.Context, id int) (WorkItemProject, error) {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.WorkItemProjectByADOID")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("ado_work_item_id", id))
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var p WorkItemProject
+	coll := db.Client.Database(DatabaseName).Collection(WorkItemProjectsCollection)
+	err := coll.FindOne(ctx, bson.M{"ado_work_item_id": id}).Decode(&p)
+	if err != nil {
+		err = fmt.Errorf("error finding work item project: %w", err)
+		span.RecordError(err)
+		return p, err
+	}
+	return p, nil
+}
+
+// UpsertWorkItemProject inserts or updates the project mapping for an ADO
+// work item, keyed on the work item ID.
+func (db *DB) UpsertWorkItemProject(ctx context.Context, p WorkItemProject) error {
+	ctx, span := helpers.StartSpanOnTracerFromContext(ctx, "db.UpsertWorkItemProject")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("ado_work_item_id", p.ADOWorkItemID),
+		attribute.String("asana_project_id", p.AsanaProjectID),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	now := time.Now()
+	coll := db.Client.Database(DatabaseName).Collection(WorkItemProjectsCollection)
+	update := bson.M{
+		"$set": bson.M{
+			"ado_project_id":       p.ADOProjectID,
+			"ado_last_updated":     p.ADOLastUpdated,
+			"ado_state":            p.ADOState,
+			"asana_project_id":     p.AsanaProjectID,
+			"asana_workspace_name": p.AsanaWorkspaceName,
+			"portfolio_id":         p.PortfolioID,
+			"updated_at":           now,
+		},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	_, err := coll.UpdateOne(ctx, bson.M{"ado_work_item_id": p.ADOWorkItemID}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		err = fmt.Errorf("error upserting work item project: %v", err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+// Package ratelimit provides an http.RoundTripper that keeps API clients
+// within their rate limits and retries requests the server throttled.
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config controls the behaviour of a Transport.
+type Config struct {
+	// Name identifies the API in span events and metrics, e.g. "asana".
+	Name string
+	// Rate is the number of requests per second allowed by the token
+	// bucket. Zero disables client-side limiting; server hints are still
+	// honoured.
+	Rate float64
+	// Burst is the size of the token bucket. Defaults to one second's worth
+	// of requests.
+	Burst int
+	// MaxRetries is the number of times a request is retried.
+	MaxRetries int
+	// BaseDelay is the initial backoff delay, doubled on every retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff delay and any server requested wait.
+	MaxDelay time.Duration
+}
+
+// Stats is a snapshot of the throttling applied by a Transport.
+type Stats struct {
+	Requests  int64
+	Throttled int64
+	Retries   int64
+	Waited    time.Duration
+}
+
+// Transport limits the requests sent through it with a token bucket shared
+// by all callers and pauses every caller when the server signals throttling
+// through Retry-After or X-RateLimit-* headers. Throttled (429) responses are
+// retried for any method as the server did not process them; network errors
+// and 5xx responses are only retried for idempotent methods.
+type Transport struct {
+	base   http.RoundTripper
+	cfg    Config
+	bucket *bucket
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+
+	requests  atomic.Int64
+	throttled atomic.Int64
+	retries   atomic.Int64
+	waited    atomic.Int64
+
+	requestCounter   metric.Int64Counter
+	throttledCounter metric.Int64Counter
+	retryCounter     metric.Int64Counter
+	waitHistogram    metric.Float64Histogram
+
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration) error
+	jitter func() float64
+}
+
+// New wraps base, or http.DefaultTransport when base is nil.
+func New(base http.RoundTripper, cfg Config) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = max(1, int(cfg.Rate))
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = 500 * time.Millisecond
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = time.Minute
+	}
+
+	t := &Transport{
+		base:   base,
+		cfg:    cfg,
+		now:    time.Now,
+		sleep:  sleepContext,
+		jitter: rand.Float64,
+	}
+	if cfg.Rate > 0 {
+		t.bucket = newBucket(cfg.Rate, cfg.Burst, t.now)
+	}
+
+	// The instrument names are fixed and valid, so creation cannot fail.
+	meter := otel.Meter("github.com/ADO-Asana-Sync/sync-engine/internal/ratelimit")
+	t.requestCounter, _ = meter.Int64Counter("http.client.ratelimit.requests",
+		metric.WithDescription("Requests sent through the rate limited transport."))
+	t.throttledCounter, _ = meter.Int64Counter("http.client.ratelimit.throttled",
+		metric.WithDescription("Responses throttled by the server."))
+	t.retryCounter, _ = meter.Int64Counter("http.client.ratelimit.retries",
+		metric.WithDescription("Requests retried after a throttle or failure."))
+	t.waitHistogram, _ = meter.Float64Histogram("http.client.ratelimit.wait",
+		metric.WithUnit("s"),
+		metric.WithDescription("Time spent waiting before sending a request."))
+	return t
+}
+
+// Stats returns the throttling applied so far.
+func (t *Transport) Stats() Stats {
+	return Stats{
+		Requests:  t.requests.Load(),
+		Throttled: t.throttled.Load(),
+		Retries:   t.retries.Load(),
+		Waited:    time.Duration(t.waited.Load()),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	span := trace.SpanFromContext(ctx)
+	apiAttr := metric.WithAttributes(attribute.String("api", t.cfg.Name))
+
+	for attempt := 0; ; attempt++ {
+		if err := t.wait(ctx, span, apiAttr); err != nil {
+			return nil, err
+		}
+
+		out := req
+		if attempt > 0 {
+			var err error
+			if out, err = rewind(req); err != nil {
+				return nil, err
+			}
+		}
+
+		t.requests.Add(1)
+		t.requestCounter.Add(ctx, 1, apiAttr)
+		resp, err := t.base.RoundTrip(out)
+
+		var delay time.Duration
+		if resp != nil {
+			delay = t.observe(resp)
+		}
+		throttled := resp != nil && resp.StatusCode == http.StatusTooManyRequests
+		if throttled {
+			t.throttled.Add(1)
+			t.throttledCounter.Add(ctx, 1, apiAttr)
+			span.AddEvent("throttled", trace.WithAttributes(
+				attribute.String("api", t.cfg.Name),
+				attribute.String("retry_after", delay.String()),
+			))
+		}
+
+		if attempt >= t.cfg.MaxRetries || !t.retryable(req, resp, err) {
+			return resp, err
+		}
+
+		if delay == 0 {
+			delay = t.backoff(attempt)
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		t.retries.Add(1)
+		t.retryCounter.Add(ctx, 1, apiAttr)
+		span.AddEvent("retry", trace.WithAttributes(
+			attribute.String("api", t.cfg.Name),
+			attribute.Int("attempt", attempt+1),
+			attribute.String("delay", delay.String()),
+		))
+		t.pause(delay)
+	}
+}
+
+// wait blocks until the server imposed pause is over and a token is
+// available.
+func (t *Transport) wait(ctx context.Context, span trace.Span, apiAttr metric.MeasurementOption) error {
+	t.mu.Lock()
+	d := t.pausedUntil.Sub(t.now())
+	t.mu.Unlock()
+	if d < 0 {
+		d = 0
+	}
+	if t.bucket != nil {
+		d += t.bucket.reserve()
+	}
+	if d <= 0 {
+		return nil
+	}
+
+	t.waited.Add(int64(d))
+	t.waitHistogram.Record(ctx, d.Seconds(), apiAttr)
+	span.AddEvent("rate limit wait", trace.WithAttributes(
+		attribute.String("api", t.cfg.Name),
+		attribute.String("wait", d.String()),
+	))
+	return t.sleep(ctx, d)
+}
+
+// pause makes every request wait for d before being sent.
+func (t *Transport) pause(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until := t.now().Add(d); until.After(t.pausedUntil) {
+		t.pausedUntil = until
+	}
+}
+
+// observe reads the rate limit headers of resp, pausing all requests if the
+// server asked for it, and returns the delay requested by the server.
+func (t *Transport) observe(resp *http.Response) time.Duration {
+	delay := parseRetryAfter(resp.Header.Get("Retry-After"), t.now())
+	if delay == 0 && resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			delay = time.Unix(reset, 0).Sub(t.now())
+		}
+	}
+	if delay <= 0 {
+		return 0
+	}
+	if delay > t.cfg.MaxDelay {
+		delay = t.cfg.MaxDelay
+	}
+	t.pause(delay)
+	return delay
+}
+
+func (t *Transport) retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		return req.Context().Err() == nil && idempotent(req.Method)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return resp.StatusCode >= 500 && idempotent(req.Method)
+}
+
+// backoff returns the exponential delay for the attempt, jittered between
+// half and all of it so retrying workers spread out.
+func (t *Transport) backoff(attempt int) time.Duration {
+	d := float64(t.cfg.BaseDelay) * math.Pow(2, float64(attempt))
+	if d > float64(t.cfg.MaxDelay) {
+		d = float64(t.cfg.MaxDelay)
+	}
+	return time.Duration(d/2 + t.jitter()*d/2)
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func rewind(req *http.Request) (*http.Request, error) {
+	out := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		out.Body = body
+	}
+	return out, nil
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an
+// HTTP date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.Duration(secs * float64(time.Second))
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// bucket is a token bucket refilled continuously at rate tokens per second.
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newBucket(rate float64, burst int, now func() time.Time) *bucket {
+	return &bucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: now(), now: now}
+}
+
+// reserve takes a token and returns how long the caller must wait before
+// using it. Tokens may go negative so concurrent callers queue up fairly.
+func (b *bucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
@@ -0,0 +1,219 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ADO-Asana-Sync/sync-engine/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock advances only when the transport sleeps.
+type fakeClock struct {
+	mu     sync.Mutex
+	t      time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) sleep(ctx context.Context, d time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sleeps = append(c.sleeps, d)
+	c.t = c.t.Add(d)
+	return ctx.Err()
+}
+
+func newTestTransport(base http.RoundTripper, cfg Config) (*Transport, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tr := New(base, cfg)
+	tr.now = clock.now
+	tr.sleep = clock.sleep
+	tr.jitter = func() float64 { return 1 }
+	if cfg.Rate > 0 {
+		tr.bucket = newBucket(cfg.Rate, tr.cfg.Burst, clock.now)
+	}
+	return tr, clock
+}
+
+func response(status int, headers map[string]string) *http.Response {
+	resp := &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("{}")), Header: make(http.Header)}
+	for k, v := range headers {
+		resp.Header.Set(k, v)
+	}
+	return resp
+}
+
+// sequence returns the responses in order, repeating the last one.
+func sequence(resps ...*http.Response) (http.RoundTripper, *int) {
+	calls := 0
+	return testutil.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp := resps[min(calls, len(resps)-1)]
+		calls++
+		return resp, nil
+	}), &calls
+}
+
+func TestRetriesAfterRetryAfter(t *testing.T) {
+	base, calls := sequence(
+		response(http.StatusTooManyRequests, map[string]string{"Retry-After": "30"}),
+		response(http.StatusOK, nil),
+	)
+	tr, clock := newTestTransport(base, Config{Name: "asana", MaxRetries: 3})
+
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/tasks", strings.NewReader(`{"data":{}}`))
+	resp, err := tr.RoundTrip(req)
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 2, *calls, "throttled POSTs are retried")
+	require.Equal(t, []time.Duration{30 * time.Second}, clock.sleeps)
+	require.Equal(t, Stats{Requests: 2, Throttled: 1, Retries: 1, Waited: 30 * time.Second}, tr.Stats())
+}
+
+func TestRetryAfterPausesOtherRequests(t *testing.T) {
+	base, _ := sequence(response(http.StatusOK, map[string]string{"Retry-After": "10"}))
+	tr, clock := newTestTransport(base, Config{})
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/a", nil)
+	_, err := tr.RoundTrip(req)
+	require.NoError(t, err)
+	require.Empty(t, clock.sleeps)
+
+	_, err = tr.RoundTrip(req)
+	require.NoError(t, err)
+	require.Equal(t, []time.Duration{10 * time.Second}, clock.sleeps)
+}
+
+func TestRateLimitRemainingPausesUntilReset(t *testing.T) {
+	tr, clock := newTestTransport(nil, Config{})
+	reset := clock.now().Add(20 * time.Second).Unix()
+	tr.base, _ = sequence(response(http.StatusOK, map[string]string{
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Reset":     strconv.FormatInt(reset, 10),
+	}))
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/a", nil)
+	_, _ = tr.RoundTrip(req)
+	_, _ = tr.RoundTrip(req)
+
+	require.Equal(t, []time.Duration{20 * time.Second}, clock.sleeps)
+}
+
+func TestServerErrorsRetriedOnlyForIdempotentMethods(t *testing.T) {
+	base, calls := sequence(response(http.StatusServiceUnavailable, nil))
+	tr, clock := newTestTransport(base, Config{MaxRetries: 2, BaseDelay: time.Second})
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/a", nil)
+	resp, err := tr.RoundTrip(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	require.Equal(t, 3, *calls)
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second}, clock.sleeps, "exponential backoff")
+
+	*calls = 0
+	req, _ = http.NewRequest(http.MethodPost, "https://example.com/a", strings.NewReader("{}"))
+	_, err = tr.RoundTrip(req)
+	require.NoError(t, err)
+	require.Equal(t, 1, *calls, "POSTs are not retried on server errors")
+}
+
+func TestNetworkErrorRetried(t *testing.T) {
+	calls := 0
+	base := testutil.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("connection reset")
+		}
+		return response(http.StatusOK, nil), nil
+	})
+	tr, _ := newTestTransport(base, Config{MaxRetries: 1})
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/a", nil)
+	resp, err := tr.RoundTrip(req)
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestRetryResendsBody(t *testing.T) {
+	var bodies []string
+	base := testutil.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(b))
+		if len(bodies) == 1 {
+			return response(http.StatusTooManyRequests, map[string]string{"Retry-After": "1"}), nil
+		}
+		return response(http.StatusOK, nil), nil
+	})
+	tr, _ := newTestTransport(base, Config{MaxRetries: 1})
+
+	req, _ := http.NewRequest(http.MethodPut, "https://example.com/a", strings.NewReader("payload"))
+	_, err := tr.RoundTrip(req)
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"payload", "payload"}, bodies)
+}
+
+func TestMaxDelayCapsRetryAfter(t *testing.T) {
+	base, _ := sequence(
+		response(http.StatusTooManyRequests, map[string]string{"Retry-After": "3600"}),
+		response(http.StatusOK, nil),
+	)
+	tr, clock := newTestTransport(base, Config{MaxRetries: 1, MaxDelay: time.Minute})
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/a", nil)
+	_, err := tr.RoundTrip(req)
+
+	require.NoError(t, err)
+	require.Equal(t, []time.Duration{time.Minute}, clock.sleeps)
+}
+
+func TestTokenBucketSpacesRequests(t *testing.T) {
+	base, _ := sequence(response(http.StatusOK, nil))
+	tr, clock := newTestTransport(base, Config{Rate: 2, Burst: 2})
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/a", nil)
+	for i := 0; i < 4; i++ {
+		_, err := tr.RoundTrip(req)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, []time.Duration{500 * time.Millisecond, 500 * time.Millisecond}, clock.sleeps, "burst then one every 500ms")
+}
+
+func TestContextCancelledWhileWaiting(t *testing.T) {
+	base, calls := sequence(response(http.StatusOK, nil))
+	tr, _ := newTestTransport(base, Config{})
+	tr.pause(time.Minute)
+	tr.sleep = sleepContext
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com/a", nil)
+	_, err := tr.RoundTrip(req)
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.Zero(t, *calls)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, 5*time.Second, parseRetryAfter("5", now))
+	require.Equal(t, 1500*time.Millisecond, parseRetryAfter("1.5", now))
+	require.Equal(t, 90*time.Second, parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now))
+	require.Zero(t, parseRetryAfter("", now))
+	require.Zero(t, parseRetryAfter("soon", now))
+}
@@ -0,0 +1,277 @@
+// Package schedule parses cron expressions and daily quiet windows, and
+// computes when a scheduled run is next due.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed standard five-field cron expression: minute, hour, day of
+// month, month and day of week. Fields accept *, numbers, ranges (a-b),
+// steps (*/n, a-b/n) and comma separated lists of those. Day of week runs
+// from 0 (Sunday) to 6, and 7 is Sunday too. As in cron, when both day
+// fields are restricted a day matching either is due.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// descriptors are the shorthands accepted in place of the five fields.
+var descriptors = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@nightly": "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// field is the range of values of a cron field.
+type field struct {
+	name     string
+	min, max int
+}
+
+var (
+	minuteField = field{"minute", 0, 59}
+	hourField   = field{"hour", 0, 23}
+	domField    = field{"day of month", 1, 31}
+	monthField  = field{"month", 1, 12}
+	dowField    = field{"day of week", 0, 7}
+)
+
+// Parse parses a cron expression.
+func Parse(expr string) (Cron, error) {
+	expr = strings.TrimSpace(expr)
+	if d, ok := descriptors[strings.ToLower(expr)]; ok {
+		expr = d
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return Cron{}, fmt.Errorf("cron expression %q must have 5 fields, has %d", expr, len(parts))
+	}
+
+	var c Cron
+	var err error
+	if c.minute, err = parseField(parts[0], minuteField); err != nil {
+		return Cron{}, err
+	}
+	if c.hour, err = parseField(parts[1], hourField); err != nil {
+		return Cron{}, err
+	}
+	if c.dom, err = parseField(parts[2], domField); err != nil {
+		return Cron{}, err
+	}
+	if c.month, err = parseField(parts[3], monthField); err != nil {
+		return Cron{}, err
+	}
+	if c.dow, err = parseField(parts[4], dowField); err != nil {
+		return Cron{}, err
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domAny = parts[2] == "*"
+	c.dowAny = parts[4] == "*"
+	return c, nil
+}
+
+// parseField returns the bitset of the values matched by a cron field.
+func parseField(s string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		rng, step := part, 1
+		if r, st, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(st)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", st, f.name)
+			}
+			rng, step = r, n
+		}
+
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("invalid value %q in %s field", a, f.name)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("invalid value %q in %s field", b, f.name)
+				}
+			} else if step > 1 {
+				// "a/n" runs from a to the end of the range.
+				hi = f.max
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("%q is out of range %d-%d in %s field", part, f.min, f.max, f.name)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first minute strictly after t matched by the
+// expression, in t's location, or the zero time if there is none within
+// five years (such as "0 0 30 2 *").
+func (c Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Window is a daily time range, such as 22:00-06:00, during which runs are
+// held back. A window ending before it starts spans midnight.
+type Window struct {
+	// Start and End are minutes since midnight.
+	Start, End int
+}
+
+// ParseWindows parses a comma separated list of HH:MM-HH:MM windows. An
+// empty string has no windows.
+func ParseWindows(s string) ([]Window, error) {
+	var windows []Window
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		a, b, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, fmt.Errorf("quiet window %q must look like 22:00-06:00", part)
+		}
+		start, err := parseClock(a)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(b)
+		if err != nil {
+			return nil, err
+		}
+		if start == end {
+			return nil, fmt.Errorf("quiet window %q is empty", part)
+		}
+		windows = append(windows, Window{Start: start, End: end})
+	}
+	return windows, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// end returns when the window containing t closes, and whether t is in the
+// window at all.
+func (w Window) end(t time.Time) (time.Time, bool) {
+	m := t.Hour()*60 + t.Minute()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch {
+	case w.Start < w.End && m >= w.Start && m < w.End:
+		return day.Add(time.Duration(w.End) * time.Minute), true
+	case w.Start > w.End && m >= w.Start:
+		return day.AddDate(0, 0, 1).Add(time.Duration(w.End) * time.Minute), true
+	case w.Start > w.End && m < w.End:
+		return day.Add(time.Duration(w.End) * time.Minute), true
+	}
+	return time.Time{}, false
+}
+
+// maxWindowMoves bounds how many quiet windows Next skips before deciding
+// the schedule never runs.
+const maxWindowMoves = 100
+
+// Schedule says when a run is due: on a cron expression, or a fixed
+// interval after the last run when there is none, but never inside a quiet
+// window.
+type Schedule struct {
+	// Cron is nil for interval schedules.
+	Cron  *Cron
+	Quiet []Window
+}
+
+// New parses a schedule from a cron expression, empty for interval
+// schedules, and a list of quiet windows.
+func New(cron, quiet string) (Schedule, error) {
+	var s Schedule
+	if strings.TrimSpace(cron) != "" {
+		c, err := Parse(cron)
+		if err != nil {
+			return Schedule{}, err
+		}
+		s.Cron = &c
+	}
+	windows, err := ParseWindows(quiet)
+	if err != nil {
+		return Schedule{}, err
+	}
+	s.Quiet = windows
+	return s, nil
+}
+
+// Next returns when the run after one made at last is due, interval being
+// the time between runs without a cron expression. A schedule that never
+// ran is due at now. The zero time means never.
+func (s Schedule) Next(last, now time.Time, interval time.Duration) time.Time {
+	var next time.Time
+	switch {
+	case last.IsZero():
+		next = now
+	case s.Cron != nil:
+		next = s.Cron.Next(last)
+	default:
+		next = last.Add(interval)
+	}
+
+	// Moving past one window may land in another; give up on schedules
+	// that only ever fall in quiet windows.
+	for i := 0; i < maxWindowMoves && !next.IsZero(); i++ {
+		moved := false
+		for _, w := range s.Quiet {
+			if end, ok := w.end(next); ok {
+				next, moved = end, true
+				if s.Cron != nil {
+					next = s.Cron.Next(end.Add(-time.Minute))
+				}
+				break
+			}
+		}
+		if !moved {
+			return next
+		}
+	}
+	return time.Time{}
+}
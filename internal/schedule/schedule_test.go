@@ -0,0 +1,87 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func at(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := Parse(expr)
+		require.Error(t, err, expr)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		expr string
+		from string
+		want string
+	}{
+		{"*/15 * * * *", "2026-03-02 10:07", "2026-03-02 10:15"},
+		{"*/15 * * * *", "2026-03-02 10:15", "2026-03-02 10:30"},
+		{"0 2 * * *", "2026-03-02 10:07", "2026-03-03 02:00"},
+		{"@nightly", "2026-12-31 23:30", "2027-01-01 00:00"},
+		{"*/10 8-17 * * 1-5", "2026-03-06 17:55", "2026-03-09 08:00"}, // Friday evening to Monday
+		{"0 9 * * 7", "2026-03-02 10:00", "2026-03-08 09:00"},         // 7 is Sunday
+		{"0 0 1,15 * 1", "2026-03-03 00:00", "2026-03-09 00:00"},      // either day field
+		{"30 4 29 2 *", "2026-03-01 00:00", "2028-02-29 04:30"},
+		{"5/20 * * * *", "2026-03-02 10:26", "2026-03-02 10:45"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := Parse(tt.expr)
+			require.NoError(t, err)
+			require.Equal(t, at(tt.want), c.Next(at(tt.from)))
+		})
+	}
+
+	c, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	require.True(t, c.Next(at("2026-03-02 10:00")).IsZero(), "February 30th never comes")
+}
+
+func TestParseWindows(t *testing.T) {
+	w, err := ParseWindows(" 22:00-06:00, 12:30-13:00 ")
+	require.NoError(t, err)
+	require.Equal(t, []Window{{Start: 22 * 60, End: 6 * 60}, {Start: 12*60 + 30, End: 13 * 60}}, w)
+
+	w, err = ParseWindows("")
+	require.NoError(t, err)
+	require.Empty(t, w)
+
+	for _, s := range []string{"22:00", "25:00-06:00", "10:00-10:00"} {
+		_, err := ParseWindows(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	interval, err := New("", "18:00-08:00")
+	require.NoError(t, err)
+	require.Equal(t, at("2026-03-02 10:05"), interval.Next(at("2026-03-02 10:00"), at("2026-03-02 10:01"), 5*time.Minute))
+	require.Equal(t, at("2026-03-03 08:00"), interval.Next(at("2026-03-02 17:58"), at("2026-03-02 17:59"), 5*time.Minute), "held back overnight")
+	require.Equal(t, at("2026-03-03 08:00"), interval.Next(time.Time{}, at("2026-03-03 01:00"), 5*time.Minute), "first run waits for the window to close")
+	require.Equal(t, at("2026-03-02 10:01"), interval.Next(time.Time{}, at("2026-03-02 10:01"), 5*time.Minute), "first run is due straight away")
+
+	cron, err := New("*/20 * * * *", "12:00-12:30")
+	require.NoError(t, err)
+	require.Equal(t, at("2026-03-02 12:40"), cron.Next(at("2026-03-02 11:40"), at("2026-03-02 11:41"), time.Hour), "next cron run after the window")
+
+	never, err := New("0 2 * * *", "01:00-03:00")
+	require.NoError(t, err)
+	require.True(t, never.Next(at("2026-03-02 11:40"), at("2026-03-02 11:41"), time.Hour).IsZero())
+
+	_, err = New("bogus", "")
+	require.Error(t, err)
+}